@@ -0,0 +1,990 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: goss.proto
+
+package goss
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type HealthRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HealthRequest) Reset()         { *m = HealthRequest{} }
+func (m *HealthRequest) String() string { return proto.CompactTextString(m) }
+func (*HealthRequest) ProtoMessage()    {}
+func (*HealthRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c224600ba9c1530f, []int{0}
+}
+
+func (m *HealthRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HealthRequest.Unmarshal(m, b)
+}
+func (m *HealthRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HealthRequest.Marshal(b, m, deterministic)
+}
+func (m *HealthRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HealthRequest.Merge(m, src)
+}
+func (m *HealthRequest) XXX_Size() int {
+	return xxx_messageInfo_HealthRequest.Size(m)
+}
+func (m *HealthRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_HealthRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HealthRequest proto.InternalMessageInfo
+
+type HealthResponse struct {
+	Version              string   `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	Storage              bool     `protobuf:"varint,2,opt,name=storage,proto3" json:"storage,omitempty"`
+	Security             bool     `protobuf:"varint,3,opt,name=security,proto3" json:"security,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HealthResponse) Reset()         { *m = HealthResponse{} }
+func (m *HealthResponse) String() string { return proto.CompactTextString(m) }
+func (*HealthResponse) ProtoMessage()    {}
+func (*HealthResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c224600ba9c1530f, []int{1}
+}
+
+func (m *HealthResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HealthResponse.Unmarshal(m, b)
+}
+func (m *HealthResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HealthResponse.Marshal(b, m, deterministic)
+}
+func (m *HealthResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HealthResponse.Merge(m, src)
+}
+func (m *HealthResponse) XXX_Size() int {
+	return xxx_messageInfo_HealthResponse.Size(m)
+}
+func (m *HealthResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_HealthResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HealthResponse proto.InternalMessageInfo
+
+func (m *HealthResponse) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *HealthResponse) GetStorage() bool {
+	if m != nil {
+		return m.Storage
+	}
+	return false
+}
+
+func (m *HealthResponse) GetSecurity() bool {
+	if m != nil {
+		return m.Security
+	}
+	return false
+}
+
+type LoginRequest struct {
+	Username string `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Password string `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	// scope optionally limits access tokens to some of the user's permissions.
+	Scope                string   `protobuf:"bytes,3,opt,name=scope,proto3" json:"scope,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LoginRequest) Reset()         { *m = LoginRequest{} }
+func (m *LoginRequest) String() string { return proto.CompactTextString(m) }
+func (*LoginRequest) ProtoMessage()    {}
+func (*LoginRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c224600ba9c1530f, []int{2}
+}
+
+func (m *LoginRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LoginRequest.Unmarshal(m, b)
+}
+func (m *LoginRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LoginRequest.Marshal(b, m, deterministic)
+}
+func (m *LoginRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LoginRequest.Merge(m, src)
+}
+func (m *LoginRequest) XXX_Size() int {
+	return xxx_messageInfo_LoginRequest.Size(m)
+}
+func (m *LoginRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LoginRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LoginRequest proto.InternalMessageInfo
+
+func (m *LoginRequest) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *LoginRequest) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+func (m *LoginRequest) GetScope() string {
+	if m != nil {
+		return m.Scope
+	}
+	return ""
+}
+
+type LoginMFARequest struct {
+	MfaToken string `protobuf:"bytes,1,opt,name=mfa_token,json=mfaToken,proto3" json:"mfa_token,omitempty"`
+	Code     string `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
+	// recovery_code can be used in place of the one-time code.
+	RecoveryCode string `protobuf:"bytes,3,opt,name=recovery_code,json=recoveryCode,proto3" json:"recovery_code,omitempty"`
+	// scope optionally limits access tokens the same way as on the password step.
+	Scope                string   `protobuf:"bytes,4,opt,name=scope,proto3" json:"scope,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LoginMFARequest) Reset()         { *m = LoginMFARequest{} }
+func (m *LoginMFARequest) String() string { return proto.CompactTextString(m) }
+func (*LoginMFARequest) ProtoMessage()    {}
+func (*LoginMFARequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c224600ba9c1530f, []int{3}
+}
+
+func (m *LoginMFARequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LoginMFARequest.Unmarshal(m, b)
+}
+func (m *LoginMFARequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LoginMFARequest.Marshal(b, m, deterministic)
+}
+func (m *LoginMFARequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LoginMFARequest.Merge(m, src)
+}
+func (m *LoginMFARequest) XXX_Size() int {
+	return xxx_messageInfo_LoginMFARequest.Size(m)
+}
+func (m *LoginMFARequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LoginMFARequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LoginMFARequest proto.InternalMessageInfo
+
+func (m *LoginMFARequest) GetMfaToken() string {
+	if m != nil {
+		return m.MfaToken
+	}
+	return ""
+}
+
+func (m *LoginMFARequest) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+func (m *LoginMFARequest) GetRecoveryCode() string {
+	if m != nil {
+		return m.RecoveryCode
+	}
+	return ""
+}
+
+func (m *LoginMFARequest) GetScope() string {
+	if m != nil {
+		return m.Scope
+	}
+	return ""
+}
+
+type RefreshRequest struct {
+	RefreshToken         string   `protobuf:"bytes,1,opt,name=refresh_token,json=refreshToken,proto3" json:"refresh_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RefreshRequest) Reset()         { *m = RefreshRequest{} }
+func (m *RefreshRequest) String() string { return proto.CompactTextString(m) }
+func (*RefreshRequest) ProtoMessage()    {}
+func (*RefreshRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c224600ba9c1530f, []int{4}
+}
+
+func (m *RefreshRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RefreshRequest.Unmarshal(m, b)
+}
+func (m *RefreshRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RefreshRequest.Marshal(b, m, deterministic)
+}
+func (m *RefreshRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RefreshRequest.Merge(m, src)
+}
+func (m *RefreshRequest) XXX_Size() int {
+	return xxx_messageInfo_RefreshRequest.Size(m)
+}
+func (m *RefreshRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RefreshRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RefreshRequest proto.InternalMessageInfo
+
+func (m *RefreshRequest) GetRefreshToken() string {
+	if m != nil {
+		return m.RefreshToken
+	}
+	return ""
+}
+
+type AuthData struct {
+	AccessToken          string   `protobuf:"bytes,1,opt,name=access_token,json=accessToken,proto3" json:"access_token,omitempty"`
+	ExpiresAt            int64    `protobuf:"varint,2,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	RefreshToken         string   `protobuf:"bytes,3,opt,name=refresh_token,json=refreshToken,proto3" json:"refresh_token,omitempty"`
+	MfaToken             string   `protobuf:"bytes,4,opt,name=mfa_token,json=mfaToken,proto3" json:"mfa_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuthData) Reset()         { *m = AuthData{} }
+func (m *AuthData) String() string { return proto.CompactTextString(m) }
+func (*AuthData) ProtoMessage()    {}
+func (*AuthData) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c224600ba9c1530f, []int{5}
+}
+
+func (m *AuthData) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuthData.Unmarshal(m, b)
+}
+func (m *AuthData) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuthData.Marshal(b, m, deterministic)
+}
+func (m *AuthData) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuthData.Merge(m, src)
+}
+func (m *AuthData) XXX_Size() int {
+	return xxx_messageInfo_AuthData.Size(m)
+}
+func (m *AuthData) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuthData.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuthData proto.InternalMessageInfo
+
+func (m *AuthData) GetAccessToken() string {
+	if m != nil {
+		return m.AccessToken
+	}
+	return ""
+}
+
+func (m *AuthData) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+func (m *AuthData) GetRefreshToken() string {
+	if m != nil {
+		return m.RefreshToken
+	}
+	return ""
+}
+
+func (m *AuthData) GetMfaToken() string {
+	if m != nil {
+		return m.MfaToken
+	}
+	return ""
+}
+
+type GetUserRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetUserRequest) Reset()         { *m = GetUserRequest{} }
+func (m *GetUserRequest) String() string { return proto.CompactTextString(m) }
+func (*GetUserRequest) ProtoMessage()    {}
+func (*GetUserRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c224600ba9c1530f, []int{6}
+}
+
+func (m *GetUserRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetUserRequest.Unmarshal(m, b)
+}
+func (m *GetUserRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetUserRequest.Marshal(b, m, deterministic)
+}
+func (m *GetUserRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetUserRequest.Merge(m, src)
+}
+func (m *GetUserRequest) XXX_Size() int {
+	return xxx_messageInfo_GetUserRequest.Size(m)
+}
+func (m *GetUserRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetUserRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetUserRequest proto.InternalMessageInfo
+
+type User struct {
+	Id                   int64    `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Username             string   `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	Role                 string   `protobuf:"bytes,3,opt,name=role,proto3" json:"role,omitempty"`
+	Disabled             bool     `protobuf:"varint,4,opt,name=disabled,proto3" json:"disabled,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *User) Reset()         { *m = User{} }
+func (m *User) String() string { return proto.CompactTextString(m) }
+func (*User) ProtoMessage()    {}
+func (*User) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c224600ba9c1530f, []int{7}
+}
+
+func (m *User) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_User.Unmarshal(m, b)
+}
+func (m *User) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_User.Marshal(b, m, deterministic)
+}
+func (m *User) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_User.Merge(m, src)
+}
+func (m *User) XXX_Size() int {
+	return xxx_messageInfo_User.Size(m)
+}
+func (m *User) XXX_DiscardUnknown() {
+	xxx_messageInfo_User.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_User proto.InternalMessageInfo
+
+func (m *User) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *User) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *User) GetRole() string {
+	if m != nil {
+		return m.Role
+	}
+	return ""
+}
+
+func (m *User) GetDisabled() bool {
+	if m != nil {
+		return m.Disabled
+	}
+	return false
+}
+
+type LogoutRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LogoutRequest) Reset()         { *m = LogoutRequest{} }
+func (m *LogoutRequest) String() string { return proto.CompactTextString(m) }
+func (*LogoutRequest) ProtoMessage()    {}
+func (*LogoutRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c224600ba9c1530f, []int{8}
+}
+
+func (m *LogoutRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogoutRequest.Unmarshal(m, b)
+}
+func (m *LogoutRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogoutRequest.Marshal(b, m, deterministic)
+}
+func (m *LogoutRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogoutRequest.Merge(m, src)
+}
+func (m *LogoutRequest) XXX_Size() int {
+	return xxx_messageInfo_LogoutRequest.Size(m)
+}
+func (m *LogoutRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogoutRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LogoutRequest proto.InternalMessageInfo
+
+type LogoutResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LogoutResponse) Reset()         { *m = LogoutResponse{} }
+func (m *LogoutResponse) String() string { return proto.CompactTextString(m) }
+func (*LogoutResponse) ProtoMessage()    {}
+func (*LogoutResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c224600ba9c1530f, []int{9}
+}
+
+func (m *LogoutResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogoutResponse.Unmarshal(m, b)
+}
+func (m *LogoutResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogoutResponse.Marshal(b, m, deterministic)
+}
+func (m *LogoutResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogoutResponse.Merge(m, src)
+}
+func (m *LogoutResponse) XXX_Size() int {
+	return xxx_messageInfo_LogoutResponse.Size(m)
+}
+func (m *LogoutResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogoutResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LogoutResponse proto.InternalMessageInfo
+
+type ValidateTokenRequest struct {
+	AccessToken          string   `protobuf:"bytes,1,opt,name=access_token,json=accessToken,proto3" json:"access_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ValidateTokenRequest) Reset()         { *m = ValidateTokenRequest{} }
+func (m *ValidateTokenRequest) String() string { return proto.CompactTextString(m) }
+func (*ValidateTokenRequest) ProtoMessage()    {}
+func (*ValidateTokenRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c224600ba9c1530f, []int{10}
+}
+
+func (m *ValidateTokenRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ValidateTokenRequest.Unmarshal(m, b)
+}
+func (m *ValidateTokenRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ValidateTokenRequest.Marshal(b, m, deterministic)
+}
+func (m *ValidateTokenRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ValidateTokenRequest.Merge(m, src)
+}
+func (m *ValidateTokenRequest) XXX_Size() int {
+	return xxx_messageInfo_ValidateTokenRequest.Size(m)
+}
+func (m *ValidateTokenRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ValidateTokenRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ValidateTokenRequest proto.InternalMessageInfo
+
+func (m *ValidateTokenRequest) GetAccessToken() string {
+	if m != nil {
+		return m.AccessToken
+	}
+	return ""
+}
+
+type TokenClaims struct {
+	UserId               int64    `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Role                 string   `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"`
+	SessionId            string   `protobuf:"bytes,3,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	ClientId             string   `protobuf:"bytes,4,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Scope                string   `protobuf:"bytes,5,opt,name=scope,proto3" json:"scope,omitempty"`
+	ApiKeyId             int64    `protobuf:"varint,6,opt,name=api_key_id,json=apiKeyId,proto3" json:"api_key_id,omitempty"`
+	ExpiresAt            int64    `protobuf:"varint,7,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TokenClaims) Reset()         { *m = TokenClaims{} }
+func (m *TokenClaims) String() string { return proto.CompactTextString(m) }
+func (*TokenClaims) ProtoMessage()    {}
+func (*TokenClaims) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c224600ba9c1530f, []int{11}
+}
+
+func (m *TokenClaims) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TokenClaims.Unmarshal(m, b)
+}
+func (m *TokenClaims) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TokenClaims.Marshal(b, m, deterministic)
+}
+func (m *TokenClaims) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TokenClaims.Merge(m, src)
+}
+func (m *TokenClaims) XXX_Size() int {
+	return xxx_messageInfo_TokenClaims.Size(m)
+}
+func (m *TokenClaims) XXX_DiscardUnknown() {
+	xxx_messageInfo_TokenClaims.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TokenClaims proto.InternalMessageInfo
+
+func (m *TokenClaims) GetUserId() int64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *TokenClaims) GetRole() string {
+	if m != nil {
+		return m.Role
+	}
+	return ""
+}
+
+func (m *TokenClaims) GetSessionId() string {
+	if m != nil {
+		return m.SessionId
+	}
+	return ""
+}
+
+func (m *TokenClaims) GetClientId() string {
+	if m != nil {
+		return m.ClientId
+	}
+	return ""
+}
+
+func (m *TokenClaims) GetScope() string {
+	if m != nil {
+		return m.Scope
+	}
+	return ""
+}
+
+func (m *TokenClaims) GetApiKeyId() int64 {
+	if m != nil {
+		return m.ApiKeyId
+	}
+	return 0
+}
+
+func (m *TokenClaims) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*HealthRequest)(nil), "goss.v1.HealthRequest")
+	proto.RegisterType((*HealthResponse)(nil), "goss.v1.HealthResponse")
+	proto.RegisterType((*LoginRequest)(nil), "goss.v1.LoginRequest")
+	proto.RegisterType((*LoginMFARequest)(nil), "goss.v1.LoginMFARequest")
+	proto.RegisterType((*RefreshRequest)(nil), "goss.v1.RefreshRequest")
+	proto.RegisterType((*AuthData)(nil), "goss.v1.AuthData")
+	proto.RegisterType((*GetUserRequest)(nil), "goss.v1.GetUserRequest")
+	proto.RegisterType((*User)(nil), "goss.v1.User")
+	proto.RegisterType((*LogoutRequest)(nil), "goss.v1.LogoutRequest")
+	proto.RegisterType((*LogoutResponse)(nil), "goss.v1.LogoutResponse")
+	proto.RegisterType((*ValidateTokenRequest)(nil), "goss.v1.ValidateTokenRequest")
+	proto.RegisterType((*TokenClaims)(nil), "goss.v1.TokenClaims")
+}
+
+func init() { proto.RegisterFile("goss.proto", fileDescriptor_c224600ba9c1530f) }
+
+var fileDescriptor_c224600ba9c1530f = []byte{
+	// 644 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x54, 0x4d, 0x6f, 0xd3, 0x40,
+	0x10, 0x55, 0x3e, 0x9a, 0xd8, 0xd3, 0x24, 0x2d, 0xab, 0x42, 0xa3, 0x40, 0xa5, 0x62, 0x0e, 0x94,
+	0x4b, 0xaa, 0x82, 0x2a, 0x54, 0x71, 0x2a, 0xad, 0x28, 0x11, 0xe5, 0x62, 0x01, 0x07, 0x84, 0x14,
+	0x36, 0xf6, 0x34, 0x59, 0xd5, 0xc9, 0x9a, 0xdd, 0x75, 0x20, 0x48, 0xfc, 0x05, 0xce, 0xfc, 0x25,
+	0xfe, 0x15, 0xda, 0xb5, 0x77, 0x6b, 0xb7, 0x91, 0xb8, 0x44, 0x3b, 0x6f, 0x66, 0xfc, 0xde, 0xce,
+	0xbe, 0x09, 0xc0, 0x94, 0x4b, 0x39, 0x4c, 0x05, 0x57, 0x9c, 0xb4, 0xcd, 0x79, 0x79, 0x14, 0x6c,
+	0x41, 0xf7, 0x2d, 0xd2, 0x44, 0xcd, 0x42, 0xfc, 0x96, 0xa1, 0x54, 0xc1, 0x57, 0xe8, 0x59, 0x40,
+	0xa6, 0x7c, 0x21, 0x91, 0xf4, 0xa1, 0xbd, 0x44, 0x21, 0x19, 0x5f, 0xf4, 0x6b, 0xfb, 0xb5, 0x03,
+	0x3f, 0xb4, 0xa1, 0xce, 0x48, 0xc5, 0x05, 0x9d, 0x62, 0xbf, 0xbe, 0x5f, 0x3b, 0xf0, 0x42, 0x1b,
+	0x92, 0x01, 0x78, 0x12, 0xa3, 0x4c, 0x30, 0xb5, 0xea, 0x37, 0x4c, 0xca, 0xc5, 0xc1, 0x17, 0xe8,
+	0x5c, 0xf2, 0x29, 0x5b, 0x14, 0x8c, 0xba, 0x36, 0x93, 0x28, 0x16, 0x74, 0x8e, 0x05, 0x81, 0x8b,
+	0x75, 0x2e, 0xa5, 0x52, 0x7e, 0xe7, 0x22, 0x36, 0x14, 0x7e, 0xe8, 0x62, 0xb2, 0x03, 0x1b, 0x32,
+	0xe2, 0x29, 0x1a, 0x02, 0x3f, 0xcc, 0x83, 0xe0, 0x17, 0x6c, 0x99, 0xaf, 0xbf, 0x7f, 0x73, 0x6a,
+	0x09, 0x1e, 0x82, 0x3f, 0xbf, 0xa2, 0x63, 0xc5, 0xaf, 0xd1, 0x5e, 0xc1, 0x9b, 0x5f, 0xd1, 0x0f,
+	0x3a, 0x26, 0x04, 0x9a, 0x11, 0x8f, 0xb1, 0xf8, 0xba, 0x39, 0x93, 0x27, 0xd0, 0x15, 0x18, 0xf1,
+	0x25, 0x8a, 0xd5, 0xd8, 0x24, 0x73, 0x86, 0x8e, 0x05, 0xcf, 0x74, 0x91, 0xa3, 0x6f, 0x96, 0xe9,
+	0x8f, 0xa1, 0x17, 0xe2, 0x95, 0x40, 0x69, 0x07, 0x9a, 0x7f, 0xcc, 0x20, 0x15, 0x05, 0x9d, 0x02,
+	0x34, 0x2a, 0x82, 0xdf, 0x35, 0xf0, 0x4e, 0x33, 0x35, 0x3b, 0xa7, 0x8a, 0x92, 0xc7, 0xd0, 0xa1,
+	0x51, 0x84, 0x52, 0x56, 0x1a, 0x36, 0x73, 0x2c, 0x57, 0xbd, 0x07, 0x80, 0x3f, 0x52, 0x26, 0x50,
+	0x8e, 0xa9, 0x32, 0xda, 0x1b, 0xa1, 0x5f, 0x20, 0xa7, 0x6b, 0x38, 0x1b, 0x77, 0x39, 0xab, 0x63,
+	0x69, 0x56, 0xc7, 0x12, 0x6c, 0x43, 0xef, 0x02, 0xd5, 0x47, 0x89, 0xc2, 0x1a, 0x63, 0x02, 0x4d,
+	0x1d, 0x92, 0x1e, 0xd4, 0x59, 0x6c, 0x34, 0x35, 0xc2, 0x3a, 0x8b, 0x2b, 0xcf, 0x57, 0xbf, 0xf5,
+	0x7c, 0x04, 0x9a, 0x82, 0x27, 0x76, 0x7e, 0xe6, 0xac, 0xeb, 0x63, 0x26, 0xe9, 0x24, 0xc1, 0xd8,
+	0xb0, 0x7a, 0xa1, 0x8b, 0xb5, 0x1b, 0x2f, 0xf9, 0x94, 0x67, 0xca, 0x92, 0x6e, 0x43, 0xcf, 0x02,
+	0xb9, 0x1b, 0x83, 0x13, 0xd8, 0xf9, 0x44, 0x13, 0x16, 0x53, 0x85, 0x46, 0xa9, 0x1d, 0xf3, 0xff,
+	0x87, 0x16, 0xfc, 0xad, 0xc1, 0xa6, 0x39, 0x9d, 0x25, 0x94, 0xcd, 0x25, 0xd9, 0x85, 0xb6, 0x56,
+	0x3a, 0x76, 0xd7, 0x69, 0xe9, 0x70, 0x14, 0x3b, 0xd9, 0xf5, 0x92, 0xec, 0x3d, 0x00, 0x89, 0x52,
+	0xdb, 0x5e, 0xd7, 0xe7, 0x17, 0xf2, 0x0b, 0x64, 0x14, 0xeb, 0x61, 0x46, 0x09, 0xc3, 0x85, 0x1a,
+	0xb3, 0xfc, 0x5a, 0x7e, 0xe8, 0xe5, 0xc0, 0xa8, 0xe4, 0xd4, 0x8d, 0x92, 0x55, 0xc8, 0x23, 0x00,
+	0x9a, 0xb2, 0xf1, 0x35, 0xae, 0x74, 0x4f, 0xcb, 0x28, 0xf0, 0x68, 0xca, 0xde, 0xe1, 0x6a, 0x14,
+	0xdf, 0x7a, 0xe1, 0xf6, 0xad, 0x17, 0x7e, 0xfe, 0xa7, 0x01, 0xcd, 0x0b, 0x2e, 0x25, 0x39, 0x81,
+	0x56, 0xbe, 0xaf, 0xe4, 0xc1, 0xb0, 0x58, 0xea, 0x61, 0x65, 0xa3, 0x07, 0xbb, 0x77, 0xf0, 0x62,
+	0xb1, 0x8f, 0x60, 0xc3, 0xac, 0x0a, 0xb9, 0xef, 0x2a, 0xca, 0x8b, 0x39, 0xb8, 0xe7, 0x60, 0x67,
+	0xcd, 0x97, 0xe0, 0xd9, 0xed, 0x22, 0xfd, 0x6a, 0xd7, 0xcd, 0xc2, 0xad, 0x6b, 0x3c, 0x86, 0x76,
+	0xb1, 0x17, 0xe4, 0x46, 0x4f, 0x75, 0x53, 0xd6, 0xb5, 0x1d, 0x41, 0xbb, 0xb0, 0x61, 0xa9, 0xad,
+	0x6a, 0xcc, 0x41, 0xd7, 0x25, 0x4c, 0xdd, 0x09, 0xb4, 0x72, 0xcb, 0x94, 0x06, 0x52, 0x31, 0xd5,
+	0x60, 0xf7, 0x0e, 0x5e, 0x0c, 0xe4, 0x1c, 0xba, 0x15, 0x6f, 0x91, 0x3d, 0x57, 0xb9, 0xce, 0x73,
+	0x83, 0x1d, 0x97, 0x2e, 0xd9, 0xea, 0xf5, 0xb3, 0xcf, 0x4f, 0xa7, 0x4c, 0xcd, 0xb2, 0xc9, 0x30,
+	0xe2, 0xf3, 0xc3, 0xe4, 0x27, 0xbd, 0x9e, 0x51, 0xc1, 0x97, 0x87, 0xba, 0xf6, 0x90, 0xa6, 0xcc,
+	0x1c, 0x5e, 0xe9, 0x9f, 0x49, 0xcb, 0xfc, 0x1b, 0xbf, 0xf8, 0x37, 0x00, 0x4c, 0x4c, 0x19, 0xfe,
+	0x9b, 0x05, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// GossClient is the client API for Goss service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type GossClient interface {
+	// Health returns the service health status.
+	Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
+	// Login creates user auth data by the credentials.
+	// A user with two-factor authentication enabled gets only the MFA token.
+	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*AuthData, error)
+	// LoginMFA completes the login of a user with two-factor authentication by the MFA token of the password step.
+	LoginMFA(ctx context.Context, in *LoginMFARequest, opts ...grpc.CallOption) (*AuthData, error)
+	// Refresh creates new auth data by the refresh token.
+	Refresh(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*AuthData, error)
+	// GetUser returns the logged in user, it requires the profile:read scope.
+	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error)
+	// Logout invalidates the current session.
+	Logout(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutResponse, error)
+	// ValidateToken returns claims of a valid access token or API key.
+	ValidateToken(ctx context.Context, in *ValidateTokenRequest, opts ...grpc.CallOption) (*TokenClaims, error)
+}
+
+type gossClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewGossClient(cc *grpc.ClientConn) GossClient {
+	return &gossClient{cc}
+}
+
+func (c *gossClient) Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error) {
+	out := new(HealthResponse)
+	err := c.cc.Invoke(ctx, "/goss.v1.Goss/Health", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*AuthData, error) {
+	out := new(AuthData)
+	err := c.cc.Invoke(ctx, "/goss.v1.Goss/Login", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossClient) LoginMFA(ctx context.Context, in *LoginMFARequest, opts ...grpc.CallOption) (*AuthData, error) {
+	out := new(AuthData)
+	err := c.cc.Invoke(ctx, "/goss.v1.Goss/LoginMFA", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossClient) Refresh(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*AuthData, error) {
+	out := new(AuthData)
+	err := c.cc.Invoke(ctx, "/goss.v1.Goss/Refresh", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/goss.v1.Goss/GetUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossClient) Logout(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutResponse, error) {
+	out := new(LogoutResponse)
+	err := c.cc.Invoke(ctx, "/goss.v1.Goss/Logout", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gossClient) ValidateToken(ctx context.Context, in *ValidateTokenRequest, opts ...grpc.CallOption) (*TokenClaims, error) {
+	out := new(TokenClaims)
+	err := c.cc.Invoke(ctx, "/goss.v1.Goss/ValidateToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GossServer is the server API for Goss service.
+type GossServer interface {
+	// Health returns the service health status.
+	Health(context.Context, *HealthRequest) (*HealthResponse, error)
+	// Login creates user auth data by the credentials.
+	// A user with two-factor authentication enabled gets only the MFA token.
+	Login(context.Context, *LoginRequest) (*AuthData, error)
+	// LoginMFA completes the login of a user with two-factor authentication by the MFA token of the password step.
+	LoginMFA(context.Context, *LoginMFARequest) (*AuthData, error)
+	// Refresh creates new auth data by the refresh token.
+	Refresh(context.Context, *RefreshRequest) (*AuthData, error)
+	// GetUser returns the logged in user, it requires the profile:read scope.
+	GetUser(context.Context, *GetUserRequest) (*User, error)
+	// Logout invalidates the current session.
+	Logout(context.Context, *LogoutRequest) (*LogoutResponse, error)
+	// ValidateToken returns claims of a valid access token or API key.
+	ValidateToken(context.Context, *ValidateTokenRequest) (*TokenClaims, error)
+}
+
+// UnimplementedGossServer can be embedded to have forward compatible implementations.
+type UnimplementedGossServer struct {
+}
+
+func (*UnimplementedGossServer) Health(ctx context.Context, req *HealthRequest) (*HealthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Health not implemented")
+}
+func (*UnimplementedGossServer) Login(ctx context.Context, req *LoginRequest) (*AuthData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (*UnimplementedGossServer) LoginMFA(ctx context.Context, req *LoginMFARequest) (*AuthData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LoginMFA not implemented")
+}
+func (*UnimplementedGossServer) Refresh(ctx context.Context, req *RefreshRequest) (*AuthData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Refresh not implemented")
+}
+func (*UnimplementedGossServer) GetUser(ctx context.Context, req *GetUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+}
+func (*UnimplementedGossServer) Logout(ctx context.Context, req *LogoutRequest) (*LogoutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Logout not implemented")
+}
+func (*UnimplementedGossServer) ValidateToken(ctx context.Context, req *ValidateTokenRequest) (*TokenClaims, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateToken not implemented")
+}
+
+func RegisterGossServer(s *grpc.Server, srv GossServer) {
+	s.RegisterService(&_Goss_serviceDesc, srv)
+}
+
+func _Goss_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossServer).Health(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/goss.v1.Goss/Health",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossServer).Health(ctx, req.(*HealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Goss_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/goss.v1.Goss/Login",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossServer).Login(ctx, req.(*LoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Goss_LoginMFA_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginMFARequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossServer).LoginMFA(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/goss.v1.Goss/LoginMFA",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossServer).LoginMFA(ctx, req.(*LoginMFARequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Goss_Refresh_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossServer).Refresh(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/goss.v1.Goss/Refresh",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossServer).Refresh(ctx, req.(*RefreshRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Goss_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/goss.v1.Goss/GetUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossServer).GetUser(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Goss_Logout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossServer).Logout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/goss.v1.Goss/Logout",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossServer).Logout(ctx, req.(*LogoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Goss_ValidateToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossServer).ValidateToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/goss.v1.Goss/ValidateToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossServer).ValidateToken(ctx, req.(*ValidateTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Goss_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "goss.v1.Goss",
+	HandlerType: (*GossServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Health",
+			Handler:    _Goss_Health_Handler,
+		},
+		{
+			MethodName: "Login",
+			Handler:    _Goss_Login_Handler,
+		},
+		{
+			MethodName: "LoginMFA",
+			Handler:    _Goss_LoginMFA_Handler,
+		},
+		{
+			MethodName: "Refresh",
+			Handler:    _Goss_Refresh_Handler,
+		},
+		{
+			MethodName: "GetUser",
+			Handler:    _Goss_GetUser_Handler,
+		},
+		{
+			MethodName: "Logout",
+			Handler:    _Goss_Logout_Handler,
+		},
+		{
+			MethodName: "ValidateToken",
+			Handler:    _Goss_ValidateToken_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "goss.proto",
+}
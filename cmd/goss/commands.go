@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lzakharov/goss/internal/domain"
+	"github.com/lzakharov/goss/internal/infrastructure/security"
+	"github.com/lzakharov/goss/internal/infrastructure/storage"
+	"go.uber.org/zap"
+)
+
+const usage = `Usage: goss [command]
+
+Runs the server without a command.
+
+Commands:
+  audit verify    Verify the audit log hash chain
+  mfa reencrypt   Re-encrypt TOTP secrets with the current encryption key`
+
+//runCommand runs the command and returns the exit code.
+//The encryption key is only required by the commands accessing TOTP secrets, the audit log is verified with the audit key.
+func runCommand(logger *zap.Logger, config *security.Config, db *sqlx.DB, auditKey []byte, args []string) int {
+	switch strings.Join(args, " ") {
+	case "audit verify":
+		return verifyAuditLog(storage.NewAdapter(logger, db, nil, nil, auditKey), auditKey)
+	case "mfa reencrypt":
+		encrypter, err := security.NewEncrypter(config.EncryptionKey, config.PreviousEncryptionKeys)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating an encrypter: %v\n", err)
+			return 1
+		}
+
+		return reencryptTOTPSecrets(storage.NewAdapter(logger, db, security.NewPasswordHasher(config.Password), encrypter, auditKey))
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+}
+
+//verifyAuditLog reports the first break of the audit log chain, the exit code is 1 if the chain is broken.
+func verifyAuditLog(storageAdapter domain.Storage, auditKey []byte) int {
+	verification, err := domain.VerifyAuditLog(storageAdapter, auditKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error verifying the audit log: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Audit chain is anchored after event %d with hash %q.\n",
+		verification.Anchor.EventID, verification.Anchor.Hash)
+
+	if verification.Break != nil {
+		fmt.Printf("Audit log is broken at event %d: %s, %d events verified before.\n",
+			verification.Break.EventID, verification.Break.Reason, verification.Verified)
+		return 1
+	}
+
+	fmt.Printf("Audit log is intact, %d events verified, the last is event %d with hash %q.\n",
+		verification.Verified, verification.LastEventID, verification.LastHash)
+	return 0
+}
+
+//reencryptTOTPSecrets re-encrypts TOTP secrets after the encryption key rotation.
+func reencryptTOTPSecrets(storageAdapter domain.Storage) int {
+	count, err := storageAdapter.ReencryptTOTPSecrets()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error re-encrypting TOTP secrets: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("%d TOTP secrets re-encrypted with the current key.\n", count)
+	return 0
+}
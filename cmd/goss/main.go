@@ -5,9 +5,11 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/lzakharov/goss/internal/configs"
 	"github.com/lzakharov/goss/internal/domain"
+	"github.com/lzakharov/goss/internal/infrastructure/grpc"
 	"github.com/lzakharov/goss/internal/infrastructure/http"
 	"github.com/lzakharov/goss/internal/infrastructure/security"
 	"github.com/lzakharov/goss/internal/infrastructure/storage"
@@ -34,6 +36,23 @@ func newLogger() (*zap.Logger, error) {
 	}
 }
 
+//purgeAuditEvents deletes audit events out of the retention period at the interval until stopped.
+//Errors are logged by the service and the next purge retries.
+func purgeAuditEvents(service domain.Service, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_ = service.PurgeAuditEvents()
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
 func main() {
 	logger, err := newLogger()
 	if err != nil {
@@ -52,20 +71,54 @@ func main() {
 		logger.Panic("Error creating a new SQL database!", zap.Error(err))
 	}
 
-	storageAdapter := storage.NewAdapter(logger, db)
+	auditKey, err := security.NewAuditKey(config.Security.AuditKey)
+	if err != nil {
+		logger.Panic("Error decoding the audit key!", zap.Error(err))
+	}
+
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(logger, config.Security, db, auditKey, os.Args[1:]))
+	}
+
+	passwordHasher := security.NewPasswordHasher(config.Security.Password)
+
+	encrypter, err := security.NewEncrypter(config.Security.EncryptionKey, config.Security.PreviousEncryptionKeys)
+	if err != nil {
+		logger.Panic("Error creating a new encrypter!", zap.Error(err))
+	}
+
+	storageAdapter := storage.NewAdapter(logger, db, passwordHasher, encrypter, auditKey)
 
 	redisClient, err := security.NewRedisClient(config.Security.RedisClient)
 	if err != nil {
 		logger.Panic("Error creating a new Redis client!", zap.Error(err))
 	}
 
-	securityAdapter := security.NewAdapter(logger, config.Security, redisClient)
+	keyring, err := security.NewKeyring(logger, config.Security)
+	if err != nil {
+		logger.Panic("Error creating a new keyring!", zap.Error(err))
+	}
+
+	securityAdapter := security.NewAdapter(logger, config.Security, redisClient, keyring)
 
-	service := domain.NewService(logger, storageAdapter, securityAdapter)
+	service := domain.NewService(logger, config.Service, storageAdapter, securityAdapter)
 
-	httpAdapter := http.NewAdapter(logger, config.HTTP, service)
+	stopPurge := make(chan struct{})
+	go purgeAuditEvents(service, config.Service.AuditPurgeInterval, stopPurge)
 
-	shutdown := make(chan error, 1)
+	rateLimiter, err := http.NewRateLimiter(config.HTTP.RateLimit, config.Security.KeyPrefix, redisClient)
+	if err != nil {
+		logger.Panic("Error creating a new rate limiter!", zap.Error(err))
+	}
+
+	httpAdapter := http.NewAdapter(logger, config.HTTP, service, rateLimiter)
+
+	grpcAdapter, err := grpc.NewAdapter(logger, config.GRPC, service, rateLimiter, config.HTTP.RateLimit)
+	if err != nil {
+		logger.Panic("Error creating a new gRPC adapter!", zap.Error(err))
+	}
+
+	shutdown := make(chan error, 2)
 
 	go func(shutdown chan<- error) {
 		if err := httpAdapter.Run(); err != nil {
@@ -73,6 +126,12 @@ func main() {
 		}
 	}(shutdown)
 
+	go func(shutdown chan<- error) {
+		if err := grpcAdapter.Run(); err != nil {
+			shutdown <- err
+		}
+	}(shutdown)
+
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
 
@@ -85,8 +144,23 @@ func main() {
 
 	logger.Info("Stopping the application...")
 
+	close(stopPurge)
+
+	//Both adapters are shut down even if one of them fails, so the other one finishes pending requests.
+	stopped := true
+
 	if err := httpAdapter.Shutdown(); err != nil {
-		logger.Panic("Error shutting down the HTTP adapter!", zap.Error(err))
+		logger.Error("Error shutting down the HTTP adapter!", zap.Error(err))
+		stopped = false
+	}
+
+	if err := grpcAdapter.Shutdown(); err != nil {
+		logger.Error("Error shutting down the gRPC adapter!", zap.Error(err))
+		stopped = false
+	}
+
+	if !stopped {
+		os.Exit(1)
 	}
 
 	logger.Info("The application gracefully stopped.")
@@ -2,6 +2,8 @@ package configs
 
 import (
 	"github.com/kelseyhightower/envconfig"
+	"github.com/lzakharov/goss/internal/domain"
+	"github.com/lzakharov/goss/internal/infrastructure/grpc"
 	"github.com/lzakharov/goss/internal/infrastructure/http"
 	"github.com/lzakharov/goss/internal/infrastructure/security"
 	"github.com/lzakharov/goss/internal/infrastructure/storage"
@@ -13,9 +15,11 @@ const prefix = "app"
 
 //Config contains an application configuration.
 type Config struct {
+	Service  *domain.Config   `validate:"required"`
 	Storage  *storage.Config  `validate:"required"`
 	Security *security.Config `validate:"required"`
 	HTTP     *http.Config     `validate:"required"`
+	GRPC     *grpc.Config     `validate:"required"`
 }
 
 // NewConfig reads an application configuration from the environment variables.
@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/lzakharov/goss/internal/domain"
+	"github.com/lzakharov/goss/internal/infrastructure/grpc"
 	"github.com/lzakharov/goss/internal/infrastructure/security"
 
 	"github.com/stretchr/testify/require"
@@ -17,6 +19,9 @@ func TestNewConfig(t *testing.T) {
 	logger := zap.NewExample()
 
 	t.Run("with a valid environment", func(t *testing.T) {
+		require.NoError(t, os.Setenv("APP_SERVICE_REGISTRATIONENABLED", "false"))
+		require.NoError(t, os.Setenv("APP_SERVICE_ISSUER", "https://goss.example.com"))
+
 		require.NoError(t, os.Setenv("APP_STORAGE_DB_DRIVERNAME", "postgres"))
 		require.NoError(t, os.Setenv("APP_STORAGE_DB_DSN", "postgres"))
 		require.NoError(t, os.Setenv("APP_STORAGE_DB_MAXOPENCONNS", "64"))
@@ -27,13 +32,30 @@ func TestNewConfig(t *testing.T) {
 
 		require.NoError(t, os.Setenv("APP_SECURITY_KEYPREFIX", "auth"))
 		require.NoError(t, os.Setenv("APP_SECURITY_SECRET", "secret"))
+		require.NoError(t, os.Setenv("APP_SECURITY_ENCRYPTIONKEY", "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U="))
+		require.NoError(t, os.Setenv("APP_SECURITY_PREVIOUSENCRYPTIONKEYS", "b2xka2V5b2xka2V5b2xka2V5b2xka2V5b2xka2V5b2w="))
+		require.NoError(t, os.Setenv("APP_SECURITY_AUDITKEY", "YXVkaXRrZXlhdWRpdGtleWF1ZGl0a2V5YXVkaXRrZXk="))
 		require.NoError(t, os.Setenv("APP_SECURITY_ACCESSTOKENLIFETIME", "2h"))
 		require.NoError(t, os.Setenv("APP_SECURITY_REFRESHTOKENLIFETIME", "720h"))
 		require.NoError(t, os.Setenv("APP_SECURITY_REDISCLIENT_ADDR", "redis:6379"))
 
 		require.NoError(t, os.Setenv("APP_HTTP_ADDRESS", "127.0.0.1:8080"))
 		require.NoError(t, os.Setenv("APP_HTTP_READTIMEOUT", "5s"))
+
+		require.NoError(t, os.Setenv("APP_GRPC_ADDRESS", "127.0.0.1:9090"))
+		require.NoError(t, os.Setenv("APP_GRPC_TLSCERTFILE", "./configs/tls/cert.pem"))
+		require.NoError(t, os.Setenv("APP_GRPC_TLSKEYFILE", "./configs/tls/key.pem"))
 		expected := &Config{
+			Service: &domain.Config{
+				RegistrationEnabled: false,
+				DefaultRole:         "user",
+				Issuer:              "https://goss.example.com",
+				IDTokenLifetime:     time.Hour,
+				TOTPIssuer:          "goss",
+				AuditRetention:      90 * 24 * time.Hour,
+				AuditPurgeInterval:  time.Hour,
+				AuditSampleInterval: time.Minute,
+			},
 			Storage: &storage.Config{
 				DB: &storage.DBConfig{
 					DriverName:      "postgres",
@@ -48,17 +70,51 @@ func TestNewConfig(t *testing.T) {
 				},
 			},
 			Security: &security.Config{
-				KeyPrefix:            "auth",
-				Secret:               []byte("secret"),
-				AccessTokenLifetime:  2 * time.Hour,
-				RefreshTokenLifetime: 30 * 24 * time.Hour,
+				KeyPrefix:                 "auth",
+				Secret:                    []byte("secret"),
+				SigningAlgorithm:          "HS256",
+				KeysReloadInterval:        time.Minute,
+				AccessTokenLifetime:       2 * time.Hour,
+				RefreshTokenLifetime:      30 * 24 * time.Hour,
+				ClientTokenLifetime:       time.Hour,
+				AuthorizationCodeLifetime: time.Minute,
+				MFATokenLifetime:          5 * time.Minute,
+				EncryptionKey:             "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U=",
+				PreviousEncryptionKeys:    []string{"b2xka2V5b2xka2V5b2xka2V5b2xka2V5b2xka2V5b2w="},
+				AuditKey:                  "YXVkaXRrZXlhdWRpdGtleWF1ZGl0a2V5YXVkaXRrZXk=",
 				RedisClient: &security.RedisClientConfig{
 					Addr: "redis:6379",
 				},
+				Password: &security.PasswordConfig{
+					Algorithm:     "bcrypt",
+					BcryptCost:    10,
+					Argon2Time:    1,
+					Argon2Memory:  64 * 1024,
+					Argon2Threads: 4,
+				},
+				Lockout: &security.LockoutConfig{
+					MaxAttempts:      5,
+					MaxAttemptsPerIP: 50,
+					Window:           15 * time.Minute,
+					Duration:         time.Minute,
+					MaxDuration:      time.Hour,
+				},
 			},
 			HTTP: &http.Config{
 				Address:     "127.0.0.1:8080",
 				ReadTimeout: 5 * time.Second,
+				RateLimit: &http.RateLimitConfig{
+					Backend: "memory",
+					Auth:    http.RateLimit{Key: "ip", Requests: 20, Period: time.Minute},
+					OAuth:   http.RateLimit{Key: "ip", Requests: 60, Period: time.Minute},
+					Bearer:  http.RateLimit{Key: "ip", Requests: 600, Period: time.Minute},
+					User:    http.RateLimit{Key: "user", Requests: 300, Period: time.Minute},
+				},
+			},
+			GRPC: &grpc.Config{
+				Address:     "127.0.0.1:9090",
+				TLSCertFile: "./configs/tls/cert.pem",
+				TLSKeyFile:  "./configs/tls/key.pem",
 			},
 		}
 
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+//APIKeyPrefix marks API keys, so they are told apart from JWT access tokens.
+const APIKeyPrefix = "goss_"
+
+const (
+	apiKeyEntropy       = 32
+	maxAPIKeyNameLength = 64
+)
+
+//newAPIKey generates an API key and its hash.
+func newAPIKey() (string, string, error) {
+	buf := make([]byte, apiKeyEntropy)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	return key, hashAPIKey(key), nil
+}
+
+//hashAPIKey hashes the key, keys are random enough to not need a slow hash.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func validateAPIKeyName(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		return ErrInvalidRequest
+	}
+
+	return nil
+}
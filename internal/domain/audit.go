@@ -0,0 +1,199 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"strconv"
+	"time"
+)
+
+//Audit event actions.
+const (
+	AuditActionLogin            = "login"
+	AuditActionLoginMFA         = "login_mfa"
+	AuditActionRefresh          = "refresh"
+	AuditActionLogout           = "logout"
+	AuditActionLogoutEverywhere = "logout_everywhere"
+	AuditActionTokenValidation  = "token_validation"
+)
+
+//Audit event outcomes.
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+const (
+	//DefaultAuditPageSize is the number of audit events listed when the limit is omitted.
+	DefaultAuditPageSize = 100
+
+	//MaxAuditPageSize is the maximum number of audit events listed at once.
+	MaxAuditPageSize = 1000
+
+	auditVerifyBatchSize = 1000
+)
+
+//AuditEvent describes an authentication event.
+//The actor is the user the event is about, the user id is zero when the user is unknown.
+//Each event is chained to the previous one by including its hash, so a changed or removed event breaks the chain.
+//Hashes are keyed with the audit key kept outside the database, so the chain can't be recomputed after changing events.
+type AuditEvent struct {
+	ID        int64     `json:"id"`
+	Time      time.Time `json:"time"`
+	UserID    int64     `json:"userID,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	Action    string    `json:"action"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"userAgent,omitempty"`
+	RequestID string    `json:"requestID,omitempty"`
+	PrevHash  string    `json:"prevHash"`
+	Hash      string    `json:"hash"`
+}
+
+//AuditFilter contains audit log query parameters, empty fields are not filtered.
+//The time range includes From and excludes To.
+type AuditFilter struct {
+	From    time.Time
+	To      time.Time
+	UserID  int64
+	Action  string
+	Outcome string
+	Cursor  string
+	Limit   int
+}
+
+//AuditVerification contains the result of the audit log verification.
+//Break is the first event that does not match the chain, it is nil if the whole chain is intact.
+//The last verified event id and hash should be compared with an external record, as a truncated tail keeps the chain intact.
+type AuditVerification struct {
+	Anchor      *AuditChainAnchor `json:"anchor"`
+	Verified    int64             `json:"verified"`
+	LastEventID int64             `json:"lastEventID,omitempty"`
+	LastHash    string            `json:"lastHash,omitempty"`
+	Break       *AuditChainBreak  `json:"break,omitempty"`
+}
+
+//AuditChainAnchor is the start of the audit log chain: events up to the event id were recorded before the chain
+//was introduced or purged by the retention, the next event must be chained to the hash.
+type AuditChainAnchor struct {
+	EventID int64  `json:"eventID"`
+	Hash    string `json:"hash"`
+}
+
+//AuditChainBreak describes the event that breaks the audit log chain.
+type AuditChainBreak struct {
+	EventID int64  `json:"eventID"`
+	Reason  string `json:"reason"`
+}
+
+//AuditCursor points to the last listed audit event, the next page starts after it.
+type AuditCursor struct {
+	ID int64 `json:"id"`
+}
+
+//AuditPage contains a page of audit events from newest to oldest and the cursor of the next page if there is one.
+type AuditPage struct {
+	Events     []*AuditEvent `json:"events"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+func encodeAuditCursor(event *AuditEvent) string {
+	data, _ := json.Marshal(&AuditCursor{ID: event.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeAuditCursor(value string) (*AuditCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidRequest
+	}
+
+	cursor := new(AuditCursor)
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, ErrInvalidRequest
+	}
+
+	return cursor, nil
+}
+
+//HashAuditEvent hashes the event fields together with the hash of the previous event with HMAC-SHA256 keyed by the audit key.
+//Time is hashed with microsecond precision as it is stored.
+func HashAuditEvent(key []byte, event *AuditEvent) string {
+	h := hmac.New(sha256.New, key)
+
+	writeHashField(h, event.PrevHash)
+	writeHashField(h, event.Time.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano))
+	writeHashField(h, strconv.FormatInt(event.UserID, 10))
+	writeHashField(h, event.Username)
+	writeHashField(h, event.Action)
+	writeHashField(h, event.Outcome)
+	writeHashField(h, event.Reason)
+	writeHashField(h, event.IP)
+	writeHashField(h, event.UserAgent)
+	writeHashField(h, event.RequestID)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+//writeHashField writes the length prefixed field, so different fields can't produce the same input.
+func writeHashField(h hash.Hash, field string) {
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(field)))
+
+	_, _ = h.Write(length[:])
+	_, _ = h.Write([]byte(field))
+}
+
+//VerifyAuditLog walks the audit log from the chain anchor and checks the hash chain.
+//Every event after the anchor must be hashed, so removed or blanked events at the chain start break it too.
+//The key must be the audit key the events were hashed with.
+func VerifyAuditLog(storage Storage, key []byte) (*AuditVerification, error) {
+	anchor, err := storage.GetAuditChainAnchor()
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &AuditVerification{Anchor: anchor}
+
+	afterID, prevHash := anchor.EventID, anchor.Hash
+
+	for {
+		events, err := storage.ListAuditChain(afterID, auditVerifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range events {
+			afterID = event.ID
+
+			switch {
+			case event.Hash == "":
+				verification.Break = &AuditChainBreak{EventID: event.ID, Reason: "missing hash"}
+			case event.PrevHash != prevHash:
+				verification.Break = &AuditChainBreak{EventID: event.ID, Reason: "previous hash mismatch"}
+			case !hmac.Equal([]byte(HashAuditEvent(key, event)), []byte(event.Hash)):
+				verification.Break = &AuditChainBreak{EventID: event.ID, Reason: "hash mismatch"}
+			}
+
+			if verification.Break != nil {
+				return verification, nil
+			}
+
+			prevHash = event.Hash
+			verification.Verified++
+			verification.LastEventID = event.ID
+			verification.LastHash = event.Hash
+		}
+
+		if len(events) < auditVerifyBatchSize {
+			return verification, nil
+		}
+	}
+}
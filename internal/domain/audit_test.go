@@ -0,0 +1,274 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+var testAuditKey = []byte("0123456789abcdef0123456789abcdef")
+
+//newAuditChain makes events chained the same way as the storage does.
+func newAuditChain(prevHash string, ids ...int64) []*AuditEvent {
+	events := make([]*AuditEvent, 0, len(ids))
+
+	for _, id := range ids {
+		event := &AuditEvent{
+			ID:       id,
+			Time:     time.Unix(1574208000+id, 0),
+			UserID:   id,
+			Action:   AuditActionLogin,
+			Outcome:  AuditOutcomeSuccess,
+			PrevHash: prevHash,
+		}
+		event.Hash = HashAuditEvent(testAuditKey, event)
+		prevHash = event.Hash
+
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func TestHashAuditEvent(t *testing.T) {
+	event := &AuditEvent{
+		Time:     time.Date(2019, 11, 20, 12, 0, 0, 123456789, time.UTC),
+		Username: "alice",
+		Action:   AuditActionLogin,
+		Outcome:  AuditOutcomeSuccess,
+	}
+	hash := HashAuditEvent(testAuditKey, event)
+
+	t.Run("stored time precision and zone", func(t *testing.T) {
+		stored := *event
+		stored.Time = event.Time.Truncate(time.Microsecond).In(time.FixedZone("MSK", 3*60*60))
+		require.Equal(t, hash, HashAuditEvent(testAuditKey, &stored))
+	})
+
+	t.Run("changed field", func(t *testing.T) {
+		changed := *event
+		changed.Outcome = AuditOutcomeFailure
+		require.NotEqual(t, hash, HashAuditEvent(testAuditKey, &changed))
+	})
+
+	t.Run("moved field boundary", func(t *testing.T) {
+		moved := *event
+		moved.Username = "alic"
+		moved.Reason = "e"
+		require.NotEqual(t, hash, HashAuditEvent(testAuditKey, &moved))
+	})
+
+	t.Run("different previous hash", func(t *testing.T) {
+		chained := *event
+		chained.PrevHash = hash
+		require.NotEqual(t, hash, HashAuditEvent(testAuditKey, &chained))
+	})
+
+	t.Run("different key", func(t *testing.T) {
+		require.NotEqual(t, hash, HashAuditEvent([]byte("fedcba9876543210fedcba9876543210"), event))
+	})
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	genesis := &AuditChainAnchor{}
+
+	t.Run("intact chain", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		anchor := &AuditChainAnchor{EventID: 1}
+		events := newAuditChain("", 2, 3, 4)
+
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetAuditChainAnchor().Return(anchor, nil)
+		storage.EXPECT().ListAuditChain(int64(1), auditVerifyBatchSize).Return(events, nil)
+
+		verification, err := VerifyAuditLog(storage, testAuditKey)
+		require.NoError(t, err)
+		require.Equal(t, &AuditVerification{
+			Anchor:      anchor,
+			Verified:    3,
+			LastEventID: 4,
+			LastHash:    events[2].Hash,
+		}, verification)
+	})
+
+	t.Run("purged chain start", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		purged := newAuditChain("", 1, 2, 3, 4, 5, 6)
+		anchor := &AuditChainAnchor{EventID: 4, Hash: purged[3].Hash}
+
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetAuditChainAnchor().Return(anchor, nil)
+		storage.EXPECT().ListAuditChain(int64(4), auditVerifyBatchSize).Return(purged[4:], nil)
+
+		verification, err := VerifyAuditLog(storage, testAuditKey)
+		require.NoError(t, err)
+		require.Equal(t, &AuditVerification{
+			Anchor:      anchor,
+			Verified:    2,
+			LastEventID: 6,
+			LastHash:    purged[5].Hash,
+		}, verification)
+	})
+
+	t.Run("deleted chain prefix", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		events := newAuditChain("", 1, 2, 3, 4)
+
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetAuditChainAnchor().Return(genesis, nil)
+		storage.EXPECT().ListAuditChain(int64(0), auditVerifyBatchSize).Return(events[2:], nil)
+
+		verification, err := VerifyAuditLog(storage, testAuditKey)
+		require.NoError(t, err)
+		require.Equal(t, &AuditVerification{
+			Anchor: genesis,
+			Break:  &AuditChainBreak{EventID: 3, Reason: "previous hash mismatch"},
+		}, verification)
+	})
+
+	t.Run("unhashed event", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		events := newAuditChain("", 1, 2, 3)
+		events[0].Hash = ""
+
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetAuditChainAnchor().Return(genesis, nil)
+		storage.EXPECT().ListAuditChain(int64(0), auditVerifyBatchSize).Return(events, nil)
+
+		verification, err := VerifyAuditLog(storage, testAuditKey)
+		require.NoError(t, err)
+		require.Equal(t, &AuditVerification{
+			Anchor: genesis,
+			Break:  &AuditChainBreak{EventID: 1, Reason: "missing hash"},
+		}, verification)
+	})
+
+	t.Run("truncated chain tail", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		events := newAuditChain("", 1, 2, 3, 4)
+
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetAuditChainAnchor().Return(genesis, nil)
+		storage.EXPECT().ListAuditChain(int64(0), auditVerifyBatchSize).Return(events[:2], nil)
+
+		verification, err := VerifyAuditLog(storage, testAuditKey)
+		require.NoError(t, err)
+		require.Equal(t, &AuditVerification{
+			Anchor:      genesis,
+			Verified:    2,
+			LastEventID: 2,
+			LastHash:    events[1].Hash,
+		}, verification)
+		require.NotEqual(t, events[3].Hash, verification.LastHash)
+	})
+
+	t.Run("changed event", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		events := newAuditChain("", 1, 2, 3)
+		events[1].Outcome = AuditOutcomeFailure
+
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetAuditChainAnchor().Return(genesis, nil)
+		storage.EXPECT().ListAuditChain(int64(0), auditVerifyBatchSize).Return(events, nil)
+
+		verification, err := VerifyAuditLog(storage, testAuditKey)
+		require.NoError(t, err)
+		require.Equal(t, &AuditVerification{
+			Anchor:      genesis,
+			Verified:    1,
+			LastEventID: 1,
+			LastHash:    events[0].Hash,
+			Break:       &AuditChainBreak{EventID: 2, Reason: "hash mismatch"},
+		}, verification)
+	})
+
+	t.Run("chain recomputed without the key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		events := newAuditChain("", 1, 2)
+		events[1].Outcome = AuditOutcomeFailure
+		events[1].Hash = HashAuditEvent([]byte("guessed"), events[1])
+
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetAuditChainAnchor().Return(genesis, nil)
+		storage.EXPECT().ListAuditChain(int64(0), auditVerifyBatchSize).Return(events, nil)
+
+		verification, err := VerifyAuditLog(storage, testAuditKey)
+		require.NoError(t, err)
+		require.Equal(t, &AuditChainBreak{EventID: 2, Reason: "hash mismatch"}, verification.Break)
+	})
+
+	t.Run("removed event", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		events := newAuditChain("", 1, 2, 3)
+		events = append(events[:1], events[2:]...)
+
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetAuditChainAnchor().Return(genesis, nil)
+		storage.EXPECT().ListAuditChain(int64(0), auditVerifyBatchSize).Return(events, nil)
+
+		verification, err := VerifyAuditLog(storage, testAuditKey)
+		require.NoError(t, err)
+		require.Equal(t, &AuditVerification{
+			Anchor:      genesis,
+			Verified:    1,
+			LastEventID: 1,
+			LastHash:    events[0].Hash,
+			Break:       &AuditChainBreak{EventID: 3, Reason: "previous hash mismatch"},
+		}, verification)
+	})
+
+	t.Run("several batches", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		ids := make([]int64, auditVerifyBatchSize+1)
+		for i := range ids {
+			ids[i] = int64(i + 1)
+		}
+		events := newAuditChain("", ids...)
+
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetAuditChainAnchor().Return(genesis, nil)
+		storage.EXPECT().ListAuditChain(int64(0), auditVerifyBatchSize).Return(events[:auditVerifyBatchSize], nil)
+		storage.EXPECT().ListAuditChain(int64(auditVerifyBatchSize), auditVerifyBatchSize).Return(events[auditVerifyBatchSize:], nil)
+
+		verification, err := VerifyAuditLog(storage, testAuditKey)
+		require.NoError(t, err)
+		require.Equal(t, &AuditVerification{
+			Anchor:      genesis,
+			Verified:    auditVerifyBatchSize + 1,
+			LastEventID: auditVerifyBatchSize + 1,
+			LastHash:    events[auditVerifyBatchSize].Hash,
+		}, verification)
+	})
+
+	t.Run("with broken storage", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetAuditChainAnchor().Return(genesis, nil)
+		storage.EXPECT().ListAuditChain(int64(0), auditVerifyBatchSize).Return(nil, ErrInternalStorage)
+
+		_, err := VerifyAuditLog(storage, testAuditKey)
+		require.Equal(t, ErrInternalStorage, err)
+	})
+
+	t.Run("without anchor", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetAuditChainAnchor().Return(nil, ErrInternalStorage)
+
+		_, err := VerifyAuditLog(storage, testAuditKey)
+		require.Equal(t, ErrInternalStorage, err)
+	})
+}
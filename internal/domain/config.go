@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+//Config contains a service configuration.
+type Config struct {
+	RegistrationEnabled bool          `default:"true"`
+	DefaultRole         string        `default:"user" validate:"required"`
+	Issuer              string        `validate:"required,url"`
+	IDTokenLifetime     time.Duration `default:"1h"`
+	TOTPIssuer          string        `default:"goss" validate:"required"`
+	AuditRetention      time.Duration `default:"2160h"`
+	AuditPurgeInterval  time.Duration `default:"1h" validate:"required"`
+	AuditSampleInterval time.Duration `default:"1m"`
+}
@@ -0,0 +1,41 @@
+package domain
+
+const (
+	//GrantTypeClientCredentials is the OAuth 2.0 client credentials grant type.
+	GrantTypeClientCredentials = "client_credentials"
+
+	//GrantTypeAuthorizationCode is the OAuth 2.0 authorization code grant type.
+	GrantTypeAuthorizationCode = "authorization_code"
+
+	//ResponseTypeCode is the OAuth 2.0 authorization code response type.
+	ResponseTypeCode = "code"
+
+	//CodeChallengeMethodS256 is the PKCE code challenge method.
+	CodeChallengeMethodS256 = "S256"
+
+	//ScopeOpenID is the OpenID Connect scope requesting an ID token.
+	ScopeOpenID = "openid"
+
+	//ScopeAll is granted to direct logins without a requested scope, their tokens are limited only by the role.
+	ScopeAll = "*"
+
+	//TokenTypeAccessToken is the OAuth 2.0 access token type identifier (RFC 7009).
+	TokenTypeAccessToken = "access_token"
+
+	//TokenTypeRefreshToken is the OAuth 2.0 refresh token type identifier (RFC 7009).
+	TokenTypeRefreshToken = "refresh_token"
+
+	tokenTypeBearer = "Bearer"
+
+	signingAlgorithmHS256 = "HS256"
+)
+
+//Endpoints published in the OpenID Connect discovery document.
+const (
+	authorizationEndpoint = "/v1/oauth/authorize"
+	tokenEndpoint         = "/v1/oauth/token"
+	introspectionEndpoint = "/v1/oauth/introspect"
+	revocationEndpoint    = "/v1/oauth/revoke"
+	userInfoEndpoint      = "/v1/oidc/userinfo"
+	jwksEndpoint          = "/.well-known/jwks.json"
+)
@@ -1,6 +1,10 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	//ErrInternalStorage represents the internal storage error.
@@ -20,4 +24,84 @@ var (
 
 	//ErrInvalidRefreshToken represents the invalid refresh token error.
 	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+	//ErrRefreshTokenReused represents the already rotated refresh token reuse error.
+	ErrRefreshTokenReused = errors.New("refresh token reused, the session has been revoked")
+
+	//ErrInvalidUsername represents the invalid username error.
+	ErrInvalidUsername = errors.New("username must be 3 to 32 characters long and contain only letters, digits, '_', '.' or '-'")
+
+	//ErrInvalidPassword represents the invalid password error.
+	ErrInvalidPassword = errors.New("password must be 8 to 72 bytes long")
+
+	//ErrUserAlreadyExists represents the user already exists error.
+	ErrUserAlreadyExists = errors.New("user already exists")
+
+	//ErrRegistrationDisabled represents the registration disabled error.
+	ErrRegistrationDisabled = errors.New("registration disabled")
+
+	//ErrInvalidRequest represents the invalid OAuth 2.0 request error.
+	ErrInvalidRequest = errors.New("invalid request")
+
+	//ErrInvalidClient represents the OAuth 2.0 client authentication error.
+	ErrInvalidClient = errors.New("invalid client")
+
+	//ErrInvalidScope represents the invalid or not allowed scope error.
+	ErrInvalidScope = errors.New("invalid scope")
+
+	//ErrUnsupportedGrantType represents the unsupported OAuth 2.0 grant type error.
+	ErrUnsupportedGrantType = errors.New("unsupported grant type")
+
+	//ErrInvalidGrant represents the invalid, expired or already used authorization grant error.
+	ErrInvalidGrant = errors.New("invalid grant")
+
+	//ErrUnauthorizedClient represents the client not allowed to use the grant type error.
+	ErrUnauthorizedClient = errors.New("unauthorized client")
+
+	//ErrInvalidRedirectURI represents the missing or not registered redirect uri error.
+	ErrInvalidRedirectURI = errors.New("invalid redirect uri")
+
+	//ErrUnsupportedResponseType represents the unsupported OAuth 2.0 response type error.
+	ErrUnsupportedResponseType = errors.New("unsupported response type")
+
+	//ErrInvalidMFAToken represents the invalid or expired MFA token error.
+	ErrInvalidMFAToken = errors.New("invalid mfa token")
+
+	//ErrInvalidMFACode represents the invalid one-time code error.
+	ErrInvalidMFACode = errors.New("invalid mfa code")
+
+	//ErrMFARequired represents the error of signing in without the second factor.
+	ErrMFARequired = errors.New("two-factor authentication required")
+
+	//ErrMFAAlreadyEnabled represents the two-factor authentication already enabled error.
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication already enabled")
+
+	//ErrMFANotEnabled represents the two-factor authentication not enabled error.
+	ErrMFANotEnabled = errors.New("two-factor authentication not enabled")
+
+	//ErrTooManyAttempts represents the too many failed login attempts error.
+	ErrTooManyAttempts = errors.New("too many failed login attempts")
+
+	//ErrPermissionDenied represents the missing permission error.
+	ErrPermissionDenied = errors.New("permission denied")
+
+	//ErrUserDisabled represents the disabled user error.
+	ErrUserDisabled = errors.New("user disabled")
+
+	//ErrInvalidRole represents the unknown role error.
+	ErrInvalidRole = errors.New("unknown role")
 )
+
+//LockoutError represents the temporary login lockout, it matches ErrTooManyAttempts.
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.RetryAfter)
+}
+
+//Is reports whether the target is ErrTooManyAttempts.
+func (e *LockoutError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
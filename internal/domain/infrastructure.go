@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 //go:generate mockgen -package $GOPACKAGE -source $GOFILE -destination mock_$GOFILE -self_package=github.com/lzakharov/goss/internal/domain/$GOPACKAGE
 
 //Mortal represents a service that can be up or down.
@@ -13,14 +15,81 @@ type Storage interface {
 
 	GetUser(userID int64) (*User, error)
 	GetUserByCredentials(credentials *Credentials) (*User, error)
+	//ListUsers returns at most MaxUserPageSize users per call and whether there are more after them.
+	ListUsers(filter *UserFilter, after *UserCursor) ([]*User, bool, error)
+	CreateUser(credentials *Credentials, role string) (*User, error)
+	UpdateUser(user *User) (*User, error)
+	SetUserDisabled(userID int64, disabled bool) error
+	UpdateUserPassword(userID int64, password string) error
+	DeleteUser(userID int64) error
+	GetClient(clientID string) (*Client, error)
+	GetClientByCredentials(credentials *ClientCredentials) (*Client, error)
+	GetTOTP(userID int64) (*TOTP, error)
+	SaveTOTP(userID int64, secret string) error
+	ConfirmTOTP(userID int64, step int64) error
+	UseTOTPStep(userID int64, step int64) error
+	DeleteTOTP(userID int64) error
+	//ReencryptTOTPSecrets re-encrypts all TOTP secrets with the current encryption key after a key rotation.
+	ReencryptTOTPSecrets() (int64, error)
+	ReplaceRecoveryCodes(userID int64, hashes []string) error
+	UseRecoveryCode(userID int64, hash string) error
+	CountRecoveryCodes(userID int64) (int, error)
+	CreateAPIKey(key *APIKey, hash string) (*APIKey, error)
+	ListAPIKeys(userID int64) ([]*APIKey, error)
+	//UseAPIKey gets the unexpired API key by the hash and updates its last used time at most once a minute.
+	UseAPIKey(hash string) (*APIKey, error)
+	DeleteAPIKey(userID, keyID int64) error
+	GetPermissions(role string) ([]string, error)
+	RoleExists(role string) (bool, error)
+	//AddAuditEvent chains the event to the last one and saves it.
+	AddAuditEvent(event *AuditEvent) error
+	//ListAuditEvents returns at most MaxAuditPageSize events per call and whether there are more after them.
+	ListAuditEvents(filter *AuditFilter, after *AuditCursor) ([]*AuditEvent, bool, error)
+	//ListAuditChain returns up to the limit of events after the event id from oldest to newest.
+	ListAuditChain(afterID int64, limit int) ([]*AuditEvent, error)
+	GetAuditChainAnchor() (*AuditChainAnchor, error)
+	//DeleteAuditEvents deletes the oldest events up to the last one before the time and moves the chain anchor to it.
+	DeleteAuditEvents(before time.Time) (int64, error)
 }
 
 //Security represents a security adapter.
 type Security interface {
 	Mortal
 
-	CreateAuthData(user *User) (*AuthData, error)
+	//CreateAuthData starts a session for the client the user authorized, the client id is empty for direct logins.
+	CreateAuthData(user *User, clientID string, scopes []string) (*AuthData, error)
+	//RefreshAuthData rotates the session tokens if the refresh token is still the latest one of the session.
+	RefreshAuthData(user *User, sessionID, refreshToken string) (*AuthData, error)
+	CreateClientAuthData(client *Client, scopes []string) (*AuthData, error)
+	CreateAuthorizationCode(code *AuthorizationCode) (string, error)
+	ConsumeAuthorizationCode(code string) (*AuthorizationCode, error)
+	CreateIDToken(claims *IDTokenClaims) (string, error)
+	CreateMFAToken(user *User) (string, error)
+	ConsumeMFAToken(mfaToken string) (int64, error)
+	CheckLoginAttempts(username, ip string) error
+	AddFailedLoginAttempt(username, ip string) error
+	ResetLoginAttempts(username string) error
 	GetAccessTokenClaims(accessToken string) (*AccessTokenClaims, error)
 	GetRefreshTokenClaims(refreshToken string) (*RefreshTokenClaims, error)
+	//IdentifyToken returns ErrNotFound for invalid, expired and already revoked tokens.
+	IdentifyToken(token string) (*TokenInfo, error)
+	RevokeToken(info *TokenInfo) error
+	GetSessions(userID int64) ([]*Session, error)
+	InvalidateSession(userID int64, sessionID string) error
 	InvalidateUserAuthData(userID int64) error
+	GetJSONWebKeySet() *JSONWebKeySet
+	GetSigningAlgorithm() string
+}
+
+//PasswordHasher represents a password hasher.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Compare(hash, password string) error
+	NeedsRehash(hash string) bool
+}
+
+//Encrypter represents a symmetric encrypter of sensitive data at rest.
+type Encrypter interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
 }
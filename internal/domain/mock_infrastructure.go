@@ -7,6 +7,7 @@ package domain
 import (
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
+	time "time"
 )
 
 // MockMortal is a mock of Mortal interface
@@ -69,18 +70,209 @@ func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
 	return m.recorder
 }
 
-// IsAlive mocks base method
-func (m *MockStorage) IsAlive() bool {
+// AddAuditEvent mocks base method
+func (m *MockStorage) AddAuditEvent(event *AuditEvent) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "IsAlive")
-	ret0, _ := ret[0].(bool)
+	ret := m.ctrl.Call(m, "AddAuditEvent", event)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// IsAlive indicates an expected call of IsAlive
-func (mr *MockStorageMockRecorder) IsAlive() *gomock.Call {
+// AddAuditEvent indicates an expected call of AddAuditEvent
+func (mr *MockStorageMockRecorder) AddAuditEvent(event interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsAlive", reflect.TypeOf((*MockStorage)(nil).IsAlive))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAuditEvent", reflect.TypeOf((*MockStorage)(nil).AddAuditEvent), event)
+}
+
+// ConfirmTOTP mocks base method
+func (m *MockStorage) ConfirmTOTP(userID, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTOTP", userID, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConfirmTOTP indicates an expected call of ConfirmTOTP
+func (mr *MockStorageMockRecorder) ConfirmTOTP(userID, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTOTP", reflect.TypeOf((*MockStorage)(nil).ConfirmTOTP), userID, step)
+}
+
+// CountRecoveryCodes mocks base method
+func (m *MockStorage) CountRecoveryCodes(userID int64) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountRecoveryCodes", userID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountRecoveryCodes indicates an expected call of CountRecoveryCodes
+func (mr *MockStorageMockRecorder) CountRecoveryCodes(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountRecoveryCodes", reflect.TypeOf((*MockStorage)(nil).CountRecoveryCodes), userID)
+}
+
+// CreateAPIKey mocks base method
+func (m *MockStorage) CreateAPIKey(key *APIKey, hash string) (*APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", key, hash)
+	ret0, _ := ret[0].(*APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey
+func (mr *MockStorageMockRecorder) CreateAPIKey(key, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockStorage)(nil).CreateAPIKey), key, hash)
+}
+
+// CreateUser mocks base method
+func (m *MockStorage) CreateUser(credentials *Credentials, role string) (*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", credentials, role)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser
+func (mr *MockStorageMockRecorder) CreateUser(credentials, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockStorage)(nil).CreateUser), credentials, role)
+}
+
+// DeleteAPIKey mocks base method
+func (m *MockStorage) DeleteAPIKey(userID, keyID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAPIKey", userID, keyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAPIKey indicates an expected call of DeleteAPIKey
+func (mr *MockStorageMockRecorder) DeleteAPIKey(userID, keyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIKey", reflect.TypeOf((*MockStorage)(nil).DeleteAPIKey), userID, keyID)
+}
+
+// DeleteAuditEvents mocks base method
+func (m *MockStorage) DeleteAuditEvents(before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAuditEvents", before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteAuditEvents indicates an expected call of DeleteAuditEvents
+func (mr *MockStorageMockRecorder) DeleteAuditEvents(before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAuditEvents", reflect.TypeOf((*MockStorage)(nil).DeleteAuditEvents), before)
+}
+
+// DeleteTOTP mocks base method
+func (m *MockStorage) DeleteTOTP(userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTOTP", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTOTP indicates an expected call of DeleteTOTP
+func (mr *MockStorageMockRecorder) DeleteTOTP(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTOTP", reflect.TypeOf((*MockStorage)(nil).DeleteTOTP), userID)
+}
+
+// DeleteUser mocks base method
+func (m *MockStorage) DeleteUser(userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser
+func (mr *MockStorageMockRecorder) DeleteUser(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockStorage)(nil).DeleteUser), userID)
+}
+
+// GetAuditChainAnchor mocks base method
+func (m *MockStorage) GetAuditChainAnchor() (*AuditChainAnchor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuditChainAnchor")
+	ret0, _ := ret[0].(*AuditChainAnchor)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuditChainAnchor indicates an expected call of GetAuditChainAnchor
+func (mr *MockStorageMockRecorder) GetAuditChainAnchor() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditChainAnchor", reflect.TypeOf((*MockStorage)(nil).GetAuditChainAnchor))
+}
+
+// GetClient mocks base method
+func (m *MockStorage) GetClient(clientID string) (*Client, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClient", clientID)
+	ret0, _ := ret[0].(*Client)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClient indicates an expected call of GetClient
+func (mr *MockStorageMockRecorder) GetClient(clientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClient", reflect.TypeOf((*MockStorage)(nil).GetClient), clientID)
+}
+
+// GetClientByCredentials mocks base method
+func (m *MockStorage) GetClientByCredentials(credentials *ClientCredentials) (*Client, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClientByCredentials", credentials)
+	ret0, _ := ret[0].(*Client)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClientByCredentials indicates an expected call of GetClientByCredentials
+func (mr *MockStorageMockRecorder) GetClientByCredentials(credentials interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClientByCredentials", reflect.TypeOf((*MockStorage)(nil).GetClientByCredentials), credentials)
+}
+
+// GetPermissions mocks base method
+func (m *MockStorage) GetPermissions(role string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPermissions", role)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPermissions indicates an expected call of GetPermissions
+func (mr *MockStorageMockRecorder) GetPermissions(role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPermissions", reflect.TypeOf((*MockStorage)(nil).GetPermissions), role)
+}
+
+// GetTOTP mocks base method
+func (m *MockStorage) GetTOTP(userID int64) (*TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTOTP", userID)
+	ret0, _ := ret[0].(*TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTOTP indicates an expected call of GetTOTP
+func (mr *MockStorageMockRecorder) GetTOTP(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTOTP", reflect.TypeOf((*MockStorage)(nil).GetTOTP), userID)
 }
 
 // GetUser mocks base method
@@ -113,6 +305,226 @@ func (mr *MockStorageMockRecorder) GetUserByCredentials(credentials interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByCredentials", reflect.TypeOf((*MockStorage)(nil).GetUserByCredentials), credentials)
 }
 
+// IsAlive mocks base method
+func (m *MockStorage) IsAlive() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsAlive")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsAlive indicates an expected call of IsAlive
+func (mr *MockStorageMockRecorder) IsAlive() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsAlive", reflect.TypeOf((*MockStorage)(nil).IsAlive))
+}
+
+// ListAPIKeys mocks base method
+func (m *MockStorage) ListAPIKeys(userID int64) ([]*APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeys", userID)
+	ret0, _ := ret[0].([]*APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPIKeys indicates an expected call of ListAPIKeys
+func (mr *MockStorageMockRecorder) ListAPIKeys(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeys", reflect.TypeOf((*MockStorage)(nil).ListAPIKeys), userID)
+}
+
+// ListAuditChain mocks base method
+func (m *MockStorage) ListAuditChain(afterID int64, limit int) ([]*AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditChain", afterID, limit)
+	ret0, _ := ret[0].([]*AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditChain indicates an expected call of ListAuditChain
+func (mr *MockStorageMockRecorder) ListAuditChain(afterID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditChain", reflect.TypeOf((*MockStorage)(nil).ListAuditChain), afterID, limit)
+}
+
+// ListAuditEvents mocks base method
+func (m *MockStorage) ListAuditEvents(filter *AuditFilter, after *AuditCursor) ([]*AuditEvent, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditEvents", filter, after)
+	ret0, _ := ret[0].([]*AuditEvent)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListAuditEvents indicates an expected call of ListAuditEvents
+func (mr *MockStorageMockRecorder) ListAuditEvents(filter, after interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditEvents", reflect.TypeOf((*MockStorage)(nil).ListAuditEvents), filter, after)
+}
+
+// ListUsers mocks base method
+func (m *MockStorage) ListUsers(filter *UserFilter, after *UserCursor) ([]*User, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", filter, after)
+	ret0, _ := ret[0].([]*User)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListUsers indicates an expected call of ListUsers
+func (mr *MockStorageMockRecorder) ListUsers(filter, after interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockStorage)(nil).ListUsers), filter, after)
+}
+
+// ReencryptTOTPSecrets mocks base method
+func (m *MockStorage) ReencryptTOTPSecrets() (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReencryptTOTPSecrets")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReencryptTOTPSecrets indicates an expected call of ReencryptTOTPSecrets
+func (mr *MockStorageMockRecorder) ReencryptTOTPSecrets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReencryptTOTPSecrets", reflect.TypeOf((*MockStorage)(nil).ReencryptTOTPSecrets))
+}
+
+// ReplaceRecoveryCodes mocks base method
+func (m *MockStorage) ReplaceRecoveryCodes(userID int64, hashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceRecoveryCodes", userID, hashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceRecoveryCodes indicates an expected call of ReplaceRecoveryCodes
+func (mr *MockStorageMockRecorder) ReplaceRecoveryCodes(userID, hashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceRecoveryCodes", reflect.TypeOf((*MockStorage)(nil).ReplaceRecoveryCodes), userID, hashes)
+}
+
+// RoleExists mocks base method
+func (m *MockStorage) RoleExists(role string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RoleExists", role)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RoleExists indicates an expected call of RoleExists
+func (mr *MockStorageMockRecorder) RoleExists(role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RoleExists", reflect.TypeOf((*MockStorage)(nil).RoleExists), role)
+}
+
+// SaveTOTP mocks base method
+func (m *MockStorage) SaveTOTP(userID int64, secret string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTOTP", userID, secret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTOTP indicates an expected call of SaveTOTP
+func (mr *MockStorageMockRecorder) SaveTOTP(userID, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTOTP", reflect.TypeOf((*MockStorage)(nil).SaveTOTP), userID, secret)
+}
+
+// SetUserDisabled mocks base method
+func (m *MockStorage) SetUserDisabled(userID int64, disabled bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserDisabled", userID, disabled)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserDisabled indicates an expected call of SetUserDisabled
+func (mr *MockStorageMockRecorder) SetUserDisabled(userID, disabled interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserDisabled", reflect.TypeOf((*MockStorage)(nil).SetUserDisabled), userID, disabled)
+}
+
+// UpdateUser mocks base method
+func (m *MockStorage) UpdateUser(user *User) (*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUser", user)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUser indicates an expected call of UpdateUser
+func (mr *MockStorageMockRecorder) UpdateUser(user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockStorage)(nil).UpdateUser), user)
+}
+
+// UpdateUserPassword mocks base method
+func (m *MockStorage) UpdateUserPassword(userID int64, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserPassword", userID, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserPassword indicates an expected call of UpdateUserPassword
+func (mr *MockStorageMockRecorder) UpdateUserPassword(userID, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockStorage)(nil).UpdateUserPassword), userID, password)
+}
+
+// UseAPIKey mocks base method
+func (m *MockStorage) UseAPIKey(hash string) (*APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseAPIKey", hash)
+	ret0, _ := ret[0].(*APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseAPIKey indicates an expected call of UseAPIKey
+func (mr *MockStorageMockRecorder) UseAPIKey(hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseAPIKey", reflect.TypeOf((*MockStorage)(nil).UseAPIKey), hash)
+}
+
+// UseRecoveryCode mocks base method
+func (m *MockStorage) UseRecoveryCode(userID int64, hash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", userID, hash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode
+func (mr *MockStorageMockRecorder) UseRecoveryCode(userID, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockStorage)(nil).UseRecoveryCode), userID, hash)
+}
+
+// UseTOTPStep mocks base method
+func (m *MockStorage) UseTOTPStep(userID, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPStep", userID, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseTOTPStep indicates an expected call of UseTOTPStep
+func (mr *MockStorageMockRecorder) UseTOTPStep(userID, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPStep", reflect.TypeOf((*MockStorage)(nil).UseTOTPStep), userID, step)
+}
+
 // MockSecurity is a mock of Security interface
 type MockSecurity struct {
 	ctrl     *gomock.Controller
@@ -136,33 +548,137 @@ func (m *MockSecurity) EXPECT() *MockSecurityMockRecorder {
 	return m.recorder
 }
 
-// IsAlive mocks base method
-func (m *MockSecurity) IsAlive() bool {
+// AddFailedLoginAttempt mocks base method
+func (m *MockSecurity) AddFailedLoginAttempt(username, ip string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "IsAlive")
-	ret0, _ := ret[0].(bool)
+	ret := m.ctrl.Call(m, "AddFailedLoginAttempt", username, ip)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// IsAlive indicates an expected call of IsAlive
-func (mr *MockSecurityMockRecorder) IsAlive() *gomock.Call {
+// AddFailedLoginAttempt indicates an expected call of AddFailedLoginAttempt
+func (mr *MockSecurityMockRecorder) AddFailedLoginAttempt(username, ip interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsAlive", reflect.TypeOf((*MockSecurity)(nil).IsAlive))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddFailedLoginAttempt", reflect.TypeOf((*MockSecurity)(nil).AddFailedLoginAttempt), username, ip)
+}
+
+// CheckLoginAttempts mocks base method
+func (m *MockSecurity) CheckLoginAttempts(username, ip string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckLoginAttempts", username, ip)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckLoginAttempts indicates an expected call of CheckLoginAttempts
+func (mr *MockSecurityMockRecorder) CheckLoginAttempts(username, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckLoginAttempts", reflect.TypeOf((*MockSecurity)(nil).CheckLoginAttempts), username, ip)
+}
+
+// ConsumeAuthorizationCode mocks base method
+func (m *MockSecurity) ConsumeAuthorizationCode(code string) (*AuthorizationCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeAuthorizationCode", code)
+	ret0, _ := ret[0].(*AuthorizationCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeAuthorizationCode indicates an expected call of ConsumeAuthorizationCode
+func (mr *MockSecurityMockRecorder) ConsumeAuthorizationCode(code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeAuthorizationCode", reflect.TypeOf((*MockSecurity)(nil).ConsumeAuthorizationCode), code)
+}
+
+// ConsumeMFAToken mocks base method
+func (m *MockSecurity) ConsumeMFAToken(mfaToken string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeMFAToken", mfaToken)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeMFAToken indicates an expected call of ConsumeMFAToken
+func (mr *MockSecurityMockRecorder) ConsumeMFAToken(mfaToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeMFAToken", reflect.TypeOf((*MockSecurity)(nil).ConsumeMFAToken), mfaToken)
 }
 
 // CreateAuthData mocks base method
-func (m *MockSecurity) CreateAuthData(user *User) (*AuthData, error) {
+func (m *MockSecurity) CreateAuthData(user *User, clientID string, scopes []string) (*AuthData, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAuthData", user)
+	ret := m.ctrl.Call(m, "CreateAuthData", user, clientID, scopes)
 	ret0, _ := ret[0].(*AuthData)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateAuthData indicates an expected call of CreateAuthData
-func (mr *MockSecurityMockRecorder) CreateAuthData(user interface{}) *gomock.Call {
+func (mr *MockSecurityMockRecorder) CreateAuthData(user, clientID, scopes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuthData", reflect.TypeOf((*MockSecurity)(nil).CreateAuthData), user, clientID, scopes)
+}
+
+// CreateAuthorizationCode mocks base method
+func (m *MockSecurity) CreateAuthorizationCode(code *AuthorizationCode) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuthorizationCode", code)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAuthorizationCode indicates an expected call of CreateAuthorizationCode
+func (mr *MockSecurityMockRecorder) CreateAuthorizationCode(code interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuthData", reflect.TypeOf((*MockSecurity)(nil).CreateAuthData), user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuthorizationCode", reflect.TypeOf((*MockSecurity)(nil).CreateAuthorizationCode), code)
+}
+
+// CreateClientAuthData mocks base method
+func (m *MockSecurity) CreateClientAuthData(client *Client, scopes []string) (*AuthData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateClientAuthData", client, scopes)
+	ret0, _ := ret[0].(*AuthData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateClientAuthData indicates an expected call of CreateClientAuthData
+func (mr *MockSecurityMockRecorder) CreateClientAuthData(client, scopes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateClientAuthData", reflect.TypeOf((*MockSecurity)(nil).CreateClientAuthData), client, scopes)
+}
+
+// CreateIDToken mocks base method
+func (m *MockSecurity) CreateIDToken(claims *IDTokenClaims) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIDToken", claims)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateIDToken indicates an expected call of CreateIDToken
+func (mr *MockSecurityMockRecorder) CreateIDToken(claims interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIDToken", reflect.TypeOf((*MockSecurity)(nil).CreateIDToken), claims)
+}
+
+// CreateMFAToken mocks base method
+func (m *MockSecurity) CreateMFAToken(user *User) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateMFAToken", user)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateMFAToken indicates an expected call of CreateMFAToken
+func (mr *MockSecurityMockRecorder) CreateMFAToken(user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMFAToken", reflect.TypeOf((*MockSecurity)(nil).CreateMFAToken), user)
 }
 
 // GetAccessTokenClaims mocks base method
@@ -180,6 +696,20 @@ func (mr *MockSecurityMockRecorder) GetAccessTokenClaims(accessToken interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccessTokenClaims", reflect.TypeOf((*MockSecurity)(nil).GetAccessTokenClaims), accessToken)
 }
 
+// GetJSONWebKeySet mocks base method
+func (m *MockSecurity) GetJSONWebKeySet() *JSONWebKeySet {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJSONWebKeySet")
+	ret0, _ := ret[0].(*JSONWebKeySet)
+	return ret0
+}
+
+// GetJSONWebKeySet indicates an expected call of GetJSONWebKeySet
+func (mr *MockSecurityMockRecorder) GetJSONWebKeySet() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJSONWebKeySet", reflect.TypeOf((*MockSecurity)(nil).GetJSONWebKeySet))
+}
+
 // GetRefreshTokenClaims mocks base method
 func (m *MockSecurity) GetRefreshTokenClaims(refreshToken string) (*RefreshTokenClaims, error) {
 	m.ctrl.T.Helper()
@@ -195,6 +725,64 @@ func (mr *MockSecurityMockRecorder) GetRefreshTokenClaims(refreshToken interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshTokenClaims", reflect.TypeOf((*MockSecurity)(nil).GetRefreshTokenClaims), refreshToken)
 }
 
+// GetSessions mocks base method
+func (m *MockSecurity) GetSessions(userID int64) ([]*Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", userID)
+	ret0, _ := ret[0].([]*Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessions indicates an expected call of GetSessions
+func (mr *MockSecurityMockRecorder) GetSessions(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockSecurity)(nil).GetSessions), userID)
+}
+
+// GetSigningAlgorithm mocks base method
+func (m *MockSecurity) GetSigningAlgorithm() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSigningAlgorithm")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetSigningAlgorithm indicates an expected call of GetSigningAlgorithm
+func (mr *MockSecurityMockRecorder) GetSigningAlgorithm() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSigningAlgorithm", reflect.TypeOf((*MockSecurity)(nil).GetSigningAlgorithm))
+}
+
+// IdentifyToken mocks base method
+func (m *MockSecurity) IdentifyToken(token string) (*TokenInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IdentifyToken", token)
+	ret0, _ := ret[0].(*TokenInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IdentifyToken indicates an expected call of IdentifyToken
+func (mr *MockSecurityMockRecorder) IdentifyToken(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IdentifyToken", reflect.TypeOf((*MockSecurity)(nil).IdentifyToken), token)
+}
+
+// InvalidateSession mocks base method
+func (m *MockSecurity) InvalidateSession(userID int64, sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvalidateSession", userID, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InvalidateSession indicates an expected call of InvalidateSession
+func (mr *MockSecurityMockRecorder) InvalidateSession(userID, sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateSession", reflect.TypeOf((*MockSecurity)(nil).InvalidateSession), userID, sessionID)
+}
+
 // InvalidateUserAuthData mocks base method
 func (m *MockSecurity) InvalidateUserAuthData(userID int64) error {
 	m.ctrl.T.Helper()
@@ -208,3 +796,179 @@ func (mr *MockSecurityMockRecorder) InvalidateUserAuthData(userID interface{}) *
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateUserAuthData", reflect.TypeOf((*MockSecurity)(nil).InvalidateUserAuthData), userID)
 }
+
+// IsAlive mocks base method
+func (m *MockSecurity) IsAlive() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsAlive")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsAlive indicates an expected call of IsAlive
+func (mr *MockSecurityMockRecorder) IsAlive() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsAlive", reflect.TypeOf((*MockSecurity)(nil).IsAlive))
+}
+
+// RefreshAuthData mocks base method
+func (m *MockSecurity) RefreshAuthData(user *User, sessionID, refreshToken string) (*AuthData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshAuthData", user, sessionID, refreshToken)
+	ret0, _ := ret[0].(*AuthData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshAuthData indicates an expected call of RefreshAuthData
+func (mr *MockSecurityMockRecorder) RefreshAuthData(user, sessionID, refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshAuthData", reflect.TypeOf((*MockSecurity)(nil).RefreshAuthData), user, sessionID, refreshToken)
+}
+
+// ResetLoginAttempts mocks base method
+func (m *MockSecurity) ResetLoginAttempts(username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetLoginAttempts", username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetLoginAttempts indicates an expected call of ResetLoginAttempts
+func (mr *MockSecurityMockRecorder) ResetLoginAttempts(username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetLoginAttempts", reflect.TypeOf((*MockSecurity)(nil).ResetLoginAttempts), username)
+}
+
+// RevokeToken mocks base method
+func (m *MockSecurity) RevokeToken(info *TokenInfo) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeToken", info)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeToken indicates an expected call of RevokeToken
+func (mr *MockSecurityMockRecorder) RevokeToken(info interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeToken", reflect.TypeOf((*MockSecurity)(nil).RevokeToken), info)
+}
+
+// MockPasswordHasher is a mock of PasswordHasher interface
+type MockPasswordHasher struct {
+	ctrl     *gomock.Controller
+	recorder *MockPasswordHasherMockRecorder
+}
+
+// MockPasswordHasherMockRecorder is the mock recorder for MockPasswordHasher
+type MockPasswordHasherMockRecorder struct {
+	mock *MockPasswordHasher
+}
+
+// NewMockPasswordHasher creates a new mock instance
+func NewMockPasswordHasher(ctrl *gomock.Controller) *MockPasswordHasher {
+	mock := &MockPasswordHasher{ctrl: ctrl}
+	mock.recorder = &MockPasswordHasherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockPasswordHasher) EXPECT() *MockPasswordHasherMockRecorder {
+	return m.recorder
+}
+
+// Compare mocks base method
+func (m *MockPasswordHasher) Compare(hash, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Compare", hash, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Compare indicates an expected call of Compare
+func (mr *MockPasswordHasherMockRecorder) Compare(hash, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Compare", reflect.TypeOf((*MockPasswordHasher)(nil).Compare), hash, password)
+}
+
+// Hash mocks base method
+func (m *MockPasswordHasher) Hash(password string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Hash", password)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Hash indicates an expected call of Hash
+func (mr *MockPasswordHasherMockRecorder) Hash(password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Hash", reflect.TypeOf((*MockPasswordHasher)(nil).Hash), password)
+}
+
+// NeedsRehash mocks base method
+func (m *MockPasswordHasher) NeedsRehash(hash string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NeedsRehash", hash)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// NeedsRehash indicates an expected call of NeedsRehash
+func (mr *MockPasswordHasherMockRecorder) NeedsRehash(hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NeedsRehash", reflect.TypeOf((*MockPasswordHasher)(nil).NeedsRehash), hash)
+}
+
+// MockEncrypter is a mock of Encrypter interface
+type MockEncrypter struct {
+	ctrl     *gomock.Controller
+	recorder *MockEncrypterMockRecorder
+}
+
+// MockEncrypterMockRecorder is the mock recorder for MockEncrypter
+type MockEncrypterMockRecorder struct {
+	mock *MockEncrypter
+}
+
+// NewMockEncrypter creates a new mock instance
+func NewMockEncrypter(ctrl *gomock.Controller) *MockEncrypter {
+	mock := &MockEncrypter{ctrl: ctrl}
+	mock.recorder = &MockEncrypterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockEncrypter) EXPECT() *MockEncrypterMockRecorder {
+	return m.recorder
+}
+
+// Decrypt mocks base method
+func (m *MockEncrypter) Decrypt(ciphertext string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Decrypt", ciphertext)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Decrypt indicates an expected call of Decrypt
+func (mr *MockEncrypterMockRecorder) Decrypt(ciphertext interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Decrypt", reflect.TypeOf((*MockEncrypter)(nil).Decrypt), ciphertext)
+}
+
+// Encrypt mocks base method
+func (m *MockEncrypter) Encrypt(plaintext string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Encrypt", plaintext)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Encrypt indicates an expected call of Encrypt
+func (mr *MockEncrypterMockRecorder) Encrypt(plaintext interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Encrypt", reflect.TypeOf((*MockEncrypter)(nil).Encrypt), plaintext)
+}
@@ -32,6 +32,36 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 	return m.recorder
 }
 
+// AuthenticateConfidentialClient mocks base method
+func (m *MockService) AuthenticateConfidentialClient(credentials *ClientCredentials) (*Client, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateConfidentialClient", credentials)
+	ret0, _ := ret[0].(*Client)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthenticateConfidentialClient indicates an expected call of AuthenticateConfidentialClient
+func (mr *MockServiceMockRecorder) AuthenticateConfidentialClient(credentials interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateConfidentialClient", reflect.TypeOf((*MockService)(nil).AuthenticateConfidentialClient), credentials)
+}
+
+// Authorize mocks base method
+func (m *MockService) Authorize(request *AuthorizationRequest, credentials *Credentials, clientInfo *ClientInfo) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authorize", request, credentials, clientInfo)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authorize indicates an expected call of Authorize
+func (mr *MockServiceMockRecorder) Authorize(request, credentials, clientInfo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authorize", reflect.TypeOf((*MockService)(nil).Authorize), request, credentials, clientInfo)
+}
+
 // CheckHealth mocks base method
 func (m *MockService) CheckHealth() *Health {
 	m.ctrl.T.Helper()
@@ -46,34 +76,223 @@ func (mr *MockServiceMockRecorder) CheckHealth() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckHealth", reflect.TypeOf((*MockService)(nil).CheckHealth))
 }
 
-// Login mocks base method
-func (m *MockService) Login(credentials *Credentials) (*AuthData, error) {
+// CheckPermission mocks base method
+func (m *MockService) CheckPermission(role, permission string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Login", credentials)
-	ret0, _ := ret[0].(*AuthData)
+	ret := m.ctrl.Call(m, "CheckPermission", role, permission)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckPermission indicates an expected call of CheckPermission
+func (mr *MockServiceMockRecorder) CheckPermission(role, permission interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckPermission", reflect.TypeOf((*MockService)(nil).CheckPermission), role, permission)
+}
+
+// ConfirmTOTP mocks base method
+func (m *MockService) ConfirmTOTP(userID int64, code string) (*RecoveryCodes, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTOTP", userID, code)
+	ret0, _ := ret[0].(*RecoveryCodes)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Login indicates an expected call of Login
-func (mr *MockServiceMockRecorder) Login(credentials interface{}) *gomock.Call {
+// ConfirmTOTP indicates an expected call of ConfirmTOTP
+func (mr *MockServiceMockRecorder) ConfirmTOTP(userID, code interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockService)(nil).Login), credentials)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTOTP", reflect.TypeOf((*MockService)(nil).ConfirmTOTP), userID, code)
 }
 
-// RefreshToken mocks base method
-func (m *MockService) RefreshToken(refreshToken string) (*AuthData, error) {
+// CreateAPIKey mocks base method
+func (m *MockService) CreateAPIKey(userID int64, request *APIKeyRequest) (*NewAPIKey, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RefreshToken", refreshToken)
-	ret0, _ := ret[0].(*AuthData)
+	ret := m.ctrl.Call(m, "CreateAPIKey", userID, request)
+	ret0, _ := ret[0].(*NewAPIKey)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// RefreshToken indicates an expected call of RefreshToken
-func (mr *MockServiceMockRecorder) RefreshToken(refreshToken interface{}) *gomock.Call {
+// CreateAPIKey indicates an expected call of CreateAPIKey
+func (mr *MockServiceMockRecorder) CreateAPIKey(userID, request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockService)(nil).CreateAPIKey), userID, request)
+}
+
+// CreateUser mocks base method
+func (m *MockService) CreateUser(newUser *NewUser) (*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", newUser)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser
+func (mr *MockServiceMockRecorder) CreateUser(newUser interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockService)(nil).CreateUser), newUser)
+}
+
+// DeleteAPIKey mocks base method
+func (m *MockService) DeleteAPIKey(userID, keyID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAPIKey", userID, keyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAPIKey indicates an expected call of DeleteAPIKey
+func (mr *MockServiceMockRecorder) DeleteAPIKey(userID, keyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIKey", reflect.TypeOf((*MockService)(nil).DeleteAPIKey), userID, keyID)
+}
+
+// DeleteUser mocks base method
+func (m *MockService) DeleteUser(userID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser
+func (mr *MockServiceMockRecorder) DeleteUser(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockService)(nil).DeleteUser), userID)
+}
+
+// DisableTOTP mocks base method
+func (m *MockService) DisableTOTP(userID int64, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", userID, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP
+func (mr *MockServiceMockRecorder) DisableTOTP(userID, code interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockService)(nil).RefreshToken), refreshToken)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockService)(nil).DisableTOTP), userID, code)
+}
+
+// EnrollTOTP mocks base method
+func (m *MockService) EnrollTOTP(userID int64) (*TOTPEnrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollTOTP", userID)
+	ret0, _ := ret[0].(*TOTPEnrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollTOTP indicates an expected call of EnrollTOTP
+func (mr *MockServiceMockRecorder) EnrollTOTP(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollTOTP", reflect.TypeOf((*MockService)(nil).EnrollTOTP), userID)
+}
+
+// GetAPIKeyClaims mocks base method
+func (m *MockService) GetAPIKeyClaims(apiKey string, clientInfo *ClientInfo) (*AccessTokenClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeyClaims", apiKey, clientInfo)
+	ret0, _ := ret[0].(*AccessTokenClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeyClaims indicates an expected call of GetAPIKeyClaims
+func (mr *MockServiceMockRecorder) GetAPIKeyClaims(apiKey, clientInfo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeyClaims", reflect.TypeOf((*MockService)(nil).GetAPIKeyClaims), apiKey, clientInfo)
+}
+
+// GetAccessTokenClaims mocks base method
+func (m *MockService) GetAccessTokenClaims(accessToken string, clientInfo *ClientInfo) (*AccessTokenClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccessTokenClaims", accessToken, clientInfo)
+	ret0, _ := ret[0].(*AccessTokenClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccessTokenClaims indicates an expected call of GetAccessTokenClaims
+func (mr *MockServiceMockRecorder) GetAccessTokenClaims(accessToken, clientInfo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccessTokenClaims", reflect.TypeOf((*MockService)(nil).GetAccessTokenClaims), accessToken, clientInfo)
+}
+
+// GetJSONWebKeySet mocks base method
+func (m *MockService) GetJSONWebKeySet() *JSONWebKeySet {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJSONWebKeySet")
+	ret0, _ := ret[0].(*JSONWebKeySet)
+	return ret0
+}
+
+// GetJSONWebKeySet indicates an expected call of GetJSONWebKeySet
+func (mr *MockServiceMockRecorder) GetJSONWebKeySet() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJSONWebKeySet", reflect.TypeOf((*MockService)(nil).GetJSONWebKeySet))
+}
+
+// GetOpenIDConfiguration mocks base method
+func (m *MockService) GetOpenIDConfiguration() *OpenIDConfiguration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOpenIDConfiguration")
+	ret0, _ := ret[0].(*OpenIDConfiguration)
+	return ret0
+}
+
+// GetOpenIDConfiguration indicates an expected call of GetOpenIDConfiguration
+func (mr *MockServiceMockRecorder) GetOpenIDConfiguration() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOpenIDConfiguration", reflect.TypeOf((*MockService)(nil).GetOpenIDConfiguration))
+}
+
+// GetPermissions mocks base method
+func (m *MockService) GetPermissions(role string) (*RolePermissions, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPermissions", role)
+	ret0, _ := ret[0].(*RolePermissions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPermissions indicates an expected call of GetPermissions
+func (mr *MockServiceMockRecorder) GetPermissions(role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPermissions", reflect.TypeOf((*MockService)(nil).GetPermissions), role)
+}
+
+// GetRecoveryCodes mocks base method
+func (m *MockService) GetRecoveryCodes(userID int64) (*RecoveryCodes, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRecoveryCodes", userID)
+	ret0, _ := ret[0].(*RecoveryCodes)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRecoveryCodes indicates an expected call of GetRecoveryCodes
+func (mr *MockServiceMockRecorder) GetRecoveryCodes(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRecoveryCodes", reflect.TypeOf((*MockService)(nil).GetRecoveryCodes), userID)
+}
+
+// GetSessions mocks base method
+func (m *MockService) GetSessions(userID int64, currentSessionID string) ([]*Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", userID, currentSessionID)
+	ret0, _ := ret[0].([]*Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessions indicates an expected call of GetSessions
+func (mr *MockServiceMockRecorder) GetSessions(userID, currentSessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockService)(nil).GetSessions), userID, currentSessionID)
 }
 
 // GetUser mocks base method
@@ -91,31 +310,295 @@ func (mr *MockServiceMockRecorder) GetUser(userID interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockService)(nil).GetUser), userID)
 }
 
+// GetUserInfo mocks base method
+func (m *MockService) GetUserInfo(userID int64) (*UserInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserInfo", userID)
+	ret0, _ := ret[0].(*UserInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserInfo indicates an expected call of GetUserInfo
+func (mr *MockServiceMockRecorder) GetUserInfo(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserInfo", reflect.TypeOf((*MockService)(nil).GetUserInfo), userID)
+}
+
+// IntrospectToken mocks base method
+func (m *MockService) IntrospectToken(request *IntrospectionRequest) (*Introspection, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IntrospectToken", request)
+	ret0, _ := ret[0].(*Introspection)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IntrospectToken indicates an expected call of IntrospectToken
+func (mr *MockServiceMockRecorder) IntrospectToken(request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IntrospectToken", reflect.TypeOf((*MockService)(nil).IntrospectToken), request)
+}
+
+// IssueToken mocks base method
+func (m *MockService) IssueToken(request *TokenRequest) (*Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueToken", request)
+	ret0, _ := ret[0].(*Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueToken indicates an expected call of IssueToken
+func (mr *MockServiceMockRecorder) IssueToken(request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueToken", reflect.TypeOf((*MockService)(nil).IssueToken), request)
+}
+
+// ListAPIKeys mocks base method
+func (m *MockService) ListAPIKeys(userID int64) ([]*APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeys", userID)
+	ret0, _ := ret[0].([]*APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPIKeys indicates an expected call of ListAPIKeys
+func (mr *MockServiceMockRecorder) ListAPIKeys(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeys", reflect.TypeOf((*MockService)(nil).ListAPIKeys), userID)
+}
+
+// ListAuditEvents mocks base method
+func (m *MockService) ListAuditEvents(filter *AuditFilter) (*AuditPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditEvents", filter)
+	ret0, _ := ret[0].(*AuditPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditEvents indicates an expected call of ListAuditEvents
+func (mr *MockServiceMockRecorder) ListAuditEvents(filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditEvents", reflect.TypeOf((*MockService)(nil).ListAuditEvents), filter)
+}
+
+// ListUsers mocks base method
+func (m *MockService) ListUsers(filter *UserFilter) (*UserPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", filter)
+	ret0, _ := ret[0].(*UserPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers
+func (mr *MockServiceMockRecorder) ListUsers(filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockService)(nil).ListUsers), filter)
+}
+
+// Login mocks base method
+func (m *MockService) Login(credentials *Credentials, clientInfo *ClientInfo) (*AuthData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Login", credentials, clientInfo)
+	ret0, _ := ret[0].(*AuthData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Login indicates an expected call of Login
+func (mr *MockServiceMockRecorder) Login(credentials, clientInfo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockService)(nil).Login), credentials, clientInfo)
+}
+
+// LoginMFA mocks base method
+func (m *MockService) LoginMFA(credentials *MFACredentials, clientInfo *ClientInfo) (*AuthData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoginMFA", credentials, clientInfo)
+	ret0, _ := ret[0].(*AuthData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoginMFA indicates an expected call of LoginMFA
+func (mr *MockServiceMockRecorder) LoginMFA(credentials, clientInfo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginMFA", reflect.TypeOf((*MockService)(nil).LoginMFA), credentials, clientInfo)
+}
+
 // Logout mocks base method
-func (m *MockService) Logout(userID int64) error {
+func (m *MockService) Logout(userID int64, sessionID string, clientInfo *ClientInfo) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Logout", userID)
+	ret := m.ctrl.Call(m, "Logout", userID, sessionID, clientInfo)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Logout indicates an expected call of Logout
-func (mr *MockServiceMockRecorder) Logout(userID interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) Logout(userID, sessionID, clientInfo interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockService)(nil).Logout), userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockService)(nil).Logout), userID, sessionID, clientInfo)
 }
 
-// GetAccessTokenClaims mocks base method
-func (m *MockService) GetAccessTokenClaims(accessToken string) (*AccessTokenClaims, error) {
+// LogoutEverywhere mocks base method
+func (m *MockService) LogoutEverywhere(userID int64, clientInfo *ClientInfo) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAccessTokenClaims", accessToken)
-	ret0, _ := ret[0].(*AccessTokenClaims)
+	ret := m.ctrl.Call(m, "LogoutEverywhere", userID, clientInfo)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LogoutEverywhere indicates an expected call of LogoutEverywhere
+func (mr *MockServiceMockRecorder) LogoutEverywhere(userID, clientInfo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogoutEverywhere", reflect.TypeOf((*MockService)(nil).LogoutEverywhere), userID, clientInfo)
+}
+
+// PurgeAuditEvents mocks base method
+func (m *MockService) PurgeAuditEvents() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeAuditEvents")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PurgeAuditEvents indicates an expected call of PurgeAuditEvents
+func (mr *MockServiceMockRecorder) PurgeAuditEvents() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeAuditEvents", reflect.TypeOf((*MockService)(nil).PurgeAuditEvents))
+}
+
+// RefreshToken mocks base method
+func (m *MockService) RefreshToken(refreshToken string, clientInfo *ClientInfo) (*AuthData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshToken", refreshToken, clientInfo)
+	ret0, _ := ret[0].(*AuthData)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAccessTokenClaims indicates an expected call of GetAccessTokenClaims
-func (mr *MockServiceMockRecorder) GetAccessTokenClaims(accessToken interface{}) *gomock.Call {
+// RefreshToken indicates an expected call of RefreshToken
+func (mr *MockServiceMockRecorder) RefreshToken(refreshToken, clientInfo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockService)(nil).RefreshToken), refreshToken, clientInfo)
+}
+
+// RegenerateRecoveryCodes mocks base method
+func (m *MockService) RegenerateRecoveryCodes(userID int64, code string) (*RecoveryCodes, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegenerateRecoveryCodes", userID, code)
+	ret0, _ := ret[0].(*RecoveryCodes)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegenerateRecoveryCodes indicates an expected call of RegenerateRecoveryCodes
+func (mr *MockServiceMockRecorder) RegenerateRecoveryCodes(userID, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegenerateRecoveryCodes", reflect.TypeOf((*MockService)(nil).RegenerateRecoveryCodes), userID, code)
+}
+
+// Register mocks base method
+func (m *MockService) Register(credentials *Credentials) (*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Register", credentials)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Register indicates an expected call of Register
+func (mr *MockServiceMockRecorder) Register(credentials interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockService)(nil).Register), credentials)
+}
+
+// ResetPassword mocks base method
+func (m *MockService) ResetPassword(userID int64, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", userID, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword
+func (mr *MockServiceMockRecorder) ResetPassword(userID, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockService)(nil).ResetPassword), userID, password)
+}
+
+// ResolveRedirectURI mocks base method
+func (m *MockService) ResolveRedirectURI(clientID, redirectURI string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveRedirectURI", clientID, redirectURI)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveRedirectURI indicates an expected call of ResolveRedirectURI
+func (mr *MockServiceMockRecorder) ResolveRedirectURI(clientID, redirectURI interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveRedirectURI", reflect.TypeOf((*MockService)(nil).ResolveRedirectURI), clientID, redirectURI)
+}
+
+// RevokeSession mocks base method
+func (m *MockService) RevokeSession(userID int64, sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", userID, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession
+func (mr *MockServiceMockRecorder) RevokeSession(userID, sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockService)(nil).RevokeSession), userID, sessionID)
+}
+
+// RevokeToken mocks base method
+func (m *MockService) RevokeToken(request *RevocationRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeToken", request)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeToken indicates an expected call of RevokeToken
+func (mr *MockServiceMockRecorder) RevokeToken(request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeToken", reflect.TypeOf((*MockService)(nil).RevokeToken), request)
+}
+
+// SetUserDisabled mocks base method
+func (m *MockService) SetUserDisabled(userID int64, disabled bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserDisabled", userID, disabled)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserDisabled indicates an expected call of SetUserDisabled
+func (mr *MockServiceMockRecorder) SetUserDisabled(userID, disabled interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserDisabled", reflect.TypeOf((*MockService)(nil).SetUserDisabled), userID, disabled)
+}
+
+// UpdateUser mocks base method
+func (m *MockService) UpdateUser(userID int64, update *UserUpdate) (*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUser", userID, update)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUser indicates an expected call of UpdateUser
+func (mr *MockServiceMockRecorder) UpdateUser(userID, update interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccessTokenClaims", reflect.TypeOf((*MockService)(nil).GetAccessTokenClaims), accessToken)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockService)(nil).UpdateUser), userID, update)
 }
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+const (
+	//DefaultUserPageSize is the number of users listed when the limit is omitted.
+	DefaultUserPageSize = 50
+
+	//MaxUserPageSize is the maximum number of users listed at once.
+	MaxUserPageSize = 100
+)
+
+//User listing sort orders, the "-" prefix means the descending order.
+const (
+	SortByID           = "id"
+	SortByIDDesc       = "-id"
+	SortByUsername     = "username"
+	SortByUsernameDesc = "-username"
+)
+
+//UserFilter contains user listing parameters, empty fields are not filtered.
+type UserFilter struct {
+	Role           string
+	Disabled       *bool
+	UsernamePrefix string
+	Sort           string
+	Cursor         string
+	Limit          int
+}
+
+//UserCursor points to the last listed user, the next page starts after it.
+type UserCursor struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username,omitempty"`
+}
+
+//UserPage contains a page of users and the cursor of the next page if there is one.
+type UserPage struct {
+	Users      []*User `json:"users"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+}
+
+func isValidUserSort(sort string) bool {
+	switch sort {
+	case SortByID, SortByIDDesc, SortByUsername, SortByUsernameDesc:
+		return true
+	default:
+		return false
+	}
+}
+
+//encodeUserCursor makes an opaque cursor pointing to the user.
+func encodeUserCursor(user *User, sort string) string {
+	cursor := &UserCursor{ID: user.ID}
+	if sort == SortByUsername || sort == SortByUsernameDesc {
+		cursor.Username = user.Username
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeUserCursor(value string) (*UserCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidRequest
+	}
+
+	cursor := new(UserCursor)
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, ErrInvalidRequest
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"regexp"
+)
+
+//codeVerifierRegexp matches PKCE code verifiers and S256 code challenges (RFC 7636, section 4.1).
+var codeVerifierRegexp = regexp.MustCompile(`^[A-Za-z0-9._~-]{43,128}$`)
+
+func validateCodeChallenge(challenge, method string) error {
+	if method != CodeChallengeMethodS256 || !codeVerifierRegexp.MatchString(challenge) {
+		return ErrInvalidRequest
+	}
+	return nil
+}
+
+//verifyCodeChallenge checks the code verifier against the S256 code challenge.
+func verifyCodeChallenge(verifier, challenge string) error {
+	if !codeVerifierRegexp.MatchString(verifier) {
+		return ErrInvalidGrant
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) != 1 {
+		return ErrInvalidGrant
+	}
+	return nil
+}
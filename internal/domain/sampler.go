@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"sync"
+	"time"
+)
+
+//auditSampler lets at most one event per key be recorded in the interval and counts the suppressed ones,
+//so a flood of failures can't turn into a flood of audit log writes.
+//A nil sampler lets every event be recorded.
+type auditSampler struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	samples map[string]*auditSample
+	swept   time.Time
+}
+
+type auditSample struct {
+	recordedAt time.Time
+	suppressed int
+}
+
+func newAuditSampler(interval time.Duration) *auditSampler {
+	if interval <= 0 {
+		return nil
+	}
+
+	return &auditSampler{
+		interval: interval,
+		samples:  make(map[string]*auditSample),
+	}
+}
+
+//sample returns true if the event should be recorded and the number of events suppressed since the last recorded one.
+//Forgotten samples are returned with their suppressed events, so they can still be reported.
+func (s *auditSampler) sample(key string, now time.Time) (bool, int, map[string]int) {
+	if s == nil {
+		return true, 0, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sample, ok := s.samples[key]
+	if ok && now.Sub(sample.recordedAt) < s.interval {
+		sample.suppressed++
+		return false, 0, s.sweep(now)
+	}
+
+	suppressed := 0
+	if ok {
+		suppressed = sample.suppressed
+	}
+
+	s.samples[key] = &auditSample{recordedAt: now}
+
+	return true, suppressed, s.sweep(now)
+}
+
+//sweep forgets samples out of the interval and returns those with suppressed events.
+func (s *auditSampler) sweep(now time.Time) map[string]int {
+	if now.Sub(s.swept) < s.interval {
+		return nil
+	}
+	s.swept = now
+
+	var forgotten map[string]int
+	for key, sample := range s.samples {
+		if now.Sub(sample.recordedAt) < s.interval {
+			continue
+		}
+
+		if sample.suppressed > 0 {
+			if forgotten == nil {
+				forgotten = make(map[string]int)
+			}
+			forgotten[key] = sample.suppressed
+		}
+		delete(s.samples, key)
+	}
+
+	return forgotten
+}
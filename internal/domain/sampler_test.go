@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditSampler(t *testing.T) {
+	now := time.Date(2019, 11, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("disabled", func(t *testing.T) {
+		sampler := newAuditSampler(0)
+
+		for i := 0; i < 3; i++ {
+			record, suppressed, forgotten := sampler.sample("192.0.2.1", now)
+			require.True(t, record)
+			require.Zero(t, suppressed)
+			require.Nil(t, forgotten)
+		}
+	})
+
+	t.Run("sampled", func(t *testing.T) {
+		sampler := newAuditSampler(time.Minute)
+
+		record, suppressed, _ := sampler.sample("192.0.2.1", now)
+		require.True(t, record)
+		require.Zero(t, suppressed)
+
+		for i := 0; i < 3; i++ {
+			record, _, _ := sampler.sample("192.0.2.1", now.Add(time.Second))
+			require.False(t, record)
+		}
+
+		record, suppressed, _ = sampler.sample("192.0.2.2", now.Add(time.Second))
+		require.True(t, record)
+		require.Zero(t, suppressed)
+
+		record, suppressed, _ = sampler.sample("192.0.2.1", now.Add(time.Minute))
+		require.True(t, record)
+		require.Equal(t, 3, suppressed)
+	})
+
+	t.Run("forgotten", func(t *testing.T) {
+		sampler := newAuditSampler(time.Minute)
+
+		_, _, _ = sampler.sample("192.0.2.1", now)
+		_, _, _ = sampler.sample("192.0.2.1", now.Add(time.Second))
+		_, _, _ = sampler.sample("192.0.2.2", now.Add(time.Second))
+
+		record, suppressed, forgotten := sampler.sample("192.0.2.3", now.Add(2*time.Minute))
+		require.True(t, record)
+		require.Zero(t, suppressed)
+		require.Equal(t, map[string]int{"192.0.2.1": 1}, forgotten)
+		require.Len(t, sampler.samples, 1)
+	})
+}
@@ -0,0 +1,68 @@
+package domain
+
+import "strings"
+
+//parseScope splits a space-delimited scope.
+func parseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+//formatScope joins scopes into a space-delimited scope.
+func formatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+//grantScopes returns requested scopes if all of them are allowed or all allowed scopes if nothing requested.
+//An empty grant is an error, as tokens without scopes are denied everything.
+func grantScopes(requested, allowed []string) ([]string, error) {
+	if len(requested) == 0 {
+		if len(allowed) == 0 {
+			return nil, ErrInvalidScope
+		}
+		return allowed, nil
+	}
+
+	permitted := make(map[string]bool, len(allowed))
+	for _, scope := range allowed {
+		permitted[scope] = true
+	}
+
+	for _, scope := range requested {
+		if !permitted[scope] {
+			return nil, ErrInvalidScope
+		}
+	}
+
+	return requested, nil
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+//withoutScope returns the scopes except the scope, the scopes are not modified.
+func withoutScope(scopes []string, scope string) []string {
+	without := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		if s != scope {
+			without = append(without, s)
+		}
+	}
+	return without
+}
+
+//limitScopes keeps the scopes granted by the permissions, OpenID Connect scopes are not permissions and are kept.
+func limitScopes(scopes, permissions []string) []string {
+	limited := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if scope == ScopeOpenID || hasScope(permissions, scope) {
+			limited = append(limited, scope)
+		}
+	}
+	return limited
+}
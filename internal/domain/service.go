@@ -1,8 +1,12 @@
 package domain
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/lzakharov/goss/internal/version"
 	"go.uber.org/zap"
 )
@@ -13,29 +17,69 @@ import (
 type Service interface {
 	CheckHealth() *Health
 
-	Login(credentials *Credentials) (*AuthData, error)
-	RefreshToken(refreshToken string) (*AuthData, error)
+	Register(credentials *Credentials) (*User, error)
+	Login(credentials *Credentials, clientInfo *ClientInfo) (*AuthData, error)
+	LoginMFA(credentials *MFACredentials, clientInfo *ClientInfo) (*AuthData, error)
+	RefreshToken(refreshToken string, clientInfo *ClientInfo) (*AuthData, error)
 	GetUser(userID int64) (*User, error)
-	Logout(userID int64) error
+	ListUsers(filter *UserFilter) (*UserPage, error)
+	CreateUser(newUser *NewUser) (*User, error)
+	UpdateUser(userID int64, update *UserUpdate) (*User, error)
+	SetUserDisabled(userID int64, disabled bool) error
+	ResetPassword(userID int64, password string) error
+	DeleteUser(userID int64) error
+	EnrollTOTP(userID int64) (*TOTPEnrollment, error)
+	ConfirmTOTP(userID int64, code string) (*RecoveryCodes, error)
+	DisableTOTP(userID int64, code string) error
+	GetRecoveryCodes(userID int64) (*RecoveryCodes, error)
+	RegenerateRecoveryCodes(userID int64, code string) (*RecoveryCodes, error)
+	GetSessions(userID int64, currentSessionID string) ([]*Session, error)
+	RevokeSession(userID int64, sessionID string) error
+	Logout(userID int64, sessionID string, clientInfo *ClientInfo) error
+	LogoutEverywhere(userID int64, clientInfo *ClientInfo) error
+	CreateAPIKey(userID int64, request *APIKeyRequest) (*NewAPIKey, error)
+	ListAPIKeys(userID int64) ([]*APIKey, error)
+	DeleteAPIKey(userID, keyID int64) error
 
-	GetAccessTokenClaims(accessToken string) (*AccessTokenClaims, error)
+	GetAccessTokenClaims(accessToken string, clientInfo *ClientInfo) (*AccessTokenClaims, error)
+	GetAPIKeyClaims(apiKey string, clientInfo *ClientInfo) (*AccessTokenClaims, error)
+	GetPermissions(role string) (*RolePermissions, error)
+	CheckPermission(role, permission string) error
+	GetJSONWebKeySet() *JSONWebKeySet
+
+	ListAuditEvents(filter *AuditFilter) (*AuditPage, error)
+	PurgeAuditEvents() error
+
+	ResolveRedirectURI(clientID, redirectURI string) (string, error)
+	Authorize(request *AuthorizationRequest, credentials *Credentials, clientInfo *ClientInfo) (string, error)
+	IssueToken(request *TokenRequest) (*Token, error)
+	AuthenticateConfidentialClient(credentials *ClientCredentials) (*Client, error)
+	IntrospectToken(request *IntrospectionRequest) (*Introspection, error)
+	RevokeToken(request *RevocationRequest) error
+
+	GetUserInfo(userID int64) (*UserInfo, error)
+	GetOpenIDConfiguration() *OpenIDConfiguration
 }
 
 //NewService creates a new service.
-func NewService(logger *zap.Logger, storage Storage, security Security) Service {
+func NewService(logger *zap.Logger, config *Config, storage Storage, security Security) Service {
 	service := &service{
-		logger:   logger,
-		storage:  storage,
-		security: security,
+		logger:        logger,
+		config:        config,
+		storage:       storage,
+		security:      security,
+		tokenFailures: newAuditSampler(config.AuditSampleInterval),
 	}
 
 	return service
 }
 
 type service struct {
-	logger   *zap.Logger
-	storage  Storage
-	security Security
+	logger        *zap.Logger
+	config        *Config
+	storage       Storage
+	security      Security
+	tokenFailures *auditSampler
 }
 
 //CheckHealth checks the application health.
@@ -47,17 +91,64 @@ func (s *service) CheckHealth() *Health {
 	}
 }
 
-//Login creates user auth data by the credentials.
-func (s *service) Login(credentials *Credentials) (*AuthData, error) {
-	user, err := s.storage.GetUserByCredentials(credentials)
+//Register creates a new user with the default role.
+func (s *service) Register(credentials *Credentials) (*User, error) {
+	if !s.config.RegistrationEnabled {
+		return nil, ErrRegistrationDisabled
+	}
+
+	if err := validateUsername(credentials.Username); err != nil {
+		return nil, err
+	}
+
+	if err := validatePassword(credentials.Password); err != nil {
+		return nil, err
+	}
+
+	user, err := s.storage.CreateUser(credentials, s.config.DefaultRole)
 	if err != nil {
-		s.logger.Error("Error getting user by credentials!",
+		s.logger.Error("Error creating a new user!",
 			zap.String("username", credentials.Username),
 			zap.Error(err))
 		return nil, err
 	}
 
-	authData, err := s.security.CreateAuthData(user)
+	return user, nil
+}
+
+//Login creates user auth data by the credentials.
+//Failed login attempts of users with two-factor authentication are reset only when the second step succeeds.
+func (s *service) Login(credentials *Credentials, clientInfo *ClientInfo) (*AuthData, error) {
+	user, err := s.authenticateUser(credentials, clientInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	mfaEnabled, err := s.isMFAEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfaEnabled {
+		mfaToken, err := s.security.CreateMFAToken(user)
+		if err != nil {
+			s.logger.Error("Error creating an MFA token!",
+				zap.Int64("userID", user.ID),
+				zap.Error(err))
+			return nil, err
+		}
+
+		return &AuthData{MFAToken: mfaToken}, nil
+	}
+
+	s.resetLoginAttempts(user.Username)
+
+	scopes, err := s.grantUserScopes(user, credentials.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	authData, err := s.security.CreateAuthData(user, "", scopes)
 	if err != nil {
 		s.logger.Error("Error creating user auth data!",
 			zap.Int64("userID", user.ID),
@@ -68,57 +159,247 @@ func (s *service) Login(credentials *Credentials) (*AuthData, error) {
 	return authData, nil
 }
 
-//RefreshToken refreshes user auth data.
-func (s *service) RefreshToken(refreshToken string) (*AuthData, error) {
-	claims, err := s.security.GetRefreshTokenClaims(refreshToken)
+//grantUserScopes returns the requested scopes if all of them are permissions of the user's role.
+//All scopes are granted if no scope is requested, so tokens are limited only by the role.
+func (s *service) grantUserScopes(user *User, scope string) ([]string, error) {
+	requested := parseScope(scope)
+	if len(requested) == 0 {
+		return []string{ScopeAll}, nil
+	}
+
+	permissions, err := s.storage.GetPermissions(user.Role)
 	if err != nil {
-		s.logger.Error("Error refreshing user auth data!",
-			zap.String("refreshToken", refreshToken),
+		s.logger.Error("Error getting role permissions!",
+			zap.String("role", user.Role),
 			zap.Error(err))
 		return nil, err
 	}
 
-	if time.Now().Unix() > claims.ExpiresAt {
-		return nil, ErrInvalidRefreshToken
+	scopes, err := grantScopes(requested, permissions)
+	if err != nil {
+		s.logger.Warn("Requested scopes exceed user's permissions!",
+			zap.Int64("userID", user.ID),
+			zap.Strings("scopes", requested))
+		return nil, err
 	}
 
-	user, err := s.storage.GetUser(claims.UserID)
+	return scopes, nil
+}
+
+//authenticateUser gets user by the credentials and records the login attempt in the audit log.
+func (s *service) authenticateUser(credentials *Credentials, clientInfo *ClientInfo) (*User, error) {
+	user, err := s.checkCredentials(credentials, clientInfo)
 	if err != nil {
-		s.logger.Error("Error getting user by id!",
-			zap.Int64("userID", claims.UserID),
+		s.audit(AuditActionLogin, clientInfo, 0, credentials.Username, err)
+		return nil, err
+	}
+
+	s.audit(AuditActionLogin, clientInfo, user.ID, user.Username, nil)
+	return user, nil
+}
+
+//checkCredentials gets user by the credentials unless login attempts are locked out.
+//Failed attempts are counted per username and client IP, the caller resets the username counter once the login succeeds.
+func (s *service) checkCredentials(credentials *Credentials, clientInfo *ClientInfo) (*User, error) {
+	if err := s.security.CheckLoginAttempts(credentials.Username, clientInfo.IP); err != nil {
+		s.logger.Warn("Login attempts locked out!",
+			zap.String("username", credentials.Username),
+			zap.String("ip", clientInfo.IP),
 			zap.Error(err))
 		return nil, err
 	}
 
-	authData, err := s.security.CreateAuthData(user)
+	user, err := s.storage.GetUserByCredentials(credentials)
 	if err != nil {
-		s.logger.Error("Error creating user auth data!",
-			zap.Int64("userID", user.ID),
+		s.logger.Error("Error getting user by credentials!",
+			zap.String("username", credentials.Username),
+			zap.Error(err))
+
+		if err == ErrInvalidCredentials {
+			s.addFailedLoginAttempt(credentials.Username, clientInfo.IP)
+		}
+		return nil, err
+	}
+
+	if user.Disabled {
+		s.logger.Warn("Disabled user tried to log in!", zap.Int64("userID", user.ID))
+		return nil, ErrUserDisabled
+	}
+
+	return user, nil
+}
+
+func (s *service) addFailedLoginAttempt(username, ip string) {
+	if err := s.security.AddFailedLoginAttempt(username, ip); err != nil {
+		s.logger.Error("Error adding a failed login attempt!",
+			zap.String("username", username),
+			zap.String("ip", ip),
+			zap.Error(err))
+	}
+}
+
+func (s *service) resetLoginAttempts(username string) {
+	if err := s.security.ResetLoginAttempts(username); err != nil {
+		s.logger.Error("Error resetting login attempts!",
+			zap.String("username", username),
 			zap.Error(err))
+	}
+}
+
+//LoginMFA completes the login of a user with two-factor authentication.
+//The MFA token is single-use: a wrong code requires signing in with the password again.
+//Wrong codes are counted as failed login attempts, so the lockout limits guessing codes too.
+func (s *service) LoginMFA(credentials *MFACredentials, clientInfo *ClientInfo) (*AuthData, error) {
+	userID, authData, err := s.loginMFA(credentials, clientInfo)
+	s.audit(AuditActionLoginMFA, clientInfo, userID, "", err)
+	if err != nil {
 		return nil, err
 	}
 
 	return authData, nil
 }
 
-//GetUser gets a user by id.
-func (s *service) GetUser(userID int64) (*User, error) {
+//loginMFA checks the second step credentials, the user id is zero unless the MFA token is valid.
+func (s *service) loginMFA(credentials *MFACredentials, clientInfo *ClientInfo) (int64, *AuthData, error) {
+	userID, err := s.security.ConsumeMFAToken(credentials.MFAToken)
+	if err != nil {
+		s.logger.Error("Error consuming the MFA token!", zap.Error(err))
+		return 0, nil, err
+	}
+
 	user, err := s.storage.GetUser(userID)
 	if err != nil {
 		s.logger.Error("Error getting user by id!",
 			zap.Int64("userID", userID),
 			zap.Error(err))
+		return userID, nil, err
+	}
+
+	if user.Disabled {
+		return userID, nil, ErrUserDisabled
+	}
+
+	if err := s.security.CheckLoginAttempts(user.Username, clientInfo.IP); err != nil {
+		s.logger.Warn("Login attempts locked out!",
+			zap.String("username", user.Username),
+			zap.String("ip", clientInfo.IP),
+			zap.Error(err))
+		return userID, nil, err
+	}
+
+	var remaining *int
+
+	if credentials.RecoveryCode != "" {
+		count, err := s.useRecoveryCode(userID, credentials.RecoveryCode)
+		if err != nil {
+			if err == ErrInvalidMFACode {
+				s.addFailedLoginAttempt(user.Username, clientInfo.IP)
+			}
+			return userID, nil, err
+		}
+		remaining = &count
+	} else if err := s.checkTOTP(userID, credentials.Code); err != nil {
+		if err == ErrInvalidMFACode {
+			s.addFailedLoginAttempt(user.Username, clientInfo.IP)
+		}
+		return userID, nil, err
+	}
+
+	s.resetLoginAttempts(user.Username)
+
+	scopes, err := s.grantUserScopes(user, credentials.Scope)
+	if err != nil {
+		return userID, nil, err
+	}
+
+	authData, err := s.security.CreateAuthData(user, "", scopes)
+	if err != nil {
+		s.logger.Error("Error creating user auth data!",
+			zap.Int64("userID", user.ID),
+			zap.Error(err))
+		return userID, nil, err
+	}
+	authData.RecoveryCodesRemaining = remaining
+
+	return userID, authData, nil
+}
+
+//EnrollTOTP generates a new TOTP secret for the user.
+//Two-factor authentication is enabled only after the secret is confirmed with a code.
+func (s *service) EnrollTOTP(userID int64) (*TOTPEnrollment, error) {
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		s.logger.Error("Error generating a TOTP secret!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return nil, ErrInternalSecurity
+	}
+
+	if err := s.storage.SaveTOTP(userID, secret); err != nil {
+		s.logger.Error("Error saving user's TOTP secret!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
 		return nil, err
 	}
 
-	return user, nil
+	enrollment := &TOTPEnrollment{
+		Secret: secret,
+		URI:    newTOTPURI(s.config.TOTPIssuer, user.Username, secret),
+	}
+
+	return enrollment, nil
 }
 
+//ConfirmTOTP enables two-factor authentication if the code matches the enrolled secret.
+//Recovery codes are generated on success and shown to the user only once.
+func (s *service) ConfirmTOTP(userID int64, code string) (*RecoveryCodes, error) {
+	totp, err := s.getTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if totp.Confirmed {
+		return nil, ErrMFAAlreadyEnabled
+	}
 
-//Logout invalidates user's auth data.
-func (s *service) Logout(userID int64) error {
-	if err := s.security.InvalidateUserAuthData(userID); err != nil {
-		s.logger.Error("Error invalidating user's auth data!",
+	step, err := verifyTOTP(totp.Secret, code, totp.LastStep, time.Now())
+	if err != nil {
+		s.logger.Warn("Invalid TOTP code!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return nil, ErrInvalidMFACode
+	}
+
+	if err := s.storage.ConfirmTOTP(userID, step); err != nil {
+		s.logger.Error("Error confirming user's TOTP secret!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	return s.newRecoveryCodes(userID)
+}
+
+//DisableTOTP disables two-factor authentication, the current code is required.
+func (s *service) DisableTOTP(userID int64, code string) error {
+	if err := s.checkTOTP(userID, code); err != nil {
+		return err
+	}
+
+	if err := s.storage.DeleteTOTP(userID); err != nil {
+		s.logger.Error("Error deleting user's TOTP secret!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return err
+	}
+
+	if err := s.storage.ReplaceRecoveryCodes(userID, nil); err != nil {
+		s.logger.Error("Error deleting user's recovery codes!",
 			zap.Int64("userID", userID),
 			zap.Error(err))
 		return err
@@ -127,15 +408,1213 @@ func (s *service) Logout(userID int64) error {
 	return nil
 }
 
-//GetAccessTokenClaims validates the access token and returns claims if success.
-func (s *service) GetAccessTokenClaims(accessToken string) (*AccessTokenClaims, error) {
-	claims, err := s.security.GetAccessTokenClaims(accessToken)
+//GetRecoveryCodes returns the number of user's unused recovery codes.
+func (s *service) GetRecoveryCodes(userID int64) (*RecoveryCodes, error) {
+	remaining, err := s.storage.CountRecoveryCodes(userID)
 	if err != nil {
-		s.logger.Error("Error getting claims from the access token!",
-			zap.String("accessToken", accessToken),
+		s.logger.Error("Error counting user's recovery codes!",
+			zap.Int64("userID", userID),
 			zap.Error(err))
 		return nil, err
 	}
 
-	return claims, nil
+	return &RecoveryCodes{Remaining: remaining}, nil
+}
+
+//RegenerateRecoveryCodes replaces all user's recovery codes, the current TOTP code is required.
+func (s *service) RegenerateRecoveryCodes(userID int64, code string) (*RecoveryCodes, error) {
+	if err := s.checkTOTP(userID, code); err != nil {
+		return nil, err
+	}
+
+	return s.newRecoveryCodes(userID)
+}
+
+func (s *service) newRecoveryCodes(userID int64) (*RecoveryCodes, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		s.logger.Error("Error generating recovery codes!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return nil, ErrInternalSecurity
+	}
+
+	if err := s.storage.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		s.logger.Error("Error saving user's recovery codes!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	return &RecoveryCodes{Codes: codes, Remaining: len(codes)}, nil
+}
+
+//useRecoveryCode marks the recovery code as used and returns the number of remaining ones.
+func (s *service) useRecoveryCode(userID int64, code string) (int, error) {
+	if err := s.storage.UseRecoveryCode(userID, hashRecoveryCode(code)); err != nil {
+		s.logger.Warn("Error using a recovery code!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return 0, err
+	}
+
+	remaining, err := s.storage.CountRecoveryCodes(userID)
+	if err != nil {
+		s.logger.Error("Error counting user's recovery codes!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return 0, err
+	}
+
+	s.logger.Info("Recovery code used.",
+		zap.Int64("userID", userID),
+		zap.Int("remaining", remaining))
+
+	return remaining, nil
+}
+
+func (s *service) isMFAEnabled(userID int64) (bool, error) {
+	totp, err := s.getTOTP(userID)
+	if err == ErrMFANotEnabled {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return totp.Confirmed, nil
+}
+
+//checkTOTP verifies the code of the confirmed TOTP secret and marks its time step as used.
+func (s *service) checkTOTP(userID int64, code string) error {
+	totp, err := s.getTOTP(userID)
+	if err != nil {
+		return err
+	}
+
+	if !totp.Confirmed {
+		return ErrMFANotEnabled
+	}
+
+	step, err := verifyTOTP(totp.Secret, code, totp.LastStep, time.Now())
+	if err != nil {
+		s.logger.Warn("Invalid TOTP code!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return ErrInvalidMFACode
+	}
+
+	if err := s.storage.UseTOTPStep(userID, step); err != nil {
+		s.logger.Error("Error using the TOTP time step!",
+			zap.Int64("userID", userID),
+			zap.Int64("step", step),
+			zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) getTOTP(userID int64) (*TOTP, error) {
+	totp, err := s.storage.GetTOTP(userID)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrMFANotEnabled
+		}
+
+		s.logger.Error("Error getting user's TOTP secret!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	return totp, nil
+}
+
+//RefreshToken rotates user's refresh token.
+//Reuse of an already rotated refresh token revokes the whole session.
+func (s *service) RefreshToken(refreshToken string, clientInfo *ClientInfo) (*AuthData, error) {
+	userID, authData, err := s.refreshToken(refreshToken)
+	s.audit(AuditActionRefresh, clientInfo, userID, "", err)
+	if err != nil {
+		return nil, err
+	}
+
+	return authData, nil
+}
+
+//refreshToken refreshes user auth data, the user id is zero unless the refresh token is valid.
+func (s *service) refreshToken(refreshToken string) (int64, *AuthData, error) {
+	claims, err := s.security.GetRefreshTokenClaims(refreshToken)
+	if err != nil {
+		s.logger.Error("Error refreshing user auth data!",
+			zap.String("refreshToken", refreshToken),
+			zap.Error(err))
+		return 0, nil, err
+	}
+
+	if time.Now().Unix() > claims.ExpiresAt {
+		return claims.UserID, nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.storage.GetUser(claims.UserID)
+	if err != nil {
+		s.logger.Error("Error getting user by id!",
+			zap.Int64("userID", claims.UserID),
+			zap.Error(err))
+		return claims.UserID, nil, err
+	}
+
+	if user.Disabled {
+		return claims.UserID, nil, ErrUserDisabled
+	}
+
+	authData, err := s.security.RefreshAuthData(user, claims.SessionID, refreshToken)
+	if err != nil {
+		s.logger.Error("Error refreshing user auth data!",
+			zap.Int64("userID", user.ID),
+			zap.String("sessionID", claims.SessionID),
+			zap.Error(err))
+		return claims.UserID, nil, err
+	}
+
+	return claims.UserID, authData, nil
+}
+
+//GetUser gets a user by id.
+func (s *service) GetUser(userID int64) (*User, error) {
+	user, err := s.storage.GetUser(userID)
+	if err != nil {
+		s.logger.Error("Error getting user by id!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	return user, nil
+}
+
+//ListUsers lists users page by page, the next page is requested with the returned cursor.
+//The limit defaults to DefaultUserPageSize and is capped by MaxUserPageSize.
+func (s *service) ListUsers(filter *UserFilter) (*UserPage, error) {
+	if filter.Sort == "" {
+		filter.Sort = SortByID
+	}
+	if !isValidUserSort(filter.Sort) {
+		return nil, ErrInvalidRequest
+	}
+
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = DefaultUserPageSize
+	case filter.Limit > MaxUserPageSize:
+		filter.Limit = MaxUserPageSize
+	}
+
+	var after *UserCursor
+	if filter.Cursor != "" {
+		cursor, err := decodeUserCursor(filter.Cursor)
+		if err != nil {
+			s.logger.Warn("Invalid user cursor!",
+				zap.String("cursor", filter.Cursor),
+				zap.Error(err))
+			return nil, err
+		}
+		after = cursor
+	}
+
+	users, more, err := s.storage.ListUsers(filter, after)
+	if err != nil {
+		s.logger.Error("Error listing users!",
+			zap.Any("filter", filter),
+			zap.Error(err))
+		return nil, err
+	}
+
+	page := &UserPage{Users: users}
+	if more && len(users) > 0 {
+		page.NextCursor = encodeUserCursor(users[len(users)-1], filter.Sort)
+	}
+
+	return page, nil
+}
+
+//CreateUser creates a new user with the role.
+func (s *service) CreateUser(newUser *NewUser) (*User, error) {
+	if err := validateUsername(newUser.Username); err != nil {
+		return nil, err
+	}
+
+	if err := validatePassword(newUser.Password); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateRole(newUser.Role); err != nil {
+		return nil, err
+	}
+
+	credentials := &Credentials{
+		Username: newUser.Username,
+		Password: newUser.Password,
+	}
+
+	user, err := s.storage.CreateUser(credentials, newUser.Role)
+	if err != nil {
+		s.logger.Error("Error creating a new user!",
+			zap.String("username", newUser.Username),
+			zap.Error(err))
+		return nil, err
+	}
+
+	return user, nil
+}
+
+//UpdateUser changes the username or the role of the user.
+//A user with a changed role is logged out everywhere so that new tokens carry the new role.
+func (s *service) UpdateUser(userID int64, update *UserUpdate) (*User, error) {
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Username != nil {
+		if err := validateUsername(*update.Username); err != nil {
+			return nil, err
+		}
+		user.Username = *update.Username
+	}
+
+	roleChanged := update.Role != nil && *update.Role != user.Role
+	if roleChanged {
+		if err := s.validateRole(*update.Role); err != nil {
+			return nil, err
+		}
+		user.Role = *update.Role
+	}
+
+	updated, err := s.storage.UpdateUser(user)
+	if err != nil {
+		s.logger.Error("Error updating the user!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	if roleChanged {
+		if err := s.logoutEverywhere(userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return updated, nil
+}
+
+//SetUserDisabled disables or enables the user, a disabled user is logged out everywhere.
+func (s *service) SetUserDisabled(userID int64, disabled bool) error {
+	if err := s.storage.SetUserDisabled(userID, disabled); err != nil {
+		s.logger.Error("Error setting the user disabled!",
+			zap.Int64("userID", userID),
+			zap.Bool("disabled", disabled),
+			zap.Error(err))
+		return err
+	}
+
+	if disabled {
+		return s.logoutEverywhere(userID)
+	}
+
+	return nil
+}
+
+//ResetPassword sets a new password of the user and logs the user out everywhere.
+func (s *service) ResetPassword(userID int64, password string) error {
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+
+	if err := s.storage.UpdateUserPassword(userID, password); err != nil {
+		s.logger.Error("Error resetting user's password!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return err
+	}
+
+	return s.logoutEverywhere(userID)
+}
+
+//DeleteUser deletes the user and logs the user out everywhere.
+func (s *service) DeleteUser(userID int64) error {
+	if err := s.storage.DeleteUser(userID); err != nil {
+		s.logger.Error("Error deleting the user!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return err
+	}
+
+	return s.logoutEverywhere(userID)
+}
+
+func (s *service) validateRole(role string) error {
+	exists, err := s.storage.RoleExists(role)
+	if err != nil {
+		s.logger.Error("Error checking the role!",
+			zap.String("role", role),
+			zap.Error(err))
+		return err
+	}
+
+	if !exists {
+		return ErrInvalidRole
+	}
+
+	return nil
+}
+
+//GetSessions gets user's active sessions and marks the current one.
+func (s *service) GetSessions(userID int64, currentSessionID string) ([]*Session, error) {
+	sessions, err := s.security.GetSessions(userID)
+	if err != nil {
+		s.logger.Error("Error getting user's sessions!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+//RevokeSession invalidates the user's session.
+func (s *service) RevokeSession(userID int64, sessionID string) error {
+	if err := s.security.InvalidateSession(userID, sessionID); err != nil {
+		s.logger.Error("Error revoking user's session!",
+			zap.Int64("userID", userID),
+			zap.String("sessionID", sessionID),
+			zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+//CreateAPIKey creates a new API key of the user limited by the requested scopes within the user's permissions.
+//Keys without scopes are refused, as claims without a scope are not limited by scopes.
+func (s *service) CreateAPIKey(userID int64, request *APIKeyRequest) (*NewAPIKey, error) {
+	if err := validateAPIKeyName(request.Name); err != nil {
+		return nil, err
+	}
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidRequest
+	}
+
+	if len(request.Scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.storage.GetPermissions(user.Role)
+	if err != nil {
+		s.logger.Error("Error getting role permissions!",
+			zap.String("role", user.Role),
+			zap.Error(err))
+		return nil, err
+	}
+
+	scopes, err := grantScopes(request.Scopes, permissions)
+	if err != nil {
+		s.logger.Warn("API key scopes exceed user's permissions!",
+			zap.Int64("userID", userID),
+			zap.Strings("scopes", request.Scopes))
+		return nil, err
+	}
+
+	key, hash, err := newAPIKey()
+	if err != nil {
+		s.logger.Error("Error generating an API key!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return nil, ErrInternalSecurity
+	}
+
+	apiKey, err := s.storage.CreateAPIKey(&APIKey{
+		UserID:    userID,
+		Name:      strings.TrimSpace(request.Name),
+		Scopes:    scopes,
+		ExpiresAt: request.ExpiresAt,
+	}, hash)
+	if err != nil {
+		s.logger.Error("Error creating an API key!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	return &NewAPIKey{
+		APIKey: *apiKey,
+		Key:    key,
+	}, nil
+}
+
+//ListAPIKeys returns user's API keys.
+func (s *service) ListAPIKeys(userID int64) ([]*APIKey, error) {
+	keys, err := s.storage.ListAPIKeys(userID)
+	if err != nil {
+		s.logger.Error("Error listing user's API keys!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+//DeleteAPIKey deletes the user's API key, it is rejected right away.
+func (s *service) DeleteAPIKey(userID, keyID int64) error {
+	if err := s.storage.DeleteAPIKey(userID, keyID); err != nil {
+		s.logger.Error("Error deleting user's API key!",
+			zap.Int64("userID", userID),
+			zap.Int64("apiKeyID", keyID),
+			zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+//Logout invalidates the current user's session.
+func (s *service) Logout(userID int64, sessionID string, clientInfo *ClientInfo) error {
+	err := s.security.InvalidateSession(userID, sessionID)
+	s.audit(AuditActionLogout, clientInfo, userID, "", err)
+	if err != nil {
+		s.logger.Error("Error invalidating user's session!",
+			zap.Int64("userID", userID),
+			zap.String("sessionID", sessionID),
+			zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+//LogoutEverywhere invalidates all user's sessions.
+func (s *service) LogoutEverywhere(userID int64, clientInfo *ClientInfo) error {
+	err := s.logoutEverywhere(userID)
+	s.audit(AuditActionLogoutEverywhere, clientInfo, userID, "", err)
+	return err
+}
+
+func (s *service) logoutEverywhere(userID int64) error {
+	if err := s.security.InvalidateUserAuthData(userID); err != nil {
+		s.logger.Error("Error invalidating user's auth data!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+//GetAccessTokenClaims validates the access token and returns claims if success.
+//Validation failures of non-empty tokens are sampled into the audit log.
+func (s *service) GetAccessTokenClaims(accessToken string, clientInfo *ClientInfo) (*AccessTokenClaims, error) {
+	claims, err := s.security.GetAccessTokenClaims(accessToken)
+	if err != nil {
+		s.logger.Error("Error getting claims from the access token!",
+			zap.String("accessToken", accessToken),
+			zap.Error(err))
+
+		if accessToken != "" {
+			s.auditTokenValidation(clientInfo, err)
+		}
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+//GetAPIKeyClaims returns access token claims of the API key with the current role of its user.
+func (s *service) GetAPIKeyClaims(apiKey string, clientInfo *ClientInfo) (*AccessTokenClaims, error) {
+	claims, err := s.getAPIKeyClaims(apiKey)
+	if err != nil {
+		s.auditTokenValidation(clientInfo, err)
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (s *service) getAPIKeyClaims(apiKey string) (*AccessTokenClaims, error) {
+	key, err := s.storage.UseAPIKey(hashAPIKey(apiKey))
+	if err != nil {
+		s.logger.Error("Error getting the API key!", zap.Error(err))
+
+		if err == ErrNotFound {
+			return nil, ErrInvalidAccessToken
+		}
+		return nil, err
+	}
+
+	user, err := s.storage.GetUser(key.UserID)
+	if err != nil {
+		s.logger.Error("Error getting the API key user!",
+			zap.Int64("userID", key.UserID),
+			zap.Int64("apiKeyID", key.ID),
+			zap.Error(err))
+
+		if err == ErrNotFound {
+			return nil, ErrInvalidAccessToken
+		}
+		return nil, err
+	}
+
+	if user.Disabled {
+		s.logger.Warn("API key of a disabled user used!",
+			zap.Int64("userID", user.ID),
+			zap.Int64("apiKeyID", key.ID))
+		return nil, ErrInvalidAccessToken
+	}
+
+	if len(key.Scopes) == 0 {
+		s.logger.Warn("API key without scopes used!",
+			zap.Int64("userID", user.ID),
+			zap.Int64("apiKeyID", key.ID))
+		return nil, ErrInvalidAccessToken
+	}
+
+	claims := &AccessTokenClaims{
+		UserID:   user.ID,
+		Role:     user.Role,
+		Scope:    formatScope(key.Scopes),
+		APIKeyID: key.ID,
+	}
+	if key.ExpiresAt != nil {
+		claims.ExpiresAt = key.ExpiresAt.Unix()
+	}
+
+	return claims, nil
+}
+
+//GetPermissions returns all permissions of the role including the ones inherited from parent roles.
+func (s *service) GetPermissions(role string) (*RolePermissions, error) {
+	permissions, err := s.storage.GetPermissions(role)
+	if err != nil {
+		s.logger.Error("Error getting role permissions!",
+			zap.String("role", role),
+			zap.Error(err))
+		return nil, err
+	}
+
+	return &RolePermissions{
+		Role:        role,
+		Permissions: permissions,
+	}, nil
+}
+
+//CheckPermission returns ErrPermissionDenied unless the role has the permission.
+func (s *service) CheckPermission(role, permission string) error {
+	permissions, err := s.storage.GetPermissions(role)
+	if err != nil {
+		s.logger.Error("Error getting role permissions!",
+			zap.String("role", role),
+			zap.Error(err))
+		return err
+	}
+
+	for _, p := range permissions {
+		if p == permission {
+			return nil
+		}
+	}
+
+	s.logger.Warn("Permission denied!",
+		zap.String("role", role),
+		zap.String("permission", permission))
+
+	return ErrPermissionDenied
+}
+
+//ResolveRedirectURI checks that the redirect uri is registered for the client.
+//The only registered redirect uri is used if the redirect uri is omitted.
+//Errors of this check must not be reported by redirecting the user agent.
+func (s *service) ResolveRedirectURI(clientID, redirectURI string) (string, error) {
+	client, err := s.getClient(clientID)
+	if err != nil {
+		return "", err
+	}
+
+	return s.resolveRedirectURI(client, redirectURI)
+}
+
+//Authorize authenticates the user and issues a single-use authorization code for the client.
+//Only the authorization code response type with a PKCE S256 code challenge is supported.
+func (s *service) Authorize(request *AuthorizationRequest, credentials *Credentials, clientInfo *ClientInfo) (string, error) {
+	client, err := s.getClient(request.ClientID)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := s.resolveRedirectURI(client, request.RedirectURI); err != nil {
+		return "", err
+	}
+
+	if request.ResponseType != ResponseTypeCode {
+		return "", ErrUnsupportedResponseType
+	}
+
+	if err := validateCodeChallenge(request.CodeChallenge, request.CodeChallengeMethod); err != nil {
+		return "", err
+	}
+
+	scopes, err := grantScopes(parseScope(request.Scope), client.Scopes)
+	if err != nil {
+		s.logger.Error("Error granting scopes to the client!",
+			zap.String("clientID", client.ClientID),
+			zap.String("scope", request.Scope),
+			zap.Error(err))
+		return "", err
+	}
+
+	if hasScope(scopes, ScopeOpenID) && !s.openIDSupported() {
+		if hasScope(parseScope(request.Scope), ScopeOpenID) {
+			s.logger.Warn("OpenID Connect requested without an asymmetric signing key!",
+				zap.String("clientID", client.ClientID))
+			return "", ErrInvalidScope
+		}
+
+		scopes = withoutScope(scopes, ScopeOpenID)
+		if len(scopes) == 0 {
+			return "", ErrInvalidScope
+		}
+	}
+
+	user, err := s.authenticateUser(credentials, clientInfo)
+	if err != nil {
+		return "", err
+	}
+
+	mfaEnabled, err := s.isMFAEnabled(user.ID)
+	if err != nil {
+		return "", err
+	}
+	if mfaEnabled {
+		return "", ErrMFARequired
+	}
+
+	s.resetLoginAttempts(user.Username)
+
+	code, err := s.security.CreateAuthorizationCode(&AuthorizationCode{
+		ClientID:      client.ClientID,
+		UserID:        user.ID,
+		RedirectURI:   request.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: request.CodeChallenge,
+		Nonce:         request.Nonce,
+	})
+	if err != nil {
+		s.logger.Error("Error creating an authorization code!",
+			zap.String("clientID", client.ClientID),
+			zap.Int64("userID", user.ID),
+			zap.Error(err))
+		return "", err
+	}
+
+	return code, nil
+}
+
+//IssueToken issues a token for the OAuth 2.0 token request.
+func (s *service) IssueToken(request *TokenRequest) (*Token, error) {
+	switch request.GrantType {
+	case GrantTypeClientCredentials:
+		return s.issueClientToken(request)
+	case GrantTypeAuthorizationCode:
+		return s.exchangeAuthorizationCode(request)
+	default:
+		return nil, ErrUnsupportedGrantType
+	}
+}
+
+//AuthenticateConfidentialClient returns the client by its credentials, public clients are refused.
+//Other adapters use it to allow token validation only to confidential clients, the same way as introspection.
+func (s *service) AuthenticateConfidentialClient(credentials *ClientCredentials) (*Client, error) {
+	client, err := s.authenticateClient(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.Public {
+		return nil, ErrUnauthorizedClient
+	}
+
+	return client, nil
+}
+
+//IntrospectToken tells a confidential client whether the access token is active and returns its claims.
+//Tokens of logged out sessions, expired and unknown tokens are inactive.
+func (s *service) IntrospectToken(request *IntrospectionRequest) (*Introspection, error) {
+	client, err := s.AuthenticateConfidentialClient(request.ClientCredentials)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.security.GetAccessTokenClaims(request.Token)
+	if err != nil {
+		if err == ErrInvalidAccessToken {
+			return &Introspection{Active: false}, nil
+		}
+
+		s.logger.Error("Error introspecting the access token!",
+			zap.String("clientID", client.ClientID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	introspection := &Introspection{
+		Active: true,
+		Sub:    claims.Subject,
+		Exp:    claims.ExpiresAt,
+		Scope:  claims.Scope,
+		Role:   claims.Role,
+	}
+	if claims.ClientID == "" {
+		introspection.Sub = subject(claims.UserID)
+	}
+
+	return introspection, nil
+}
+
+//RevokeToken revokes the access or refresh token of the client.
+//Unknown tokens are ignored, while tokens issued to another client or by direct logins are refused (RFC 7009, section 2.1).
+func (s *service) RevokeToken(request *RevocationRequest) error {
+	client, err := s.authenticateClient(request.ClientCredentials)
+	if err != nil {
+		return err
+	}
+
+	info, err := s.security.IdentifyToken(request.Token)
+	if err != nil {
+		if err == ErrNotFound {
+			s.logger.Info("Unknown token revocation requested.", zap.String("clientID", client.ClientID))
+			return nil
+		}
+
+		s.logger.Error("Error identifying the token!",
+			zap.String("clientID", client.ClientID),
+			zap.Error(err))
+		return err
+	}
+
+	if info.ClientID != client.ClientID {
+		s.logger.Warn("Client tried to revoke a token of another client!",
+			zap.String("clientID", client.ClientID),
+			zap.String("tokenClientID", info.ClientID))
+		return ErrUnauthorizedClient
+	}
+
+	if err := s.security.RevokeToken(info); err != nil {
+		s.logger.Error("Error revoking the token!",
+			zap.String("clientID", client.ClientID),
+			zap.String("tokenID", info.ID),
+			zap.String("tokenType", info.Type),
+			zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) issueClientToken(request *TokenRequest) (*Token, error) {
+	client, err := s.authenticateClient(request.ClientCredentials)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.Public {
+		return nil, ErrUnauthorizedClient
+	}
+
+	scopes, err := grantScopes(parseScope(request.Scope), client.Scopes)
+	if err != nil {
+		s.logger.Error("Error granting scopes to the client!",
+			zap.String("clientID", client.ClientID),
+			zap.String("scope", request.Scope),
+			zap.Error(err))
+		return nil, err
+	}
+
+	authData, err := s.security.CreateClientAuthData(client, scopes)
+	if err != nil {
+		s.logger.Error("Error creating client auth data!",
+			zap.String("clientID", client.ClientID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	return newToken(authData, scopes), nil
+}
+
+func (s *service) exchangeAuthorizationCode(request *TokenRequest) (*Token, error) {
+	client, err := s.authenticateClient(request.ClientCredentials)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := s.security.ConsumeAuthorizationCode(request.Code)
+	if err != nil {
+		s.logger.Error("Error consuming the authorization code!",
+			zap.String("clientID", client.ClientID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	if code.ClientID != client.ClientID || code.RedirectURI != request.RedirectURI {
+		s.logger.Warn("Authorization code was issued for another client or redirect uri!",
+			zap.String("clientID", client.ClientID),
+			zap.String("redirectURI", request.RedirectURI))
+		return nil, ErrInvalidGrant
+	}
+
+	if err := verifyCodeChallenge(request.CodeVerifier, code.CodeChallenge); err != nil {
+		s.logger.Warn("Code verifier does not match the code challenge!",
+			zap.String("clientID", client.ClientID))
+		return nil, err
+	}
+
+	user, err := s.storage.GetUser(code.UserID)
+	if err != nil {
+		s.logger.Error("Error getting user by id!",
+			zap.Int64("userID", code.UserID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	if user.Disabled {
+		s.logger.Warn("Authorization code was issued for a disabled user!",
+			zap.Int64("userID", user.ID),
+			zap.String("clientID", client.ClientID))
+		return nil, ErrUserDisabled
+	}
+
+	permissions, err := s.storage.GetPermissions(user.Role)
+	if err != nil {
+		s.logger.Error("Error getting role permissions!",
+			zap.String("role", user.Role),
+			zap.Error(err))
+		return nil, err
+	}
+
+	scopes := limitScopes(code.Scopes, permissions)
+	if len(scopes) == 0 {
+		s.logger.Warn("User has none of the granted scopes!",
+			zap.Int64("userID", user.ID),
+			zap.Strings("scopes", code.Scopes))
+		return nil, ErrInvalidScope
+	}
+
+	if hasScope(scopes, ScopeOpenID) && !s.openIDSupported() {
+		s.logger.Warn("Authorization code grants OpenID Connect without an asymmetric signing key!",
+			zap.String("clientID", client.ClientID))
+		return nil, ErrInvalidScope
+	}
+
+	authData, err := s.security.CreateAuthData(user, client.ClientID, scopes)
+	if err != nil {
+		s.logger.Error("Error creating user auth data!",
+			zap.Int64("userID", user.ID),
+			zap.Error(err))
+		return nil, err
+	}
+
+	token := newToken(authData, scopes)
+
+	if hasScope(scopes, ScopeOpenID) {
+		token.IDToken, err = s.newIDToken(user, client.ClientID, code.Nonce)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return token, nil
+}
+
+//openIDSupported returns true if tokens are signed with an asymmetric key published in the JWKS.
+//Clients can't verify ID tokens signed with the HS256 secret, so OpenID Connect is disabled with it.
+func (s *service) openIDSupported() bool {
+	return s.security.GetSigningAlgorithm() != signingAlgorithmHS256
+}
+
+func (s *service) newIDToken(user *User, clientID, nonce string) (string, error) {
+	now := time.Now()
+
+	idToken, err := s.security.CreateIDToken(&IDTokenClaims{
+		Nonce:             nonce,
+		PreferredUsername: user.Username,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    s.config.Issuer,
+			Subject:   subject(user.ID),
+			Audience:  clientID,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(s.config.IDTokenLifetime).Unix(),
+		},
+	})
+	if err != nil {
+		s.logger.Error("Error creating an ID token!",
+			zap.Int64("userID", user.ID),
+			zap.String("clientID", clientID),
+			zap.Error(err))
+		return "", err
+	}
+
+	return idToken, nil
+}
+
+//authenticateClient authenticates confidential clients with the secret.
+//Public clients cannot keep a secret and are identified by the client id only.
+func (s *service) authenticateClient(credentials *ClientCredentials) (*Client, error) {
+	if credentials == nil {
+		return nil, ErrInvalidClient
+	}
+
+	if credentials.ClientSecret != "" {
+		client, err := s.storage.GetClientByCredentials(credentials)
+		if err != nil {
+			s.logger.Error("Error getting client by credentials!",
+				zap.String("clientID", credentials.ClientID),
+				zap.Error(err))
+			return nil, err
+		}
+		return client, nil
+	}
+
+	client, err := s.getClient(credentials.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.Public {
+		return nil, ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+func (s *service) getClient(clientID string) (*Client, error) {
+	client, err := s.storage.GetClient(clientID)
+	if err != nil {
+		s.logger.Error("Error getting client!",
+			zap.String("clientID", clientID),
+			zap.Error(err))
+
+		if err == ErrNotFound {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (s *service) resolveRedirectURI(client *Client, redirectURI string) (string, error) {
+	if redirectURI == "" {
+		if len(client.RedirectURIs) != 1 {
+			return "", ErrInvalidRedirectURI
+		}
+		return client.RedirectURIs[0], nil
+	}
+
+	for _, registered := range client.RedirectURIs {
+		if redirectURI == registered {
+			return redirectURI, nil
+		}
+	}
+
+	s.logger.Warn("Redirect uri is not registered for the client!",
+		zap.String("clientID", client.ClientID),
+		zap.String("redirectURI", redirectURI))
+	return "", ErrInvalidRedirectURI
+}
+
+func newToken(authData *AuthData, scopes []string) *Token {
+	return &Token{
+		AccessToken:  authData.AccessToken,
+		TokenType:    tokenTypeBearer,
+		ExpiresIn:    authData.ExpiresAt - time.Now().Unix(),
+		RefreshToken: authData.RefreshToken,
+		Scope:        formatScope(scopes),
+	}
+}
+
+//GetJSONWebKeySet returns public keys for token verification.
+func (s *service) GetJSONWebKeySet() *JSONWebKeySet {
+	return s.security.GetJSONWebKeySet()
+}
+
+//GetUserInfo returns OpenID Connect claims about the user.
+func (s *service) GetUserInfo(userID int64) (*UserInfo, error) {
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	userInfo := &UserInfo{
+		Subject:           subject(user.ID),
+		PreferredUsername: user.Username,
+	}
+
+	return userInfo, nil
+}
+
+//GetOpenIDConfiguration returns OpenID Connect provider metadata.
+//Neither the openid scope nor ID token algorithms are advertised while tokens are signed with the HS256 secret.
+func (s *service) GetOpenIDConfiguration() *OpenIDConfiguration {
+	issuer := strings.TrimSuffix(s.config.Issuer, "/")
+
+	scopes := []string{}
+	algorithms := []string{}
+	if algorithm := s.security.GetSigningAlgorithm(); algorithm != signingAlgorithmHS256 {
+		scopes = append(scopes, ScopeOpenID)
+		algorithms = append(algorithms, algorithm)
+	}
+
+	return &OpenIDConfiguration{
+		Issuer:                            s.config.Issuer,
+		AuthorizationEndpoint:             issuer + authorizationEndpoint,
+		TokenEndpoint:                     issuer + tokenEndpoint,
+		IntrospectionEndpoint:             issuer + introspectionEndpoint,
+		RevocationEndpoint:                issuer + revocationEndpoint,
+		UserInfoEndpoint:                  issuer + userInfoEndpoint,
+		JWKSURI:                           issuer + jwksEndpoint,
+		ScopesSupported:                   scopes,
+		ResponseTypesSupported:            []string{ResponseTypeCode},
+		GrantTypesSupported:               []string{GrantTypeAuthorizationCode, GrantTypeClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algorithms,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{CodeChallengeMethodS256},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "iat", "exp", "nonce", "preferred_username"},
+	}
+}
+
+//subject returns the OpenID Connect subject identifier of the user.
+func subject(userID int64) string {
+	return strconv.FormatInt(userID, 10)
+}
+
+//audit records the authentication event, a failure to record it is only logged to not fail the action.
+func (s *service) audit(action string, clientInfo *ClientInfo, userID int64, username string, err error) {
+	event := &AuditEvent{
+		Time:     time.Now(),
+		UserID:   userID,
+		Username: username,
+		Action:   action,
+		Outcome:  AuditOutcomeSuccess,
+	}
+
+	if err != nil {
+		event.Outcome = AuditOutcomeFailure
+		event.Reason = err.Error()
+	}
+
+	if clientInfo != nil {
+		event.IP = clientInfo.IP
+		event.UserAgent = clientInfo.UserAgent
+		event.RequestID = clientInfo.RequestID
+	}
+
+	if err := s.storage.AddAuditEvent(event); err != nil {
+		s.logger.Error("Error adding an audit event!",
+			zap.Any("event", event),
+			zap.Error(err))
+	}
+}
+
+//auditTokenValidation records the token validation failure once per client ip in the sample interval,
+//the next recorded failure tells how many were skipped. Skipped failures of forgotten ips are recorded separately.
+func (s *service) auditTokenValidation(clientInfo *ClientInfo, err error) {
+	var ip string
+	if clientInfo != nil {
+		ip = clientInfo.IP
+	}
+
+	record, suppressed, forgotten := s.tokenFailures.sample(ip, time.Now())
+
+	for forgottenIP, count := range forgotten {
+		s.audit(AuditActionTokenValidation, &ClientInfo{IP: forgottenIP}, 0, "",
+			fmt.Errorf("%d more failures not recorded", count))
+	}
+
+	if !record {
+		return
+	}
+
+	if suppressed > 0 {
+		err = fmt.Errorf("%v, %d more failures not recorded", err, suppressed)
+	}
+
+	s.audit(AuditActionTokenValidation, clientInfo, 0, "", err)
+}
+
+//ListAuditEvents lists audit events from newest to oldest page by page.
+//The limit defaults to DefaultAuditPageSize and is capped by MaxAuditPageSize.
+func (s *service) ListAuditEvents(filter *AuditFilter) (*AuditPage, error) {
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, ErrInvalidRequest
+	}
+
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = DefaultAuditPageSize
+	case filter.Limit > MaxAuditPageSize:
+		filter.Limit = MaxAuditPageSize
+	}
+
+	var after *AuditCursor
+	if filter.Cursor != "" {
+		cursor, err := decodeAuditCursor(filter.Cursor)
+		if err != nil {
+			s.logger.Warn("Invalid audit cursor!",
+				zap.String("cursor", filter.Cursor),
+				zap.Error(err))
+			return nil, err
+		}
+		after = cursor
+	}
+
+	events, more, err := s.storage.ListAuditEvents(filter, after)
+	if err != nil {
+		s.logger.Error("Error listing audit events!",
+			zap.Any("filter", filter),
+			zap.Error(err))
+		return nil, err
+	}
+
+	page := &AuditPage{Events: events}
+	if more && len(events) > 0 {
+		page.NextCursor = encodeAuditCursor(events[len(events)-1])
+	}
+
+	return page, nil
+}
+
+//PurgeAuditEvents deletes audit events older than the retention period, zero retention keeps all events.
+func (s *service) PurgeAuditEvents() error {
+	if s.config.AuditRetention <= 0 {
+		return nil
+	}
+
+	deleted, err := s.storage.DeleteAuditEvents(time.Now().Add(-s.config.AuditRetention))
+	if err != nil {
+		s.logger.Error("Error purging audit events!", zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("Audit events purged.", zap.Int64("deleted", deleted))
+	return nil
 }
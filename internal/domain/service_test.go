@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,6 +14,19 @@ import (
 	"go.uber.org/zap"
 )
 
+var config = &Config{
+	RegistrationEnabled: true,
+	DefaultRole:         "user",
+	Issuer:              "https://goss.example.com",
+	IDTokenLifetime:     time.Hour,
+}
+
+var clientInfo = &ClientInfo{
+	IP:        "192.0.2.1",
+	UserAgent: "test",
+	RequestID: "request",
+}
+
 func TestNewService(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	logger := zap.NewExample()
@@ -19,11 +34,12 @@ func TestNewService(t *testing.T) {
 	security := NewMockSecurity(ctrl)
 	expected := &service{
 		logger:   logger,
+		config:   config,
 		storage:  storage,
 		security: security,
 	}
 
-	actual := NewService(logger, storage, security)
+	actual := NewService(logger, config, storage, security)
 	require.Equal(t, expected, actual)
 }
 
@@ -49,90 +65,106 @@ func TestService_CheckHealth(t *testing.T) {
 	require.Equal(t, expected, actual)
 }
 
-func TestService_Login(t *testing.T) {
+func TestService_Register(t *testing.T) {
 	t.Run("with valid credentials", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
 		credentials := &Credentials{
-			Username: "user",
+			Username: "alice",
 			Password: "password",
 		}
 
-		user := &User{
-			ID:       1,
-			Username: "user",
-			Role:     "client",
-		}
-
-		expected := &AuthData{
-			AccessToken:  "accessToken",
-			ExpiresAt:    time.Now().Add(24 * time.Hour).Unix(),
-			RefreshToken: "refreshToken",
+		expected := &User{
+			ID:       42,
+			Username: "alice",
+			Role:     "user",
 		}
 
 		logger := zap.NewExample()
 		storage := NewMockStorage(ctrl)
-		storage.EXPECT().GetUserByCredentials(credentials).Return(user, nil)
-		security := NewMockSecurity(ctrl)
-		security.EXPECT().CreateAuthData(user).Return(expected, nil)
+		storage.EXPECT().CreateUser(credentials, "user").Return(expected, nil)
 		service := &service{
-			logger:   logger,
-			storage:  storage,
-			security: security,
+			logger:  logger,
+			config:  config,
+			storage: storage,
 		}
 
-		actual, err := service.Login(credentials)
+		actual, err := service.Register(credentials)
 		require.NoError(t, err)
 		require.Equal(t, expected, actual)
 	})
 
-	t.Run("with invalid credentials", func(t *testing.T) {
-		ctrl := gomock.NewController(t)
-
+	t.Run("with invalid username", func(t *testing.T) {
 		credentials := &Credentials{
-			Username: "user",
+			Username: "a!",
 			Password: "password",
 		}
 
-		expected := ErrInvalidCredentials
+		service := &service{
+			logger: zap.NewExample(),
+			config: config,
+		}
+
+		_, err := service.Register(credentials)
+		require.Equal(t, ErrInvalidUsername, err)
+	})
+
+	t.Run("with short password", func(t *testing.T) {
+		credentials := &Credentials{
+			Username: "alice",
+			Password: "secret",
+		}
 
-		logger := zap.NewExample()
-		storage := NewMockStorage(ctrl)
-		storage.EXPECT().GetUserByCredentials(credentials).Return(nil, expected)
 		service := &service{
-			logger:  logger,
-			storage: storage,
+			logger: zap.NewExample(),
+			config: config,
 		}
 
-		_, err := service.Login(credentials)
-		require.Error(t, err)
-		require.Equal(t, expected, err)
+		_, err := service.Register(credentials)
+		require.Equal(t, ErrInvalidPassword, err)
 	})
 
-	t.Run("with broken storage", func(t *testing.T) {
+	t.Run("with existing username", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
 		credentials := &Credentials{
-			Username: "user",
+			Username: "alice",
 			Password: "password",
 		}
 
-		expected := ErrInternalStorage
+		expected := ErrUserAlreadyExists
 
 		logger := zap.NewExample()
 		storage := NewMockStorage(ctrl)
-		storage.EXPECT().GetUserByCredentials(credentials).Return(nil, expected)
+		storage.EXPECT().CreateUser(credentials, "user").Return(nil, expected)
 		service := &service{
 			logger:  logger,
+			config:  config,
 			storage: storage,
 		}
 
-		_, err := service.Login(credentials)
-		require.Error(t, err)
+		_, err := service.Register(credentials)
 		require.Equal(t, expected, err)
 	})
 
-	t.Run("with broken security", func(t *testing.T) {
+	t.Run("with disabled registration", func(t *testing.T) {
+		credentials := &Credentials{
+			Username: "alice",
+			Password: "password",
+		}
+
+		service := &service{
+			logger: zap.NewExample(),
+			config: &Config{DefaultRole: "user"},
+		}
+
+		_, err := service.Register(credentials)
+		require.Equal(t, ErrRegistrationDisabled, err)
+	})
+}
+
+func TestService_Login(t *testing.T) {
+	t.Run("with valid credentials", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
 		credentials := &Credentials{
@@ -141,115 +173,137 @@ func TestService_Login(t *testing.T) {
 		}
 
 		user := &User{
-			ID:       42,
+			ID:       1,
 			Username: "user",
 			Role:     "client",
 		}
 
-		expected := ErrInternalSecurity
+		expected := &AuthData{
+			AccessToken:  "accessToken",
+			ExpiresAt:    time.Now().Add(24 * time.Hour).Unix(),
+			RefreshToken: "refreshToken",
+		}
 
 		logger := zap.NewExample()
 		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
 		storage.EXPECT().GetUserByCredentials(credentials).Return(user, nil)
+		storage.EXPECT().GetTOTP(user.ID).Return(nil, ErrNotFound)
 		security := NewMockSecurity(ctrl)
-		security.EXPECT().CreateAuthData(user).Return(nil, expected)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		security.EXPECT().ResetLoginAttempts("user").Return(nil)
+		security.EXPECT().CreateAuthData(user, "", []string{ScopeAll}).Return(expected, nil)
 		service := &service{
 			logger:   logger,
 			storage:  storage,
 			security: security,
 		}
 
-		_, err := service.Login(credentials)
-		require.Error(t, err)
-		require.Equal(t, expected, err)
+		actual, err := service.Login(credentials, clientInfo)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
 	})
-}
 
-func TestService_RefreshToken(t *testing.T) {
-	t.Run("with valid token", func(t *testing.T) {
+	t.Run("with scope", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		refreshToken := "refreshToken"
-
-		claims := &RefreshTokenClaims{
-			UserID: 42,
-			StandardClaims: jwt.StandardClaims{
-				ExpiresAt: time.Now().Add(time.Hour).Unix(),
-			},
+		credentials := &Credentials{
+			Username: "user",
+			Password: "password",
+			Scope:    "users:read",
 		}
 
 		user := &User{
-			ID:       42,
-			Username: "alice",
-			Role:     "client",
+			ID:       1,
+			Username: "user",
+			Role:     "admin",
 		}
 
 		expected := &AuthData{
-			AccessToken:  "newAccessToken",
+			AccessToken:  "accessToken",
 			ExpiresAt:    time.Now().Add(24 * time.Hour).Unix(),
-			RefreshToken: "newRefreshToken",
+			RefreshToken: "refreshToken",
 		}
 
 		logger := zap.NewExample()
 		storage := NewMockStorage(ctrl)
-		storage.EXPECT().GetUser(int64(42)).Return(user, nil)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetUserByCredentials(credentials).Return(user, nil)
+		storage.EXPECT().GetTOTP(user.ID).Return(nil, ErrNotFound)
+		storage.EXPECT().GetPermissions("admin").Return([]string{"users:read", "users:write"}, nil)
 		security := NewMockSecurity(ctrl)
-		security.EXPECT().GetRefreshTokenClaims(refreshToken).Return(claims, nil)
-		security.EXPECT().CreateAuthData(user).Return(expected, nil)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		security.EXPECT().ResetLoginAttempts("user").Return(nil)
+		security.EXPECT().CreateAuthData(user, "", []string{"users:read"}).Return(expected, nil)
 		service := &service{
 			logger:   logger,
-			security: security,
 			storage:  storage,
+			security: security,
 		}
 
-		actual, err := service.RefreshToken(refreshToken)
+		actual, err := service.Login(credentials, clientInfo)
 		require.NoError(t, err)
 		require.Equal(t, expected, actual)
 	})
 
-	t.Run("with expired token", func(t *testing.T) {
+	t.Run("with scope beyond permissions", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		refreshToken := "refreshToken"
-
-		claims := &RefreshTokenClaims{
-			UserID: 42,
-			StandardClaims: jwt.StandardClaims{
-				ExpiresAt: time.Now().Add(-time.Hour).Unix(),
-			},
+		credentials := &Credentials{
+			Username: "user",
+			Password: "password",
+			Scope:    "audit:read",
 		}
 
-		expected := ErrInvalidRefreshToken
+		user := &User{
+			ID:       1,
+			Username: "user",
+			Role:     "user",
+		}
 
 		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetUserByCredentials(credentials).Return(user, nil)
+		storage.EXPECT().GetTOTP(user.ID).Return(nil, ErrNotFound)
+		storage.EXPECT().GetPermissions("user").Return([]string{"profile:read", "profile:write"}, nil)
 		security := NewMockSecurity(ctrl)
-		security.EXPECT().GetRefreshTokenClaims(refreshToken).Return(claims, nil)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		security.EXPECT().ResetLoginAttempts("user").Return(nil)
 		service := &service{
 			logger:   logger,
+			storage:  storage,
 			security: security,
 		}
 
-		_, err := service.RefreshToken(refreshToken)
-		require.Error(t, err)
-		require.Equal(t, expected, err)
+		_, err := service.Login(credentials, clientInfo)
+		require.Equal(t, ErrInvalidScope, err)
 	})
 
-	t.Run("with invalid token", func(t *testing.T) {
+	t.Run("with invalid credentials", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		refreshToken := "refreshToken"
+		credentials := &Credentials{
+			Username: "user",
+			Password: "password",
+		}
 
-		expected := ErrInvalidRefreshToken
+		expected := ErrInvalidCredentials
 
 		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetUserByCredentials(credentials).Return(nil, expected)
 		security := NewMockSecurity(ctrl)
-		security.EXPECT().GetRefreshTokenClaims(refreshToken).Return(nil, expected)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		security.EXPECT().AddFailedLoginAttempt("user", clientInfo.IP).Return(nil)
 		service := &service{
 			logger:   logger,
+			storage:  storage,
 			security: security,
 		}
 
-		_, err := service.RefreshToken(refreshToken)
+		_, err := service.Login(credentials, clientInfo)
 		require.Error(t, err)
 		require.Equal(t, expected, err)
 	})
@@ -257,29 +311,26 @@ func TestService_RefreshToken(t *testing.T) {
 	t.Run("with broken storage", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		refreshToken := "refreshToken"
-
-		claims := &RefreshTokenClaims{
-			UserID: 42,
-			StandardClaims: jwt.StandardClaims{
-				ExpiresAt: time.Now().Add(time.Hour).Unix(),
-			},
+		credentials := &Credentials{
+			Username: "user",
+			Password: "password",
 		}
 
 		expected := ErrInternalStorage
 
 		logger := zap.NewExample()
 		storage := NewMockStorage(ctrl)
-		storage.EXPECT().GetUser(int64(42)).Return(nil, expected)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetUserByCredentials(credentials).Return(nil, expected)
 		security := NewMockSecurity(ctrl)
-		security.EXPECT().GetRefreshTokenClaims(refreshToken).Return(claims, nil)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
 		service := &service{
 			logger:   logger,
-			security: security,
 			storage:  storage,
+			security: security,
 		}
 
-		_, err := service.RefreshToken(refreshToken)
+		_, err := service.Login(credentials, clientInfo)
 		require.Error(t, err)
 		require.Equal(t, expected, err)
 	})
@@ -287,137 +338,2826 @@ func TestService_RefreshToken(t *testing.T) {
 	t.Run("with broken security", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		refreshToken := "refreshToken"
+		credentials := &Credentials{
+			Username: "user",
+			Password: "password",
+		}
+
+		user := &User{
+			ID:       42,
+			Username: "user",
+			Role:     "client",
+		}
 
 		expected := ErrInternalSecurity
 
 		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetUserByCredentials(credentials).Return(user, nil)
+		storage.EXPECT().GetTOTP(user.ID).Return(nil, ErrNotFound)
 		security := NewMockSecurity(ctrl)
-		security.EXPECT().GetRefreshTokenClaims(refreshToken).Return(nil, expected)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		security.EXPECT().ResetLoginAttempts("user").Return(nil)
+		security.EXPECT().CreateAuthData(user, "", []string{ScopeAll}).Return(nil, expected)
 		service := &service{
 			logger:   logger,
+			storage:  storage,
 			security: security,
 		}
 
-		_, err := service.RefreshToken(refreshToken)
+		_, err := service.Login(credentials, clientInfo)
 		require.Error(t, err)
 		require.Equal(t, expected, err)
 	})
-}
 
-func TestService_GetUser(t *testing.T) {
-	t.Run("normal", func(t *testing.T) {
+	t.Run("with two-factor authentication", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		expected := &User{
-			ID:       42,
-			Username: "username",
-			Role:     "user",
+		credentials := &Credentials{
+			Username: "user",
+			Password: "password",
+		}
+
+		user := &User{
+			ID:       1,
+			Username: "user",
+			Role:     "client",
+		}
+
+		expected := &AuthData{
+			MFAToken: "mfaToken",
 		}
 
 		logger := zap.NewExample()
 		storage := NewMockStorage(ctrl)
-		storage.EXPECT().GetUser(expected.ID).Return(expected, nil)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetUserByCredentials(credentials).Return(user, nil)
+		storage.EXPECT().GetTOTP(user.ID).Return(&TOTP{Secret: "secret", Confirmed: true}, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		security.EXPECT().CreateMFAToken(user).Return("mfaToken", nil)
 		service := &service{
-			logger:  logger,
-			storage: storage,
+			logger:   logger,
+			storage:  storage,
+			security: security,
 		}
 
-		actual, err := service.GetUser(42)
+		actual, err := service.Login(credentials, clientInfo)
 		require.NoError(t, err)
 		require.Equal(t, expected, actual)
 	})
-}
 
-func TestService_Logout(t *testing.T) {
-	t.Run("normal", func(t *testing.T) {
+	t.Run("with unconfirmed two-factor authentication", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		userID := int64(42)
+		credentials := &Credentials{
+			Username: "user",
+			Password: "password",
+		}
+
+		user := &User{
+			ID:       1,
+			Username: "user",
+			Role:     "client",
+		}
+
+		expected := &AuthData{
+			AccessToken:  "accessToken",
+			ExpiresAt:    time.Now().Add(24 * time.Hour).Unix(),
+			RefreshToken: "refreshToken",
+		}
 
 		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetUserByCredentials(credentials).Return(user, nil)
+		storage.EXPECT().GetTOTP(user.ID).Return(&TOTP{Secret: "secret"}, nil)
 		security := NewMockSecurity(ctrl)
-		security.EXPECT().InvalidateUserAuthData(userID).Return(nil)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		security.EXPECT().ResetLoginAttempts("user").Return(nil)
+		security.EXPECT().CreateAuthData(user, "", []string{ScopeAll}).Return(expected, nil)
 		service := &service{
 			logger:   logger,
+			storage:  storage,
 			security: security,
 		}
 
-		require.NoError(t, service.Logout(userID))
+		actual, err := service.Login(credentials, clientInfo)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
 	})
 
-	t.Run("with broken security", func(t *testing.T) {
+	t.Run("with locked out login", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		userID := int64(42)
-		expected := ErrInternalSecurity
+		credentials := &Credentials{
+			Username: "user",
+			Password: "password",
+		}
+
+		expected := &LockoutError{RetryAfter: time.Minute}
 
 		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
 		security := NewMockSecurity(ctrl)
-		security.EXPECT().InvalidateUserAuthData(userID).Return(expected)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(expected)
 		service := &service{
 			logger:   logger,
+			storage:  storage,
 			security: security,
 		}
 
-		err := service.Logout(userID)
-		require.Error(t, err)
+		_, err := service.Login(credentials, clientInfo)
 		require.Equal(t, expected, err)
+		require.True(t, errors.Is(err, ErrTooManyAttempts))
 	})
-}
 
-func TestService_GetClaims(t *testing.T) {
-	t.Run("with valid token", func(t *testing.T) {
+	t.Run("with disabled user", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		userID := int64(42)
+		credentials := &Credentials{
+			Username: "user",
+			Password: "password",
+		}
+
+		user := &User{
+			ID:       1,
+			Username: "user",
+			Role:     "client",
+			Disabled: true,
+		}
 
 		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetUserByCredentials(credentials).Return(user, nil)
 		security := NewMockSecurity(ctrl)
-		security.EXPECT().InvalidateUserAuthData(userID).Return(nil)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
 		service := &service{
 			logger:   logger,
+			storage:  storage,
 			security: security,
 		}
 
-		require.NoError(t, service.Logout(userID))
+		_, err := service.Login(credentials, clientInfo)
+		require.Equal(t, ErrUserDisabled, err)
 	})
+}
 
-	t.Run("with invalid token", func(t *testing.T) {
+func TestService_LoginMFA(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	user := &User{
+		ID:       1,
+		Username: "user",
+		Role:     "client",
+	}
+
+	authData := &AuthData{
+		AccessToken:  "accessToken",
+		ExpiresAt:    time.Now().Add(24 * time.Hour).Unix(),
+		RefreshToken: "refreshToken",
+	}
+
+	t.Run("with valid code", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		userID := int64(42)
-		expected := ErrInvalidAccessToken
+		step := time.Now().Unix() / totpPeriod
+		key, _ := totpEncoding.DecodeString(secret)
+		code := totpCode(key, step)
 
 		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetTOTP(user.ID).Return(&TOTP{Secret: secret, Confirmed: true}, nil)
+		storage.EXPECT().UseTOTPStep(user.ID, step).Return(nil)
+		storage.EXPECT().GetUser(user.ID).Return(user, nil)
 		security := NewMockSecurity(ctrl)
-		security.EXPECT().InvalidateUserAuthData(userID).Return(expected)
+		security.EXPECT().ConsumeMFAToken("mfaToken").Return(user.ID, nil)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		security.EXPECT().ResetLoginAttempts("user").Return(nil)
+		security.EXPECT().CreateAuthData(user, "", []string{ScopeAll}).Return(authData, nil)
 		service := &service{
 			logger:   logger,
+			storage:  storage,
 			security: security,
 		}
 
-		err := service.Logout(userID)
-		require.Error(t, err)
+		actual, err := service.LoginMFA(&MFACredentials{MFAToken: "mfaToken", Code: code}, clientInfo)
+		require.NoError(t, err)
+		require.Equal(t, authData, actual)
+	})
+
+	t.Run("with invalid code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetTOTP(user.ID).Return(&TOTP{Secret: secret, Confirmed: true}, nil)
+		storage.EXPECT().GetUser(user.ID).Return(user, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().ConsumeMFAToken("mfaToken").Return(user.ID, nil)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		security.EXPECT().AddFailedLoginAttempt("user", clientInfo.IP).Return(nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.LoginMFA(&MFACredentials{MFAToken: "mfaToken", Code: "abcdef"}, clientInfo)
+		require.Equal(t, ErrInvalidMFACode, err)
+	})
+
+	t.Run("with replayed code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		step := time.Now().Unix() / totpPeriod
+		key, _ := totpEncoding.DecodeString(secret)
+		code := totpCode(key, step)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetTOTP(user.ID).Return(&TOTP{Secret: secret, Confirmed: true, LastStep: step}, nil)
+		storage.EXPECT().GetUser(user.ID).Return(user, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().ConsumeMFAToken("mfaToken").Return(user.ID, nil)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		security.EXPECT().AddFailedLoginAttempt("user", clientInfo.IP).Return(nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.LoginMFA(&MFACredentials{MFAToken: "mfaToken", Code: code}, clientInfo)
+		require.Equal(t, ErrInvalidMFACode, err)
+	})
+
+	t.Run("with invalid mfa token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().ConsumeMFAToken("mfaToken").Return(int64(0), ErrInvalidMFAToken)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.LoginMFA(&MFACredentials{MFAToken: "mfaToken", Code: "123456"}, clientInfo)
+		require.Equal(t, ErrInvalidMFAToken, err)
+	})
+
+	t.Run("with locked out login", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		expected := &LockoutError{RetryAfter: time.Minute}
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetUser(user.ID).Return(user, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().ConsumeMFAToken("mfaToken").Return(user.ID, nil)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(expected)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.LoginMFA(&MFACredentials{MFAToken: "mfaToken", Code: "123456"}, clientInfo)
 		require.Equal(t, expected, err)
+		require.True(t, errors.Is(err, ErrTooManyAttempts))
 	})
 
-	t.Run("with broken security", func(t *testing.T) {
+	t.Run("with recovery code", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 
-		userID := int64(42)
-		expected := ErrInternalSecurity
+		remaining := 9
+		expected := &AuthData{
+			AccessToken:            authData.AccessToken,
+			ExpiresAt:              authData.ExpiresAt,
+			RefreshToken:           authData.RefreshToken,
+			RecoveryCodesRemaining: &remaining,
+		}
 
 		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().UseRecoveryCode(user.ID, hashRecoveryCode("abcde-fghij")).Return(nil)
+		storage.EXPECT().CountRecoveryCodes(user.ID).Return(remaining, nil)
+		storage.EXPECT().GetUser(user.ID).Return(user, nil)
 		security := NewMockSecurity(ctrl)
-		security.EXPECT().InvalidateUserAuthData(userID).Return(expected)
+		security.EXPECT().ConsumeMFAToken("mfaToken").Return(user.ID, nil)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		security.EXPECT().ResetLoginAttempts("user").Return(nil)
+		security.EXPECT().CreateAuthData(user, "", []string{ScopeAll}).Return(&AuthData{
+			AccessToken:  authData.AccessToken,
+			ExpiresAt:    authData.ExpiresAt,
+			RefreshToken: authData.RefreshToken,
+		}, nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		actual, err := service.LoginMFA(&MFACredentials{MFAToken: "mfaToken", RecoveryCode: "ABCDE-FGHIJ"}, clientInfo)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("with used recovery code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().UseRecoveryCode(user.ID, hashRecoveryCode("abcde-fghij")).Return(ErrInvalidMFACode)
+		storage.EXPECT().GetUser(user.ID).Return(user, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().ConsumeMFAToken("mfaToken").Return(user.ID, nil)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		security.EXPECT().AddFailedLoginAttempt("user", clientInfo.IP).Return(nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.LoginMFA(&MFACredentials{MFAToken: "mfaToken", RecoveryCode: "abcde-fghij"}, clientInfo)
+		require.Equal(t, ErrInvalidMFACode, err)
+	})
+}
+
+func TestService_EnrollTOTP(t *testing.T) {
+	user := &User{
+		ID:       1,
+		Username: "user",
+		Role:     "client",
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		var secret string
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetUser(user.ID).Return(user, nil)
+		storage.EXPECT().SaveTOTP(user.ID, gomock.Any()).DoAndReturn(func(_ int64, s string) error {
+			secret = s
+			return nil
+		})
+		service := &service{
+			logger:  logger,
+			config:  &Config{TOTPIssuer: "goss"},
+			storage: storage,
+		}
+
+		actual, err := service.EnrollTOTP(user.ID)
+		require.NoError(t, err)
+		require.Equal(t, secret, actual.Secret)
+		require.Len(t, actual.Secret, 32)
+		require.Equal(t, "otpauth://totp/goss:user?algorithm=SHA1&digits=6&issuer=goss&period=30&secret="+secret, actual.URI)
+	})
+
+	t.Run("with enabled two-factor authentication", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetUser(user.ID).Return(user, nil)
+		storage.EXPECT().SaveTOTP(user.ID, gomock.Any()).Return(ErrMFAAlreadyEnabled)
+		service := &service{
+			logger:  logger,
+			config:  &Config{TOTPIssuer: "goss"},
+			storage: storage,
+		}
+
+		_, err := service.EnrollTOTP(user.ID)
+		require.Equal(t, ErrMFAAlreadyEnabled, err)
+	})
+}
+
+func TestService_ConfirmTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	t.Run("with valid code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		step := time.Now().Unix() / totpPeriod
+		key, _ := totpEncoding.DecodeString(secret)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetTOTP(int64(1)).Return(&TOTP{Secret: secret}, nil)
+		storage.EXPECT().ConfirmTOTP(int64(1), step).Return(nil)
+		storage.EXPECT().ReplaceRecoveryCodes(int64(1), gomock.Any()).Return(nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		actual, err := service.ConfirmTOTP(1, totpCode(key, step))
+		require.NoError(t, err)
+		require.Len(t, actual.Codes, recoveryCodesCount)
+		require.Equal(t, recoveryCodesCount, actual.Remaining)
+	})
+
+	t.Run("without enrollment", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetTOTP(int64(1)).Return(nil, ErrNotFound)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.ConfirmTOTP(1, "123456")
+		require.Equal(t, ErrMFANotEnabled, err)
+	})
+
+	t.Run("with confirmed secret", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetTOTP(int64(1)).Return(&TOTP{Secret: secret, Confirmed: true}, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.ConfirmTOTP(1, "123456")
+		require.Equal(t, ErrMFAAlreadyEnabled, err)
+	})
+}
+
+func TestService_DisableTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	t.Run("with valid code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		step := time.Now().Unix() / totpPeriod
+		key, _ := totpEncoding.DecodeString(secret)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetTOTP(int64(1)).Return(&TOTP{Secret: secret, Confirmed: true}, nil)
+		storage.EXPECT().UseTOTPStep(int64(1), step).Return(nil)
+		storage.EXPECT().DeleteTOTP(int64(1)).Return(nil)
+		storage.EXPECT().ReplaceRecoveryCodes(int64(1), nil).Return(nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		require.NoError(t, service.DisableTOTP(1, totpCode(key, step)))
+	})
+
+	t.Run("with invalid code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetTOTP(int64(1)).Return(&TOTP{Secret: secret, Confirmed: true}, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		require.Equal(t, ErrInvalidMFACode, service.DisableTOTP(1, "abcdef"))
+	})
+}
+
+func TestService_RefreshToken(t *testing.T) {
+	t.Run("with valid token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		refreshToken := "refreshToken"
+
+		claims := &RefreshTokenClaims{
+			UserID:    42,
+			SessionID: "session",
+			StandardClaims: jwt.StandardClaims{
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			},
+		}
+
+		user := &User{
+			ID:       42,
+			Username: "alice",
+			Role:     "client",
+		}
+
+		expected := &AuthData{
+			AccessToken:  "newAccessToken",
+			ExpiresAt:    time.Now().Add(24 * time.Hour).Unix(),
+			RefreshToken: "newRefreshToken",
+		}
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetUser(int64(42)).Return(user, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetRefreshTokenClaims(refreshToken).Return(claims, nil)
+		security.EXPECT().RefreshAuthData(user, "session", "refreshToken").Return(expected, nil)
+		service := &service{
+			logger:   logger,
+			security: security,
+			storage:  storage,
+		}
+
+		actual, err := service.RefreshToken(refreshToken, clientInfo)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("with expired token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		refreshToken := "refreshToken"
+
+		claims := &RefreshTokenClaims{
+			UserID:    42,
+			SessionID: "session",
+			StandardClaims: jwt.StandardClaims{
+				ExpiresAt: time.Now().Add(-time.Hour).Unix(),
+			},
+		}
+
+		expected := ErrInvalidRefreshToken
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetRefreshTokenClaims(refreshToken).Return(claims, nil)
 		service := &service{
 			logger:   logger,
+			storage:  storage,
 			security: security,
 		}
 
-		err := service.Logout(userID)
+		_, err := service.RefreshToken(refreshToken, clientInfo)
 		require.Error(t, err)
 		require.Equal(t, expected, err)
 	})
+
+	t.Run("with invalid token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		refreshToken := "refreshToken"
+
+		expected := ErrInvalidRefreshToken
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetRefreshTokenClaims(refreshToken).Return(nil, expected)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.RefreshToken(refreshToken, clientInfo)
+		require.Error(t, err)
+		require.Equal(t, expected, err)
+	})
+
+	t.Run("with reused token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		refreshToken := "refreshToken"
+
+		expected := ErrRefreshTokenReused
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetRefreshTokenClaims(refreshToken).Return(nil, expected)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.RefreshToken(refreshToken, clientInfo)
+		require.Error(t, err)
+		require.Equal(t, expected, err)
+	})
+
+	t.Run("with broken storage", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		refreshToken := "refreshToken"
+
+		claims := &RefreshTokenClaims{
+			UserID:    42,
+			SessionID: "session",
+			StandardClaims: jwt.StandardClaims{
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			},
+		}
+
+		expected := ErrInternalStorage
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetUser(int64(42)).Return(nil, expected)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetRefreshTokenClaims(refreshToken).Return(claims, nil)
+		service := &service{
+			logger:   logger,
+			security: security,
+			storage:  storage,
+		}
+
+		_, err := service.RefreshToken(refreshToken, clientInfo)
+		require.Error(t, err)
+		require.Equal(t, expected, err)
+	})
+
+	t.Run("with broken security", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		refreshToken := "refreshToken"
+
+		expected := ErrInternalSecurity
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetRefreshTokenClaims(refreshToken).Return(nil, expected)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.RefreshToken(refreshToken, clientInfo)
+		require.Error(t, err)
+		require.Equal(t, expected, err)
+	})
+}
+
+func TestService_GetUser(t *testing.T) {
+	t.Run("normal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		expected := &User{
+			ID:       42,
+			Username: "username",
+			Role:     "user",
+		}
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetUser(expected.ID).Return(expected, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		actual, err := service.GetUser(42)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+}
+
+func TestService_GetSessions(t *testing.T) {
+	t.Run("normal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userID := int64(42)
+
+		sessions := []*Session{
+			{ID: "first", CreatedAt: 1},
+			{ID: "second", CreatedAt: 2},
+		}
+
+		expected := []*Session{
+			{ID: "first", CreatedAt: 1},
+			{ID: "second", CreatedAt: 2, Current: true},
+		}
+
+		logger := zap.NewExample()
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetSessions(userID).Return(sessions, nil)
+		service := &service{
+			logger:   logger,
+			security: security,
+		}
+
+		actual, err := service.GetSessions(userID, "second")
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+}
+
+func TestService_RevokeSession(t *testing.T) {
+	t.Run("normal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userID := int64(42)
+
+		logger := zap.NewExample()
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().InvalidateSession(userID, "session").Return(nil)
+		service := &service{
+			logger:   logger,
+			security: security,
+		}
+
+		require.NoError(t, service.RevokeSession(userID, "session"))
+	})
+
+	t.Run("with unknown session", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userID := int64(42)
+		expected := ErrNotFound
+
+		logger := zap.NewExample()
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().InvalidateSession(userID, "session").Return(expected)
+		service := &service{
+			logger:   logger,
+			security: security,
+		}
+
+		err := service.RevokeSession(userID, "session")
+		require.Error(t, err)
+		require.Equal(t, expected, err)
+	})
+}
+
+func TestService_Logout(t *testing.T) {
+	t.Run("normal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userID := int64(42)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().InvalidateSession(userID, "session").Return(nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		require.NoError(t, service.Logout(userID, "session", clientInfo))
+	})
+
+	t.Run("with broken security", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userID := int64(42)
+		expected := ErrInternalSecurity
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().InvalidateSession(userID, "session").Return(expected)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		err := service.Logout(userID, "session", clientInfo)
+		require.Error(t, err)
+		require.Equal(t, expected, err)
+	})
+}
+
+func TestService_LogoutEverywhere(t *testing.T) {
+	t.Run("normal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userID := int64(42)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().InvalidateUserAuthData(userID).Return(nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		require.NoError(t, service.LogoutEverywhere(userID, clientInfo))
+	})
+
+	t.Run("with broken security", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userID := int64(42)
+		expected := ErrInternalSecurity
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().InvalidateUserAuthData(userID).Return(expected)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		err := service.LogoutEverywhere(userID, clientInfo)
+		require.Error(t, err)
+		require.Equal(t, expected, err)
+	})
+}
+
+func TestService_IssueToken(t *testing.T) {
+	credentials := &ClientCredentials{
+		ClientID:     "backend",
+		ClientSecret: "secret",
+	}
+
+	client := &Client{
+		ID:       1,
+		ClientID: "backend",
+		Scopes:   []string{"users:read", "users:write"},
+	}
+
+	authData := &AuthData{
+		AccessToken: "accessToken",
+		ExpiresAt:   time.Now().Add(time.Hour).Unix(),
+	}
+
+	t.Run("with all allowed scopes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(client, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().CreateClientAuthData(client, client.Scopes).Return(authData, nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		actual, err := service.IssueToken(&TokenRequest{
+			GrantType:         GrantTypeClientCredentials,
+			ClientCredentials: credentials,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "accessToken", actual.AccessToken)
+		require.Equal(t, "Bearer", actual.TokenType)
+		require.InDelta(t, int64(time.Hour/time.Second), actual.ExpiresIn, 1)
+		require.Empty(t, actual.RefreshToken)
+		require.Equal(t, "users:read users:write", actual.Scope)
+	})
+
+	t.Run("with requested scope", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(client, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().CreateClientAuthData(client, []string{"users:read"}).Return(authData, nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		actual, err := service.IssueToken(&TokenRequest{
+			GrantType:         GrantTypeClientCredentials,
+			ClientCredentials: credentials,
+			Scope:             "users:read",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "users:read", actual.Scope)
+	})
+
+	t.Run("with not allowed scope", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(client, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.IssueToken(&TokenRequest{
+			GrantType:         GrantTypeClientCredentials,
+			ClientCredentials: credentials,
+			Scope:             "users:read users:delete",
+		})
+		require.Equal(t, ErrInvalidScope, err)
+	})
+
+	t.Run("with invalid client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(nil, ErrInvalidClient)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.IssueToken(&TokenRequest{
+			GrantType:         GrantTypeClientCredentials,
+			ClientCredentials: credentials,
+		})
+		require.Equal(t, ErrInvalidClient, err)
+	})
+
+	t.Run("with unsupported grant type", func(t *testing.T) {
+		service := &service{
+			logger: zap.NewExample(),
+		}
+
+		_, err := service.IssueToken(&TokenRequest{
+			GrantType:         "password",
+			ClientCredentials: credentials,
+		})
+		require.Equal(t, ErrUnsupportedGrantType, err)
+	})
+
+	t.Run("with public client and client credentials grant", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(&Client{ClientID: "spa", Public: true}, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.IssueToken(&TokenRequest{
+			GrantType:         GrantTypeClientCredentials,
+			ClientCredentials: &ClientCredentials{ClientID: "spa"},
+		})
+		require.Equal(t, ErrUnauthorizedClient, err)
+	})
+}
+
+func TestService_IssueToken_AuthorizationCode(t *testing.T) {
+	client := &Client{
+		ID:           2,
+		ClientID:     "spa",
+		Scopes:       []string{"users:read"},
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		Public:       true,
+	}
+
+	code := &AuthorizationCode{
+		ClientID:      "spa",
+		UserID:        1,
+		RedirectURI:   "https://app.example.com/callback",
+		Scopes:        []string{"users:read"},
+		CodeChallenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+	}
+
+	user := &User{
+		ID:       1,
+		Username: "user",
+		Role:     "user",
+	}
+
+	authData := &AuthData{
+		AccessToken:  "accessToken",
+		ExpiresAt:    time.Now().Add(time.Hour).Unix(),
+		RefreshToken: "refreshToken",
+	}
+
+	newRequest := func() *TokenRequest {
+		return &TokenRequest{
+			GrantType:         GrantTypeAuthorizationCode,
+			ClientCredentials: &ClientCredentials{ClientID: "spa"},
+			Code:              "code",
+			RedirectURI:       "https://app.example.com/callback",
+			CodeVerifier:      "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk",
+		}
+	}
+
+	t.Run("with valid code verifier", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		storage.EXPECT().GetUser(int64(1)).Return(user, nil)
+		storage.EXPECT().GetPermissions("user").Return([]string{"profile:read", "users:read"}, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().ConsumeAuthorizationCode("code").Return(code, nil)
+		security.EXPECT().CreateAuthData(user, "spa", []string{"users:read"}).Return(authData, nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		actual, err := service.IssueToken(newRequest())
+		require.NoError(t, err)
+		require.Equal(t, "accessToken", actual.AccessToken)
+		require.Equal(t, "refreshToken", actual.RefreshToken)
+		require.Equal(t, "users:read", actual.Scope)
+	})
+
+	t.Run("with scope beyond user's permissions", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		storage.EXPECT().GetUser(int64(1)).Return(user, nil)
+		storage.EXPECT().GetPermissions("user").Return([]string{"profile:read"}, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().ConsumeAuthorizationCode("code").Return(code, nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.IssueToken(newRequest())
+		require.Equal(t, ErrInvalidScope, err)
+	})
+
+	t.Run("with disabled user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		storage.EXPECT().GetUser(int64(1)).Return(&User{ID: 1, Username: "user", Role: "user", Disabled: true}, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().ConsumeAuthorizationCode("code").Return(code, nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.IssueToken(newRequest())
+		require.Equal(t, ErrUserDisabled, err)
+	})
+
+	t.Run("without scopes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		unscoped := *code
+		unscoped.Scopes = nil
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		storage.EXPECT().GetUser(int64(1)).Return(user, nil)
+		storage.EXPECT().GetPermissions("user").Return([]string{"users:read"}, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().ConsumeAuthorizationCode("code").Return(&unscoped, nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.IssueToken(newRequest())
+		require.Equal(t, ErrInvalidScope, err)
+	})
+
+	t.Run("with invalid code verifier", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().ConsumeAuthorizationCode("code").Return(code, nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		request := newRequest()
+		request.CodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXK"
+
+		_, err := service.IssueToken(request)
+		require.Equal(t, ErrInvalidGrant, err)
+	})
+
+	t.Run("with another redirect uri", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().ConsumeAuthorizationCode("code").Return(code, nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		request := newRequest()
+		request.RedirectURI = "https://app.example.com/other"
+
+		_, err := service.IssueToken(request)
+		require.Equal(t, ErrInvalidGrant, err)
+	})
+
+	t.Run("with openid scope", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		openIDCode := *code
+		openIDCode.Scopes = []string{ScopeOpenID}
+		openIDCode.Nonce = "nonce"
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		storage.EXPECT().GetUser(int64(1)).Return(user, nil)
+		storage.EXPECT().GetPermissions("user").Return([]string{"profile:read"}, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().ConsumeAuthorizationCode("code").Return(&openIDCode, nil)
+		security.EXPECT().GetSigningAlgorithm().Return("RS256")
+		security.EXPECT().CreateAuthData(user, "spa", []string{ScopeOpenID}).Return(authData, nil)
+		security.EXPECT().CreateIDToken(gomock.Any()).DoAndReturn(func(claims *IDTokenClaims) (string, error) {
+			require.Equal(t, "https://goss.example.com", claims.Issuer)
+			require.Equal(t, "1", claims.Subject)
+			require.Equal(t, "spa", claims.Audience)
+			require.Equal(t, "nonce", claims.Nonce)
+			require.Equal(t, "user", claims.PreferredUsername)
+			require.Equal(t, claims.IssuedAt+int64(time.Hour/time.Second), claims.ExpiresAt)
+			return "idToken", nil
+		})
+		service := &service{
+			logger:   logger,
+			config:   config,
+			storage:  storage,
+			security: security,
+		}
+
+		actual, err := service.IssueToken(newRequest())
+		require.NoError(t, err)
+		require.Equal(t, "idToken", actual.IDToken)
+	})
+
+	t.Run("with openid scope and HS256 secret", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		openIDCode := *code
+		openIDCode.Scopes = []string{ScopeOpenID}
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		storage.EXPECT().GetUser(int64(1)).Return(user, nil)
+		storage.EXPECT().GetPermissions("user").Return([]string{"profile:read"}, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().ConsumeAuthorizationCode("code").Return(&openIDCode, nil)
+		security.EXPECT().GetSigningAlgorithm().Return("HS256")
+		service := &service{
+			logger:   logger,
+			config:   config,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.IssueToken(newRequest())
+		require.Equal(t, ErrInvalidScope, err)
+	})
+
+	t.Run("with used code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().ConsumeAuthorizationCode("code").Return(nil, ErrInvalidGrant)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.IssueToken(newRequest())
+		require.Equal(t, ErrInvalidGrant, err)
+	})
+
+	t.Run("with confidential client without secret", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(&Client{ClientID: "spa"}, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.IssueToken(newRequest())
+		require.Equal(t, ErrInvalidClient, err)
+	})
+}
+
+func TestService_Authorize(t *testing.T) {
+	client := &Client{
+		ID:           2,
+		ClientID:     "spa",
+		Scopes:       []string{"users:read"},
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		Public:       true,
+	}
+
+	credentials := &Credentials{
+		Username: "user",
+		Password: "password",
+	}
+
+	user := &User{
+		ID:       1,
+		Username: "user",
+		Role:     "user",
+	}
+
+	newRequest := func() *AuthorizationRequest {
+		return &AuthorizationRequest{
+			ResponseType:        ResponseTypeCode,
+			ClientID:            "spa",
+			RedirectURI:         "https://app.example.com/callback",
+			State:               "state",
+			CodeChallenge:       "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+			CodeChallengeMethod: CodeChallengeMethodS256,
+		}
+	}
+
+	t.Run("with valid request", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		storage.EXPECT().GetUserByCredentials(credentials).Return(user, nil)
+		storage.EXPECT().GetTOTP(user.ID).Return(nil, ErrNotFound)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		security.EXPECT().ResetLoginAttempts("user").Return(nil)
+		security.EXPECT().CreateAuthorizationCode(&AuthorizationCode{
+			ClientID:      "spa",
+			UserID:        1,
+			RedirectURI:   "https://app.example.com/callback",
+			Scopes:        []string{"users:read"},
+			CodeChallenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		}).Return("code", nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		actual, err := service.Authorize(newRequest(), credentials, clientInfo)
+		require.NoError(t, err)
+		require.Equal(t, "code", actual)
+	})
+
+	t.Run("with openid scope and HS256 secret", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		openIDClient := *client
+		openIDClient.Scopes = []string{"users:read", ScopeOpenID}
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(&openIDClient, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetSigningAlgorithm().Return("HS256")
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		request := newRequest()
+		request.Scope = "openid users:read"
+
+		_, err := service.Authorize(request, credentials, clientInfo)
+		require.Equal(t, ErrInvalidScope, err)
+	})
+
+	t.Run("with all client scopes and HS256 secret", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		openIDClient := *client
+		openIDClient.Scopes = []string{"users:read", ScopeOpenID}
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetClient("spa").Return(&openIDClient, nil)
+		storage.EXPECT().GetUserByCredentials(credentials).Return(user, nil)
+		storage.EXPECT().GetTOTP(user.ID).Return(nil, ErrNotFound)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetSigningAlgorithm().Return("HS256")
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		security.EXPECT().ResetLoginAttempts("user").Return(nil)
+		security.EXPECT().CreateAuthorizationCode(&AuthorizationCode{
+			ClientID:      "spa",
+			UserID:        1,
+			RedirectURI:   "https://app.example.com/callback",
+			Scopes:        []string{"users:read"},
+			CodeChallenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		}).Return("code", nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		actual, err := service.Authorize(newRequest(), credentials, clientInfo)
+		require.NoError(t, err)
+		require.Equal(t, "code", actual)
+		require.Equal(t, []string{"users:read", ScopeOpenID}, openIDClient.Scopes)
+	})
+
+	t.Run("with client without scopes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		unscoped := *client
+		unscoped.Scopes = nil
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(&unscoped, nil)
+		security := NewMockSecurity(ctrl)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.Authorize(newRequest(), credentials, clientInfo)
+		require.Equal(t, ErrInvalidScope, err)
+	})
+
+	t.Run("with not registered redirect uri", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		request := newRequest()
+		request.RedirectURI = "https://evil.example.com/callback"
+
+		_, err := service.Authorize(request, credentials, clientInfo)
+		require.Equal(t, ErrInvalidRedirectURI, err)
+	})
+
+	t.Run("without code challenge", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		request := newRequest()
+		request.CodeChallenge = ""
+
+		_, err := service.Authorize(request, credentials, clientInfo)
+		require.Equal(t, ErrInvalidRequest, err)
+	})
+
+	t.Run("with plain code challenge method", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		request := newRequest()
+		request.CodeChallengeMethod = "plain"
+
+		_, err := service.Authorize(request, credentials, clientInfo)
+		require.Equal(t, ErrInvalidRequest, err)
+	})
+
+	t.Run("with two-factor authentication", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		storage.EXPECT().GetUserByCredentials(credentials).Return(user, nil)
+		storage.EXPECT().GetTOTP(user.ID).Return(&TOTP{Secret: "secret", Confirmed: true}, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.Authorize(newRequest(), credentials, clientInfo)
+		require.Equal(t, ErrMFARequired, err)
+	})
+
+	t.Run("with invalid credentials", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		storage.EXPECT().GetUserByCredentials(credentials).Return(nil, ErrInvalidCredentials)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().CheckLoginAttempts("user", clientInfo.IP).Return(nil)
+		security.EXPECT().AddFailedLoginAttempt("user", clientInfo.IP).Return(nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.Authorize(newRequest(), credentials, clientInfo)
+		require.Equal(t, ErrInvalidCredentials, err)
+	})
+}
+
+func TestService_ResolveRedirectURI(t *testing.T) {
+	client := &Client{
+		ID:           2,
+		ClientID:     "spa",
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		Public:       true,
+	}
+
+	t.Run("with registered redirect uri", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		service := &service{
+			logger:  zap.NewExample(),
+			storage: storage,
+		}
+
+		actual, err := service.ResolveRedirectURI("spa", "https://app.example.com/callback")
+		require.NoError(t, err)
+		require.Equal(t, "https://app.example.com/callback", actual)
+	})
+
+	t.Run("without redirect uri", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(client, nil)
+		service := &service{
+			logger:  zap.NewExample(),
+			storage: storage,
+		}
+
+		actual, err := service.ResolveRedirectURI("spa", "")
+		require.NoError(t, err)
+		require.Equal(t, "https://app.example.com/callback", actual)
+	})
+
+	t.Run("with unknown client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("unknown").Return(nil, ErrNotFound)
+		service := &service{
+			logger:  zap.NewExample(),
+			storage: storage,
+		}
+
+		_, err := service.ResolveRedirectURI("unknown", "https://app.example.com/callback")
+		require.Equal(t, ErrInvalidClient, err)
+	})
+}
+
+func TestService_GetUserInfo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	user := &User{
+		ID:       42,
+		Username: "user",
+		Role:     "user",
+	}
+
+	expected := &UserInfo{
+		Subject:           "42",
+		PreferredUsername: "user",
+	}
+
+	logger := zap.NewExample()
+	storage := NewMockStorage(ctrl)
+	storage.EXPECT().GetUser(int64(42)).Return(user, nil)
+	service := &service{
+		logger:  logger,
+		storage: storage,
+	}
+
+	actual, err := service.GetUserInfo(42)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
+func TestService_GetOpenIDConfiguration(t *testing.T) {
+	t.Run("with asymmetric key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetSigningAlgorithm().Return("RS256")
+		service := &service{
+			logger:   zap.NewExample(),
+			config:   config,
+			security: security,
+		}
+
+		actual := service.GetOpenIDConfiguration()
+		require.Equal(t, "https://goss.example.com", actual.Issuer)
+		require.Equal(t, "https://goss.example.com/v1/oauth/authorize", actual.AuthorizationEndpoint)
+		require.Equal(t, "https://goss.example.com/v1/oauth/token", actual.TokenEndpoint)
+		require.Equal(t, "https://goss.example.com/v1/oauth/introspect", actual.IntrospectionEndpoint)
+		require.Equal(t, "https://goss.example.com/v1/oauth/revoke", actual.RevocationEndpoint)
+		require.Equal(t, "https://goss.example.com/v1/oidc/userinfo", actual.UserInfoEndpoint)
+		require.Equal(t, "https://goss.example.com/.well-known/jwks.json", actual.JWKSURI)
+		require.Equal(t, []string{ScopeOpenID}, actual.ScopesSupported)
+		require.Equal(t, []string{"RS256"}, actual.IDTokenSigningAlgValuesSupported)
+	})
+
+	t.Run("with HS256 secret", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetSigningAlgorithm().Return("HS256")
+		service := &service{
+			logger:   zap.NewExample(),
+			config:   config,
+			security: security,
+		}
+
+		actual := service.GetOpenIDConfiguration()
+		require.Empty(t, actual.ScopesSupported)
+		require.Empty(t, actual.IDTokenSigningAlgValuesSupported)
+	})
+}
+
+func TestService_GetClaims(t *testing.T) {
+	t.Run("with valid token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userID := int64(42)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().InvalidateUserAuthData(userID).Return(nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		require.NoError(t, service.LogoutEverywhere(userID, clientInfo))
+	})
+
+	t.Run("with invalid token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userID := int64(42)
+		expected := ErrInvalidAccessToken
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().InvalidateUserAuthData(userID).Return(expected)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		err := service.LogoutEverywhere(userID, clientInfo)
+		require.Error(t, err)
+		require.Equal(t, expected, err)
+	})
+
+	t.Run("with broken security", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userID := int64(42)
+		expected := ErrInternalSecurity
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().InvalidateUserAuthData(userID).Return(expected)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		err := service.LogoutEverywhere(userID, clientInfo)
+		require.Error(t, err)
+		require.Equal(t, expected, err)
+	})
+}
+
+func TestService_GetRecoveryCodes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	logger := zap.NewExample()
+	storage := NewMockStorage(ctrl)
+	storage.EXPECT().CountRecoveryCodes(int64(1)).Return(3, nil)
+	service := &service{
+		logger:  logger,
+		storage: storage,
+	}
+
+	actual, err := service.GetRecoveryCodes(1)
+	require.NoError(t, err)
+	require.Equal(t, &RecoveryCodes{Remaining: 3}, actual)
+}
+
+func TestService_RegenerateRecoveryCodes(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	t.Run("with valid code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		step := time.Now().Unix() / totpPeriod
+		key, _ := totpEncoding.DecodeString(secret)
+
+		var hashes []string
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetTOTP(int64(1)).Return(&TOTP{Secret: secret, Confirmed: true}, nil)
+		storage.EXPECT().UseTOTPStep(int64(1), step).Return(nil)
+		storage.EXPECT().ReplaceRecoveryCodes(int64(1), gomock.Any()).DoAndReturn(func(_ int64, h []string) error {
+			hashes = h
+			return nil
+		})
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		actual, err := service.RegenerateRecoveryCodes(1, totpCode(key, step))
+		require.NoError(t, err)
+		require.Len(t, actual.Codes, recoveryCodesCount)
+		require.Equal(t, recoveryCodesCount, actual.Remaining)
+		for i, code := range actual.Codes {
+			require.Regexp(t, "^[a-z2-9]{5}-[a-z2-9]{5}$", code)
+			require.Equal(t, hashRecoveryCode(code), hashes[i])
+		}
+	})
+
+	t.Run("with invalid code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetTOTP(int64(1)).Return(&TOTP{Secret: secret, Confirmed: true}, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.RegenerateRecoveryCodes(1, "abcdef")
+		require.Equal(t, ErrInvalidMFACode, err)
+	})
+}
+
+func TestService_GetPermissions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	logger := zap.NewExample()
+	storage := NewMockStorage(ctrl)
+	storage.EXPECT().GetPermissions("admin").Return([]string{"profile:read", "users:read"}, nil)
+	service := &service{
+		logger:  logger,
+		storage: storage,
+	}
+
+	actual, err := service.GetPermissions("admin")
+	require.NoError(t, err)
+	require.Equal(t, &RolePermissions{Role: "admin", Permissions: []string{"profile:read", "users:read"}}, actual)
+}
+
+func TestService_CheckPermission(t *testing.T) {
+	t.Run("with permission", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetPermissions("admin").Return([]string{"profile:read", "users:read"}, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		require.NoError(t, service.CheckPermission("admin", "users:read"))
+	})
+
+	t.Run("without permission", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetPermissions("user").Return([]string{"profile:read"}, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		require.Equal(t, ErrPermissionDenied, service.CheckPermission("user", "users:read"))
+	})
+
+	t.Run("with broken storage", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetPermissions("user").Return(nil, ErrInternalStorage)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		require.Equal(t, ErrInternalStorage, service.CheckPermission("user", "users:read"))
+	})
+}
+
+func TestService_CreateUser(t *testing.T) {
+	newUser := &NewUser{
+		Username: "alice",
+		Password: "password",
+		Role:     "admin",
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		expected := &User{
+			ID:       5,
+			Username: "alice",
+			Role:     "admin",
+		}
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().RoleExists("admin").Return(true, nil)
+		storage.EXPECT().CreateUser(&Credentials{Username: "alice", Password: "password"}, "admin").Return(expected, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		actual, err := service.CreateUser(newUser)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("with unknown role", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().RoleExists("admin").Return(false, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.CreateUser(newUser)
+		require.Equal(t, ErrInvalidRole, err)
+	})
+}
+
+func TestService_UpdateUser(t *testing.T) {
+	newUser := func() *User {
+		return &User{
+			ID:       2,
+			Username: "user1",
+			Role:     "admin",
+		}
+	}
+
+	t.Run("with new username", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		username := "alice"
+		expected := &User{
+			ID:       2,
+			Username: "alice",
+			Role:     "admin",
+		}
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetUser(int64(2)).Return(newUser(), nil)
+		storage.EXPECT().UpdateUser(expected).Return(expected, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		actual, err := service.UpdateUser(2, &UserUpdate{Username: &username})
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("with demotion", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		role := "user"
+		expected := &User{
+			ID:       2,
+			Username: "user1",
+			Role:     "user",
+		}
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetUser(int64(2)).Return(newUser(), nil)
+		storage.EXPECT().RoleExists("user").Return(true, nil)
+		storage.EXPECT().UpdateUser(expected).Return(expected, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().InvalidateUserAuthData(int64(2)).Return(nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		actual, err := service.UpdateUser(2, &UserUpdate{Role: &role})
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("with invalid username", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		username := "-"
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetUser(int64(2)).Return(newUser(), nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.UpdateUser(2, &UserUpdate{Username: &username})
+		require.Equal(t, ErrInvalidUsername, err)
+	})
+}
+
+func TestService_SetUserDisabled(t *testing.T) {
+	t.Run("disable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().SetUserDisabled(int64(2), true).Return(nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().InvalidateUserAuthData(int64(2)).Return(nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		require.NoError(t, service.SetUserDisabled(2, true))
+	})
+
+	t.Run("enable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().SetUserDisabled(int64(2), false).Return(nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		require.NoError(t, service.SetUserDisabled(2, false))
+	})
+
+	t.Run("with unknown user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().SetUserDisabled(int64(2), true).Return(ErrNotFound)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		require.Equal(t, ErrNotFound, service.SetUserDisabled(2, true))
+	})
+}
+
+func TestService_ResetPassword(t *testing.T) {
+	t.Run("normal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().UpdateUserPassword(int64(2), "new password").Return(nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().InvalidateUserAuthData(int64(2)).Return(nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		require.NoError(t, service.ResetPassword(2, "new password"))
+	})
+
+	t.Run("with short password", func(t *testing.T) {
+		service := &service{
+			logger: zap.NewExample(),
+		}
+
+		require.Equal(t, ErrInvalidPassword, service.ResetPassword(2, "short"))
+	})
+}
+
+func TestService_DeleteUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	logger := zap.NewExample()
+	storage := NewMockStorage(ctrl)
+	storage.EXPECT().DeleteUser(int64(2)).Return(nil)
+	security := NewMockSecurity(ctrl)
+	security.EXPECT().InvalidateUserAuthData(int64(2)).Return(nil)
+	service := &service{
+		logger:   logger,
+		storage:  storage,
+		security: security,
+	}
+
+	require.NoError(t, service.DeleteUser(2))
+}
+
+func TestService_ListUsers(t *testing.T) {
+	logger := zap.NewExample()
+
+	users := []*User{
+		{ID: 1, Username: "alice", Role: "user"},
+		{ID: 2, Username: "bob", Role: "user"},
+	}
+
+	t.Run("first page", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().
+			ListUsers(&UserFilter{Sort: SortByUsername, Limit: 2}, nil).
+			Return(users, true, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		page, err := service.ListUsers(&UserFilter{Sort: SortByUsername, Limit: 2})
+		require.NoError(t, err)
+		require.Equal(t, users, page.Users)
+
+		cursor, err := decodeUserCursor(page.NextCursor)
+		require.NoError(t, err)
+		require.Equal(t, &UserCursor{ID: 2, Username: "bob"}, cursor)
+	})
+
+	t.Run("next page", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().
+			ListUsers(&UserFilter{Sort: SortByID, Cursor: encodeUserCursor(users[0], SortByID), Limit: DefaultUserPageSize}, &UserCursor{ID: 1}).
+			Return(users[1:], false, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		page, err := service.ListUsers(&UserFilter{Cursor: encodeUserCursor(users[0], SortByID)})
+		require.NoError(t, err)
+		require.Equal(t, &UserPage{Users: users[1:]}, page)
+	})
+
+	t.Run("limit is capped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().
+			ListUsers(&UserFilter{Sort: SortByID, Limit: MaxUserPageSize}, nil).
+			Return(nil, false, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.ListUsers(&UserFilter{Limit: 1000})
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid sort", func(t *testing.T) {
+		service := &service{
+			logger: logger,
+		}
+
+		_, err := service.ListUsers(&UserFilter{Sort: "password"})
+		require.Equal(t, ErrInvalidRequest, err)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		service := &service{
+			logger: logger,
+		}
+
+		_, err := service.ListUsers(&UserFilter{Cursor: "not a cursor"})
+		require.Equal(t, ErrInvalidRequest, err)
+	})
+}
+
+func TestService_audit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	logger := zap.NewExample()
+	storage := NewMockStorage(ctrl)
+	storage.EXPECT().AddAuditEvent(gomock.Any()).Do(func(event *AuditEvent) {
+		require.False(t, event.Time.IsZero())
+		event.Time = time.Time{}
+
+		require.Equal(t, &AuditEvent{
+			Username:  "alice",
+			Action:    AuditActionLogin,
+			Outcome:   AuditOutcomeFailure,
+			Reason:    ErrInvalidCredentials.Error(),
+			IP:        "192.0.2.1",
+			UserAgent: "test",
+			RequestID: "request",
+		}, event)
+	}).Return(ErrInternalStorage)
+	service := &service{
+		logger:  logger,
+		storage: storage,
+	}
+
+	service.audit(AuditActionLogin, clientInfo, 0, "alice", ErrInvalidCredentials)
+}
+
+func TestService_GetAccessTokenClaims(t *testing.T) {
+	t.Run("with valid token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		expected := &AccessTokenClaims{UserID: 42}
+
+		logger := zap.NewExample()
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetAccessTokenClaims("accessToken").Return(expected, nil)
+		service := &service{
+			logger:   logger,
+			security: security,
+		}
+
+		actual, err := service.GetAccessTokenClaims("accessToken", clientInfo)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("with invalid token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Do(func(event *AuditEvent) {
+			require.Equal(t, AuditActionTokenValidation, event.Action)
+			require.Equal(t, AuditOutcomeFailure, event.Outcome)
+		}).Return(nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetAccessTokenClaims("accessToken").Return(nil, ErrInvalidAccessToken)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.GetAccessTokenClaims("accessToken", clientInfo)
+		require.Equal(t, ErrInvalidAccessToken, err)
+	})
+
+	t.Run("without token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetAccessTokenClaims("").Return(nil, ErrInvalidAccessToken)
+		service := &service{
+			logger:   logger,
+			security: security,
+		}
+
+		_, err := service.GetAccessTokenClaims("", clientInfo)
+		require.Equal(t, ErrInvalidAccessToken, err)
+	})
+}
+
+func TestService_GetAPIKeyClaims(t *testing.T) {
+	apiKey := APIKeyPrefix + "key"
+	expiresAt := time.Now().Add(time.Hour)
+
+	t.Run("with valid key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().UseAPIKey(hashAPIKey(apiKey)).Return(&APIKey{
+			ID:        3,
+			UserID:    42,
+			Scopes:    []string{"users:read", "users:write"},
+			ExpiresAt: &expiresAt,
+		}, nil)
+		storage.EXPECT().GetUser(int64(42)).Return(&User{ID: 42, Role: "admin"}, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		actual, err := service.GetAPIKeyClaims(apiKey, clientInfo)
+		require.NoError(t, err)
+		require.Equal(t, &AccessTokenClaims{
+			UserID:         42,
+			Role:           "admin",
+			Scope:          "users:read users:write",
+			APIKeyID:       3,
+			StandardClaims: jwt.StandardClaims{ExpiresAt: expiresAt.Unix()},
+		}, actual)
+	})
+
+	t.Run("with unknown key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().UseAPIKey(hashAPIKey(apiKey)).Return(nil, ErrNotFound)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Do(func(event *AuditEvent) {
+			require.Equal(t, AuditActionTokenValidation, event.Action)
+			require.Equal(t, AuditOutcomeFailure, event.Outcome)
+		}).Return(nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.GetAPIKeyClaims(apiKey, clientInfo)
+		require.Equal(t, ErrInvalidAccessToken, err)
+	})
+
+	t.Run("of disabled user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().UseAPIKey(hashAPIKey(apiKey)).Return(&APIKey{ID: 3, UserID: 42}, nil)
+		storage.EXPECT().GetUser(int64(42)).Return(&User{ID: 42, Role: "admin", Disabled: true}, nil)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.GetAPIKeyClaims(apiKey, clientInfo)
+		require.Equal(t, ErrInvalidAccessToken, err)
+	})
+
+	t.Run("without scopes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().UseAPIKey(hashAPIKey(apiKey)).Return(&APIKey{ID: 3, UserID: 42, Scopes: []string{}}, nil)
+		storage.EXPECT().GetUser(int64(42)).Return(&User{ID: 42, Role: "admin"}, nil)
+		storage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.GetAPIKeyClaims(apiKey, clientInfo)
+		require.Equal(t, ErrInvalidAccessToken, err)
+	})
+}
+
+func TestService_CreateAPIKey(t *testing.T) {
+	userID := int64(42)
+	user := &User{ID: userID, Username: "alice", Role: "admin"}
+	permissions := []string{"users:read", "users:write"}
+
+	t.Run("normal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetUser(userID).Return(user, nil)
+		storage.EXPECT().GetPermissions("admin").Return(permissions, nil)
+		storage.EXPECT().CreateAPIKey(&APIKey{
+			UserID: userID,
+			Name:   "ci",
+			Scopes: []string{"users:read"},
+		}, gomock.Any()).Return(&APIKey{ID: 3, UserID: userID, Name: "ci", Scopes: []string{"users:read"}}, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		actual, err := service.CreateAPIKey(userID, &APIKeyRequest{Name: " ci ", Scopes: []string{"users:read"}})
+		require.NoError(t, err)
+		require.Equal(t, int64(3), actual.ID)
+		require.Equal(t, []string{"users:read"}, actual.Scopes)
+		require.True(t, strings.HasPrefix(actual.Key, APIKeyPrefix))
+	})
+
+	t.Run("without scopes", func(t *testing.T) {
+		service := &service{
+			logger: zap.NewExample(),
+		}
+
+		_, err := service.CreateAPIKey(userID, &APIKeyRequest{Name: "ci"})
+		require.Equal(t, ErrInvalidScope, err)
+
+		_, err = service.CreateAPIKey(userID, &APIKeyRequest{Name: "ci", Scopes: []string{}})
+		require.Equal(t, ErrInvalidScope, err)
+	})
+
+	t.Run("with scope exceeding permissions", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetUser(userID).Return(user, nil)
+		storage.EXPECT().GetPermissions("admin").Return(permissions, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.CreateAPIKey(userID, &APIKeyRequest{Name: "ci", Scopes: []string{"audit:read"}})
+		require.Equal(t, ErrInvalidScope, err)
+	})
+
+	t.Run("with past expiration", func(t *testing.T) {
+		expiresAt := time.Now().Add(-time.Hour)
+
+		service := &service{
+			logger: zap.NewExample(),
+		}
+
+		_, err := service.CreateAPIKey(userID, &APIKeyRequest{Name: "ci", ExpiresAt: &expiresAt})
+		require.Equal(t, ErrInvalidRequest, err)
+	})
+
+	t.Run("without name", func(t *testing.T) {
+		service := &service{
+			logger: zap.NewExample(),
+		}
+
+		_, err := service.CreateAPIKey(userID, &APIKeyRequest{Name: " "})
+		require.Equal(t, ErrInvalidRequest, err)
+	})
+}
+
+func TestService_DeleteAPIKey(t *testing.T) {
+	t.Run("normal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().DeleteAPIKey(int64(42), int64(3)).Return(nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		require.NoError(t, service.DeleteAPIKey(42, 3))
+	})
+
+	t.Run("with unknown key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().DeleteAPIKey(int64(42), int64(3)).Return(ErrNotFound)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		require.Equal(t, ErrNotFound, service.DeleteAPIKey(42, 3))
+	})
+}
+
+func TestService_ListAuditEvents(t *testing.T) {
+	logger := zap.NewExample()
+
+	now := time.Now()
+	events := []*AuditEvent{
+		{ID: 3, Action: AuditActionLogin, Outcome: AuditOutcomeSuccess},
+		{ID: 2, Action: AuditActionLogout, Outcome: AuditOutcomeSuccess},
+	}
+
+	t.Run("first page", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().
+			ListAuditEvents(&AuditFilter{From: now.Add(-time.Hour), To: now, Limit: 2}, nil).
+			Return(events, true, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		page, err := service.ListAuditEvents(&AuditFilter{From: now.Add(-time.Hour), To: now, Limit: 2})
+		require.NoError(t, err)
+		require.Equal(t, events, page.Events)
+
+		cursor, err := decodeAuditCursor(page.NextCursor)
+		require.NoError(t, err)
+		require.Equal(t, &AuditCursor{ID: 2}, cursor)
+	})
+
+	t.Run("next page", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().
+			ListAuditEvents(&AuditFilter{Cursor: encodeAuditCursor(events[0]), Limit: MaxAuditPageSize}, &AuditCursor{ID: 3}).
+			Return(events[1:], false, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		page, err := service.ListAuditEvents(&AuditFilter{Cursor: encodeAuditCursor(events[0]), Limit: 5000})
+		require.NoError(t, err)
+		require.Equal(t, &AuditPage{Events: events[1:]}, page)
+	})
+
+	t.Run("invalid time range", func(t *testing.T) {
+		service := &service{
+			logger: logger,
+		}
+
+		_, err := service.ListAuditEvents(&AuditFilter{From: now, To: now.Add(-time.Hour)})
+		require.Equal(t, ErrInvalidRequest, err)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		service := &service{
+			logger: logger,
+		}
+
+		_, err := service.ListAuditEvents(&AuditFilter{Cursor: "not a cursor"})
+		require.Equal(t, ErrInvalidRequest, err)
+	})
+}
+
+func TestService_PurgeAuditEvents(t *testing.T) {
+	logger := zap.NewExample()
+
+	t.Run("with retention", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().DeleteAuditEvents(gomock.Any()).Do(func(before time.Time) {
+			require.WithinDuration(t, time.Now().Add(-24*time.Hour), before, time.Minute)
+		}).Return(int64(10), nil)
+		service := &service{
+			logger:  logger,
+			config:  &Config{AuditRetention: 24 * time.Hour},
+			storage: storage,
+		}
+
+		require.NoError(t, service.PurgeAuditEvents())
+	})
+
+	t.Run("without retention", func(t *testing.T) {
+		service := &service{
+			logger: logger,
+			config: &Config{},
+		}
+
+		require.NoError(t, service.PurgeAuditEvents())
+	})
+}
+
+func TestService_AuthenticateConfidentialClient(t *testing.T) {
+	t.Run("with confidential client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		credentials := &ClientCredentials{
+			ClientID:     "backend",
+			ClientSecret: "secret",
+		}
+		client := &Client{ID: 1, ClientID: "backend"}
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(client, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		actual, err := service.AuthenticateConfidentialClient(credentials)
+		require.NoError(t, err)
+		require.Equal(t, client, actual)
+	})
+
+	t.Run("with public client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(&Client{ID: 2, ClientID: "spa", Public: true}, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.AuthenticateConfidentialClient(&ClientCredentials{ClientID: "spa"})
+		require.Equal(t, ErrUnauthorizedClient, err)
+	})
+}
+
+func TestService_IntrospectToken(t *testing.T) {
+	credentials := &ClientCredentials{
+		ClientID:     "backend",
+		ClientSecret: "secret",
+	}
+
+	client := &Client{
+		ID:       1,
+		ClientID: "backend",
+		Scopes:   []string{"users:read"},
+	}
+
+	expiresAt := time.Now().Add(time.Hour).Unix()
+
+	newRequest := func() *IntrospectionRequest {
+		return &IntrospectionRequest{
+			ClientCredentials: credentials,
+			Token:             "accessToken",
+		}
+	}
+
+	t.Run("with user token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(client, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetAccessTokenClaims("accessToken").Return(&AccessTokenClaims{
+			UserID:         42,
+			Role:           "admin",
+			SessionID:      "session",
+			StandardClaims: jwt.StandardClaims{ExpiresAt: expiresAt},
+		}, nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		actual, err := service.IntrospectToken(newRequest())
+		require.NoError(t, err)
+		require.Equal(t, &Introspection{
+			Active: true,
+			Sub:    "42",
+			Exp:    expiresAt,
+			Role:   "admin",
+		}, actual)
+	})
+
+	t.Run("with client token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(client, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetAccessTokenClaims("accessToken").Return(&AccessTokenClaims{
+			ClientID:       "backend",
+			Scope:          "users:read",
+			StandardClaims: jwt.StandardClaims{Subject: "backend", ExpiresAt: expiresAt},
+		}, nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		actual, err := service.IntrospectToken(newRequest())
+		require.NoError(t, err)
+		require.Equal(t, &Introspection{
+			Active: true,
+			Sub:    "backend",
+			Exp:    expiresAt,
+			Scope:  "users:read",
+		}, actual)
+	})
+
+	t.Run("with logged out token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(client, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetAccessTokenClaims("accessToken").Return(nil, ErrInvalidAccessToken)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		actual, err := service.IntrospectToken(newRequest())
+		require.NoError(t, err)
+		require.Equal(t, &Introspection{Active: false}, actual)
+	})
+
+	t.Run("with broken security", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(client, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().GetAccessTokenClaims("accessToken").Return(nil, ErrInternalSecurity)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		_, err := service.IntrospectToken(newRequest())
+		require.Equal(t, ErrInternalSecurity, err)
+	})
+
+	t.Run("by public client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClient("spa").Return(&Client{ID: 2, ClientID: "spa", Public: true}, nil)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.IntrospectToken(&IntrospectionRequest{
+			ClientCredentials: &ClientCredentials{ClientID: "spa"},
+			Token:             "accessToken",
+		})
+		require.Equal(t, ErrUnauthorizedClient, err)
+	})
+
+	t.Run("with invalid client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(nil, ErrInvalidClient)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		_, err := service.IntrospectToken(newRequest())
+		require.Equal(t, ErrInvalidClient, err)
+	})
+}
+
+func TestService_RevokeToken(t *testing.T) {
+	credentials := &ClientCredentials{
+		ClientID:     "backend",
+		ClientSecret: "secret",
+	}
+
+	client := &Client{
+		ID:       1,
+		ClientID: "backend",
+		Scopes:   []string{"users:read"},
+	}
+
+	newRequest := func() *RevocationRequest {
+		return &RevocationRequest{
+			ClientCredentials: credentials,
+			Token:             "refreshToken",
+		}
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		info := &TokenInfo{
+			ID:        "tokenID",
+			Type:      TokenTypeRefreshToken,
+			UserID:    42,
+			ClientID:  "backend",
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		}
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(client, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().IdentifyToken("refreshToken").Return(info, nil)
+		security.EXPECT().RevokeToken(info).Return(nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		err := service.RevokeToken(newRequest())
+		require.NoError(t, err)
+	})
+
+	t.Run("with unknown token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(client, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().IdentifyToken("refreshToken").Return(nil, ErrNotFound)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		err := service.RevokeToken(newRequest())
+		require.NoError(t, err)
+	})
+
+	t.Run("with token of another client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(client, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().IdentifyToken("refreshToken").Return(&TokenInfo{
+			ID:        "tokenID",
+			Type:      TokenTypeAccessToken,
+			ClientID:  "reporting",
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		}, nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		err := service.RevokeToken(newRequest())
+		require.Equal(t, ErrUnauthorizedClient, err)
+	})
+
+	t.Run("with user token of another client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(client, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().IdentifyToken("refreshToken").Return(&TokenInfo{
+			ID:        "tokenID",
+			Type:      TokenTypeRefreshToken,
+			UserID:    42,
+			ClientID:  "spa",
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		}, nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		err := service.RevokeToken(newRequest())
+		require.Equal(t, ErrUnauthorizedClient, err)
+	})
+
+	t.Run("with user token of direct login", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(client, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().IdentifyToken("refreshToken").Return(&TokenInfo{
+			ID:        "tokenID",
+			Type:      TokenTypeRefreshToken,
+			UserID:    42,
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		}, nil)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		err := service.RevokeToken(newRequest())
+		require.Equal(t, ErrUnauthorizedClient, err)
+	})
+
+	t.Run("with broken security", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(client, nil)
+		security := NewMockSecurity(ctrl)
+		security.EXPECT().IdentifyToken("refreshToken").Return(nil, ErrInternalSecurity)
+		service := &service{
+			logger:   logger,
+			storage:  storage,
+			security: security,
+		}
+
+		err := service.RevokeToken(newRequest())
+		require.Equal(t, ErrInternalSecurity, err)
+	})
+
+	t.Run("with invalid client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		logger := zap.NewExample()
+		storage := NewMockStorage(ctrl)
+		storage.EXPECT().GetClientByCredentials(credentials).Return(nil, ErrInvalidClient)
+		service := &service{
+			logger:  logger,
+			storage: storage,
+		}
+
+		err := service.RevokeToken(newRequest())
+		require.Equal(t, ErrInvalidClient, err)
+	})
 }
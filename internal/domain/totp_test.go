@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+//RFC 6238 test vectors for the SHA1 mode truncated to 6 digits.
+func TestVerifyTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	t.Run("test vectors", func(t *testing.T) {
+		for unix, code := range map[int64]string{
+			59:         "287082",
+			1111111109: "081804",
+			1111111111: "050471",
+			1234567890: "005924",
+			2000000000: "279037",
+		} {
+			step, err := verifyTOTP(secret, code, 0, time.Unix(unix, 0))
+			require.NoError(t, err)
+			require.Equal(t, unix/totpPeriod, step)
+		}
+	})
+
+	t.Run("clock skew", func(t *testing.T) {
+		step, err := verifyTOTP(secret, "287082", 0, time.Unix(59+totpPeriod, 0))
+		require.NoError(t, err)
+		require.Equal(t, int64(1), step)
+
+		_, err = verifyTOTP(secret, "287082", 0, time.Unix(59+2*totpPeriod, 0))
+		require.Equal(t, ErrInvalidMFACode, err)
+	})
+
+	t.Run("used step", func(t *testing.T) {
+		_, err := verifyTOTP(secret, "287082", 1, time.Unix(59, 0))
+		require.Equal(t, ErrInvalidMFACode, err)
+	})
+
+	t.Run("invalid code", func(t *testing.T) {
+		_, err := verifyTOTP(secret, "000000", 0, time.Unix(59, 0))
+		require.Equal(t, ErrInvalidMFACode, err)
+	})
+}
@@ -1,6 +1,10 @@
 package domain
 
-import "github.com/dgrijalva/jwt-go"
+import (
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
 
 //Health contains an application health status.
 type Health struct {
@@ -10,34 +14,298 @@ type Health struct {
 }
 
 //Credentials contains user credentials.
+//The optional scope limits access tokens of the login to some of the user's permissions, registration ignores it.
 type Credentials struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Scope    string `json:"scope,omitempty"`
 }
 
 //AuthData contains auth data.
+//A user with two-factor authentication enabled gets only the MFA token after the password step.
 type AuthData struct {
-	AccessToken  string `json:"accessToken"`
-	ExpiresAt    int64  `json:"expiresAt"`
-	RefreshToken string `json:"refreshToken"`
+	AccessToken  string `json:"accessToken,omitempty"`
+	ExpiresAt    int64  `json:"expiresAt,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	MFAToken     string `json:"mfaToken,omitempty"`
+
+	RecoveryCodesRemaining *int `json:"recoveryCodesRemaining,omitempty"`
+}
+
+//ClientInfo describes the client the request came from.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+//MFACredentials contains the second login step credentials.
+//A recovery code can be used in place of the one-time code.
+//The optional scope limits access tokens the same way as on the password step.
+type MFACredentials struct {
+	MFAToken     string `json:"mfaToken"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+//RecoveryCodes contains newly generated recovery codes and the number of unused ones.
+type RecoveryCodes struct {
+	Codes     []string `json:"codes,omitempty"`
+	Remaining int      `json:"remaining"`
+}
+
+//MFACode contains a one-time code from the user's authenticator.
+type MFACode struct {
+	Code string `json:"code"`
+}
+
+//TOTP contains user's TOTP authenticator.
+type TOTP struct {
+	Secret    string
+	Confirmed bool
+	LastStep  int64
+}
+
+//TOTPEnrollment contains a TOTP secret to be added to the user's authenticator app.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
 }
 
 //AccessTokenClaims contains access token claims.
+//Type tells access, refresh and ID tokens apart, as they are signed with the same keys.
 type AccessTokenClaims struct {
-	UserID int64  `json:"userID"`
-	Role   string `json:"role"`
+	Type      string `json:"typ,omitempty"`
+	UserID    int64  `json:"userID,omitempty"`
+	Role      string `json:"role,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+	ClientID  string `json:"clientID,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	APIKeyID  int64  `json:"apiKeyID,omitempty"`
 	jwt.StandardClaims
 }
 
+//HasScope returns true if the scope or all scopes are granted, claims without a scope are denied.
+func (c *AccessTokenClaims) HasScope(scope string) bool {
+	scopes := parseScope(c.Scope)
+	return hasScope(scopes, ScopeAll) || hasScope(scopes, scope)
+}
+
 //RefreshTokenClaims contains refresh token claims.
 type RefreshTokenClaims struct {
-	UserID int64 `json:"userID"`
+	Type      string `json:"typ,omitempty"`
+	UserID    int64  `json:"userID"`
+	SessionID string `json:"sid"`
 	jwt.StandardClaims
 }
 
+//APIKey contains a user's API key without the key itself, only its hash is stored.
+//Keys without the expiration time are valid until deleted.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+//APIKeyRequest contains a new API key request, at least one scope is required.
+type APIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+//NewAPIKey contains a created API key, the key is shown only once.
+type NewAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+//Session contains a user session.
+type Session struct {
+	ID          string `json:"id"`
+	CreatedAt   int64  `json:"createdAt"`
+	RefreshedAt int64  `json:"refreshedAt"`
+	ExpiresAt   int64  `json:"expiresAt"`
+	Current     bool   `json:"current"`
+}
+
 //User contains a user.
 type User struct {
 	ID       int64  `json:"id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	Disabled bool   `json:"disabled"`
+}
+
+//NewUser contains a user created by an administrator.
+type NewUser struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+//UserUpdate contains changes of a user, omitted fields stay unchanged.
+type UserUpdate struct {
+	Username *string `json:"username,omitempty"`
+	Role     *string `json:"role,omitempty"`
+}
+
+//PasswordReset contains a new password set by an administrator.
+type PasswordReset struct {
+	Password string `json:"password"`
+}
+
+//Client contains a registered OAuth 2.0 client.
+type Client struct {
+	ID           int64    `json:"id"`
+	ClientID     string   `json:"clientID"`
+	Scopes       []string `json:"scopes"`
+	RedirectURIs []string `json:"redirectURIs"`
+	Public       bool     `json:"public"`
+}
+
+//ClientCredentials contains OAuth 2.0 client credentials.
+type ClientCredentials struct {
+	ClientID     string
+	ClientSecret string
+}
+
+//TokenRequest contains an OAuth 2.0 token request.
+type TokenRequest struct {
+	GrantType         string
+	ClientCredentials *ClientCredentials
+	Scope             string
+	Code              string
+	RedirectURI       string
+	CodeVerifier      string
+}
+
+//AuthorizationRequest contains an OAuth 2.0 authorization request.
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+}
+
+//AuthorizationCode contains an OAuth 2.0 authorization grant bound to the code.
+type AuthorizationCode struct {
+	ClientID      string   `json:"clientID"`
+	UserID        int64    `json:"userID"`
+	RedirectURI   string   `json:"redirectURI"`
+	Scopes        []string `json:"scopes"`
+	CodeChallenge string   `json:"codeChallenge"`
+	Nonce         string   `json:"nonce,omitempty"`
+}
+
+//Token contains an OAuth 2.0 token response.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+//IntrospectionRequest contains an OAuth 2.0 token introspection request (RFC 7662).
+//Only access tokens are introspected, so the token type hint is ignored.
+type IntrospectionRequest struct {
+	ClientCredentials *ClientCredentials
+	Token             string
+	TokenTypeHint     string
+}
+
+//Introspection contains an OAuth 2.0 token introspection response, only active tokens have other fields.
+type Introspection struct {
+	Active bool   `json:"active"`
+	Sub    string `json:"sub,omitempty"`
+	Exp    int64  `json:"exp,omitempty"`
+	Scope  string `json:"scope,omitempty"`
+	Role   string `json:"role,omitempty"`
+}
+
+//RevocationRequest contains an OAuth 2.0 token revocation request (RFC 7009).
+//The token type is identified by the token itself, so the token type hint is ignored.
+type RevocationRequest struct {
+	ClientCredentials *ClientCredentials
+	Token             string
+	TokenTypeHint     string
+}
+
+//TokenInfo identifies an issued access or refresh token by its JWT ID.
+//Client id is the client the token is issued to: the client itself or the one the user authorized,
+//it is empty for user tokens issued by direct logins.
+type TokenInfo struct {
+	ID        string
+	Type      string
+	UserID    int64
+	ClientID  string
+	ExpiresAt int64
+}
+
+//IDTokenClaims contains OpenID Connect ID token claims.
+type IDTokenClaims struct {
+	Type              string `json:"typ,omitempty"`
+	Nonce             string `json:"nonce,omitempty"`
+	PreferredUsername string `json:"preferred_username"`
+	jwt.StandardClaims
+}
+
+//UserInfo contains OpenID Connect standard claims about the user.
+type UserInfo struct {
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+//OpenIDConfiguration contains OpenID Connect provider metadata.
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+//JSONWebKey contains a public JSON web key.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+//JSONWebKeySet contains a JSON web key set.
+type JSONWebKeySet struct {
+	Keys []*JSONWebKey `json:"keys"`
+}
+
+//RolePermissions contains the role with all its permissions including inherited ones.
+type RolePermissions struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
 }
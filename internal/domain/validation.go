@@ -0,0 +1,26 @@
+package domain
+
+import "regexp"
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+var usernameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{2,31}$`)
+
+func validateUsername(username string) error {
+	if !usernameRegexp.MatchString(username) {
+		return ErrInvalidUsername
+	}
+
+	return nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return ErrInvalidPassword
+	}
+
+	return nil
+}
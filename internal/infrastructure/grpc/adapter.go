@@ -0,0 +1,85 @@
+package grpc
+
+import (
+	"net"
+
+	"github.com/lzakharov/goss/api/goss"
+	"github.com/lzakharov/goss/internal/domain"
+	"github.com/lzakharov/goss/internal/infrastructure/http"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+//Adapter represents a gRPC adapter.
+type Adapter interface {
+	Run() error
+	Shutdown() error
+}
+
+//NewAdapter creates a new gRPC adapter.
+//Requests share the rate limiter and the limits with the HTTP adapter, so switching protocols doesn't add attempts.
+func NewAdapter(logger *zap.Logger, config *Config, service domain.Service,
+	rateLimiter http.RateLimiter, rateLimits *http.RateLimitConfig) (Adapter, error) {
+	adapter := &adapter{
+		logger:  logger,
+		config:  config,
+		service: service,
+	}
+
+	interceptor := chainInterceptors(
+		loggerInterceptor(logger),
+		rateLimitInterceptor(logger, rateLimiter, rateLimits),
+	)
+
+	options := []grpc.ServerOption{grpc.UnaryInterceptor(interceptor)}
+
+	if config.TLSCertFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			logger.Error("Error loading the gRPC TLS certificate!", zap.Error(err))
+			return nil, err
+		}
+		options = append(options, grpc.Creds(creds))
+	}
+
+	adapter.server = grpc.NewServer(options...)
+	goss.RegisterGossServer(adapter.server, adapter)
+
+	return adapter, nil
+}
+
+type adapter struct {
+	logger  *zap.Logger
+	config  *Config
+	service domain.Service
+	server  *grpc.Server
+}
+
+//Run starts listening and serving gRPC requests.
+func (a *adapter) Run() error {
+	a.logger.Info("Starting listening and serving gRPC requests.", zap.String("address", a.config.Address))
+
+	if a.config.TLSCertFile == "" {
+		a.logger.Warn("Warning serving gRPC requests without TLS!", zap.String("address", a.config.Address))
+	}
+
+	listener, err := net.Listen("tcp", a.config.Address)
+	if err != nil {
+		a.logger.Error("Error listening the gRPC address!", zap.Error(err))
+		return err
+	}
+
+	if err := a.server.Serve(listener); err != nil {
+		a.logger.Error("Error serving gRPC requests!", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+//Shutdown gracefully shuts down the adapter, it waits for pending requests to finish.
+func (a *adapter) Shutdown() error {
+	a.server.GracefulStop()
+	return nil
+}
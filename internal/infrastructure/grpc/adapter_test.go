@@ -0,0 +1,410 @@
+package grpc
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lzakharov/goss/api/goss"
+	"github.com/lzakharov/goss/internal/domain"
+	"github.com/lzakharov/goss/internal/infrastructure/http"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufferSize = 1024 * 1024
+
+//newTestClient serves the adapter without rate limits on an in-memory listener and returns a client connected to it.
+//Calls time out, so an unexpected service call failing the test in the server goroutine doesn't hang it.
+func newTestClient(t *testing.T, service domain.Service) (goss.GossClient, context.Context, func()) {
+	return newRateLimitedTestClient(t, service, &http.RateLimitConfig{Backend: "memory"})
+}
+
+func newRateLimitedTestClient(t *testing.T, service domain.Service, rateLimits *http.RateLimitConfig) (goss.GossClient, context.Context, func()) {
+	listener := bufconn.Listen(bufferSize)
+
+	rateLimiter, err := http.NewRateLimiter(rateLimits, "", nil)
+	require.NoError(t, err)
+
+	a, err := NewAdapter(zap.NewExample(), &Config{}, service, rateLimiter, rateLimits)
+	require.NoError(t, err)
+	server := a.(*adapter).server
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := grpc.DialContext(context.Background(), "bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithInsecure())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	return goss.NewGossClient(conn), ctx, func() {
+		cancel()
+		_ = conn.Close()
+		server.Stop()
+	}
+}
+
+func withBearer(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, authorizationMetadata, bearerAuthPrefix+token)
+}
+
+func TestAdapter_GetUser(t *testing.T) {
+	user := &domain.User{ID: 42, Username: "alice", Role: "user"}
+
+	t.Run("with access token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().GetAccessTokenClaims("token", gomock.Any()).
+			DoAndReturn(func(_ string, clientInfo *domain.ClientInfo) (*domain.AccessTokenClaims, error) {
+				require.NotEmpty(t, clientInfo.RequestID)
+				require.Contains(t, clientInfo.UserAgent, "grpc-go")
+				return &domain.AccessTokenClaims{UserID: 42, Scope: "profile:read"}, nil
+			})
+		service.EXPECT().GetUser(int64(42)).Return(user, nil)
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		actual, err := client.GetUser(withBearer(ctx, "token"), &goss.GetUserRequest{})
+		require.NoError(t, err)
+		require.Equal(t, int64(42), actual.Id)
+		require.Equal(t, "alice", actual.Username)
+		require.Equal(t, "user", actual.Role)
+	})
+
+	t.Run("with API key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		apiKey := domain.APIKeyPrefix + "key"
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().GetAPIKeyClaims(apiKey, gomock.Any()).
+			Return(&domain.AccessTokenClaims{UserID: 42, Scope: "profile:read", APIKeyID: 3}, nil)
+		service.EXPECT().GetUser(int64(42)).Return(user, nil)
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		actual, err := client.GetUser(withBearer(ctx, apiKey), &goss.GetUserRequest{})
+		require.NoError(t, err)
+		require.Equal(t, int64(42), actual.Id)
+	})
+
+	t.Run("without authorization", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().GetAccessTokenClaims("", gomock.Any()).Return(nil, domain.ErrInvalidAccessToken)
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		_, err := client.GetUser(ctx, &goss.GetUserRequest{})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("with client token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().GetAccessTokenClaims("token", gomock.Any()).
+			Return(&domain.AccessTokenClaims{ClientID: "backend"}, nil)
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		_, err := client.GetUser(withBearer(ctx, "token"), &goss.GetUserRequest{})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("without scope", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().GetAccessTokenClaims("token", gomock.Any()).
+			Return(&domain.AccessTokenClaims{UserID: 42, Scope: "users:read"}, nil)
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		_, err := client.GetUser(withBearer(ctx, "token"), &goss.GetUserRequest{})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("with empty scope", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().GetAccessTokenClaims("token", gomock.Any()).
+			Return(&domain.AccessTokenClaims{UserID: 42}, nil)
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		_, err := client.GetUser(withBearer(ctx, "token"), &goss.GetUserRequest{})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("with all scopes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().GetAccessTokenClaims("token", gomock.Any()).
+			Return(&domain.AccessTokenClaims{UserID: 42, Scope: domain.ScopeAll}, nil)
+		service.EXPECT().GetUser(int64(42)).Return(user, nil)
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		actual, err := client.GetUser(withBearer(ctx, "token"), &goss.GetUserRequest{})
+		require.NoError(t, err)
+		require.Equal(t, int64(42), actual.Id)
+	})
+}
+
+func TestAdapter_Login(t *testing.T) {
+	credentials := &domain.Credentials{
+		Username: "alice",
+		Password: "password",
+	}
+
+	for err, code := range errCode {
+		err, code := err, code
+
+		t.Run(err.Error(), func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			service := domain.NewMockService(ctrl)
+			service.EXPECT().Login(credentials, gomock.Any()).Return(nil, err)
+
+			client, ctx, stop := newTestClient(t, service)
+			defer stop()
+
+			_, actual := client.Login(ctx, &goss.LoginRequest{Username: "alice", Password: "password"})
+			require.Equal(t, code, status.Code(actual))
+			require.Equal(t, err.Error(), status.Convert(actual).Message())
+		})
+	}
+
+	t.Run("with lockout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().Login(credentials, gomock.Any()).
+			Return(nil, &domain.LockoutError{RetryAfter: 90*time.Second + time.Millisecond})
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		var header metadata.MD
+
+		_, err := client.Login(ctx, &goss.LoginRequest{Username: "alice", Password: "password"}, grpc.Header(&header))
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		require.Equal(t, []string{"91"}, header.Get(retryAfterMetadata))
+	})
+
+	t.Run("with rate limit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().Login(credentials, gomock.Any()).Return(nil, domain.ErrInvalidCredentials)
+
+		client, ctx, stop := newRateLimitedTestClient(t, service, &http.RateLimitConfig{
+			Backend: "memory",
+			Auth:    http.RateLimit{Key: "ip", Requests: 1, Period: 90 * time.Second},
+		})
+		defer stop()
+
+		_, err := client.Login(ctx, &goss.LoginRequest{Username: "alice", Password: "password"})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		var header metadata.MD
+
+		_, err = client.Login(ctx, &goss.LoginRequest{Username: "alice", Password: "password"}, grpc.Header(&header))
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		require.Equal(t, []string{"90"}, header.Get(retryAfterMetadata))
+	})
+
+	t.Run("with unknown error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().Login(credentials, gomock.Any()).Return(nil, errors.New("unknown"))
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		_, err := client.Login(ctx, &goss.LoginRequest{Username: "alice", Password: "password"})
+		require.Equal(t, codes.Internal, status.Code(err))
+	})
+}
+
+func TestAdapter_LoginMFA(t *testing.T) {
+	credentials := &domain.MFACredentials{
+		MFAToken: "mfaToken",
+		Code:     "123456",
+		Scope:    "profile:read",
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().LoginMFA(credentials, gomock.Any()).Return(&domain.AuthData{
+			AccessToken:  "accessToken",
+			ExpiresAt:    1574208000,
+			RefreshToken: "refreshToken",
+		}, nil)
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		actual, err := client.LoginMFA(ctx, &goss.LoginMFARequest{MfaToken: "mfaToken", Code: "123456", Scope: "profile:read"})
+		require.NoError(t, err)
+		require.Equal(t, "accessToken", actual.AccessToken)
+		require.Equal(t, int64(1574208000), actual.ExpiresAt)
+		require.Equal(t, "refreshToken", actual.RefreshToken)
+	})
+
+	t.Run("with invalid code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().LoginMFA(credentials, gomock.Any()).Return(nil, domain.ErrInvalidMFACode)
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		_, err := client.LoginMFA(ctx, &goss.LoginMFARequest{MfaToken: "mfaToken", Code: "123456", Scope: "profile:read"})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestAdapter_ValidateToken(t *testing.T) {
+	credentials := &domain.ClientCredentials{
+		ClientID:     "backend",
+		ClientSecret: "secret",
+	}
+
+	backend := &domain.Client{ID: 1, ClientID: "backend"}
+
+	withClientCredentials := func(ctx context.Context) context.Context {
+		return metadata.AppendToOutgoingContext(ctx, authorizationMetadata,
+			"Basic "+base64.StdEncoding.EncodeToString([]byte("backend:secret")))
+	}
+
+	t.Run("access token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		claims := &domain.AccessTokenClaims{
+			UserID:    42,
+			Role:      "user",
+			SessionID: "session",
+			Scope:     "profile:read",
+		}
+		claims.ExpiresAt = 1574208000
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().AuthenticateConfidentialClient(credentials).Return(backend, nil)
+		service.EXPECT().GetAccessTokenClaims("token", gomock.Any()).Return(claims, nil)
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		actual, err := client.ValidateToken(withClientCredentials(ctx), &goss.ValidateTokenRequest{AccessToken: "token"})
+		require.NoError(t, err)
+		require.Equal(t, int64(42), actual.UserId)
+		require.Equal(t, "user", actual.Role)
+		require.Equal(t, "session", actual.SessionId)
+		require.Equal(t, "profile:read", actual.Scope)
+		require.Equal(t, int64(1574208000), actual.ExpiresAt)
+	})
+
+	t.Run("client token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().AuthenticateConfidentialClient(credentials).Return(backend, nil)
+		service.EXPECT().GetAccessTokenClaims("token", gomock.Any()).
+			Return(&domain.AccessTokenClaims{ClientID: "backend", Scope: "users:read"}, nil)
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		actual, err := client.ValidateToken(withClientCredentials(ctx), &goss.ValidateTokenRequest{AccessToken: "token"})
+		require.NoError(t, err)
+		require.Equal(t, "backend", actual.ClientId)
+	})
+
+	t.Run("API key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		apiKey := domain.APIKeyPrefix + "key"
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().AuthenticateConfidentialClient(credentials).Return(backend, nil)
+		service.EXPECT().GetAPIKeyClaims(apiKey, gomock.Any()).
+			Return(&domain.AccessTokenClaims{UserID: 42, Scope: "users:read", APIKeyID: 3}, nil)
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		actual, err := client.ValidateToken(withClientCredentials(ctx), &goss.ValidateTokenRequest{AccessToken: apiKey})
+		require.NoError(t, err)
+		require.Equal(t, int64(3), actual.ApiKeyId)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().AuthenticateConfidentialClient(credentials).Return(backend, nil)
+		service.EXPECT().GetAccessTokenClaims("token", gomock.Any()).Return(nil, domain.ErrInvalidAccessToken)
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		_, err := client.ValidateToken(withClientCredentials(ctx), &goss.ValidateTokenRequest{AccessToken: "token"})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("without client credentials", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		service := domain.NewMockService(ctrl)
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		_, err := client.ValidateToken(ctx, &goss.ValidateTokenRequest{AccessToken: "token"})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("with public client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		service := domain.NewMockService(ctrl)
+		service.EXPECT().AuthenticateConfidentialClient(credentials).Return(nil, domain.ErrUnauthorizedClient)
+
+		client, ctx, stop := newTestClient(t, service)
+		defer stop()
+
+		_, err := client.ValidateToken(withClientCredentials(ctx), &goss.ValidateTokenRequest{AccessToken: "token"})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
@@ -0,0 +1,9 @@
+package grpc
+
+//Config contains a gRPC adapter configuration.
+//Requests are served over TLS when the certificate and key files are set.
+type Config struct {
+	Address     string `validate:"required"`
+	TLSCertFile string `validate:"required_with=TLSKeyFile"`
+	TLSKeyFile  string `validate:"required_with=TLSCertFile"`
+}
@@ -0,0 +1,32 @@
+package grpc
+
+const (
+	authorizationMetadata = "authorization"
+	userAgentMetadata     = "user-agent"
+	retryAfterMetadata    = "retry-after"
+
+	bearerAuthPrefix = "Bearer "
+
+	permissionProfileRead = "profile:read"
+)
+
+const (
+	methodLogin         = "/goss.v1.Goss/Login"
+	methodLoginMFA      = "/goss.v1.Goss/LoginMFA"
+	methodRefresh       = "/goss.v1.Goss/Refresh"
+	methodGetUser       = "/goss.v1.Goss/GetUser"
+	methodLogout        = "/goss.v1.Goss/Logout"
+	methodValidateToken = "/goss.v1.Goss/ValidateToken"
+)
+
+//Rate limit keys are the same as the HTTP adapter's, so both adapters take tokens from the same buckets.
+const (
+	rateLimitKeyIP = "ip"
+
+	rateLimitGroupAuth   = "auth"
+	rateLimitGroupBearer = "bearer"
+)
+
+type ctxKey int
+
+const ctxRequestID ctxKey = iota
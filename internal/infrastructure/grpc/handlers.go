@@ -0,0 +1,138 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/lzakharov/goss/api/goss"
+	"github.com/lzakharov/goss/internal/domain"
+	"go.uber.org/zap"
+)
+
+//Health responses with the service health status.
+func (a *adapter) Health(ctx context.Context, req *goss.HealthRequest) (*goss.HealthResponse, error) {
+	health := a.service.CheckHealth()
+
+	return &goss.HealthResponse{
+		Version:  health.Version,
+		Storage:  health.Storage,
+		Security: health.Security,
+	}, nil
+}
+
+//Login handles user login.
+func (a *adapter) Login(ctx context.Context, req *goss.LoginRequest) (*goss.AuthData, error) {
+	credentials := &domain.Credentials{
+		Username: req.Username,
+		Password: req.Password,
+		Scope:    req.Scope,
+	}
+
+	authData, err := a.service.Login(credentials, newClientInfo(ctx))
+	if err != nil {
+		a.logger.Error("Login error!", zap.Error(err))
+		return nil, err
+	}
+
+	return newAuthData(authData), nil
+}
+
+//LoginMFA handles the second login step of users with two-factor authentication.
+func (a *adapter) LoginMFA(ctx context.Context, req *goss.LoginMFARequest) (*goss.AuthData, error) {
+	credentials := &domain.MFACredentials{
+		MFAToken:     req.MfaToken,
+		Code:         req.Code,
+		RecoveryCode: req.RecoveryCode,
+		Scope:        req.Scope,
+	}
+
+	authData, err := a.service.LoginMFA(credentials, newClientInfo(ctx))
+	if err != nil {
+		a.logger.Error("MFA login error!", zap.Error(err))
+		return nil, err
+	}
+
+	return newAuthData(authData), nil
+}
+
+//Refresh handles user access token refresh.
+func (a *adapter) Refresh(ctx context.Context, req *goss.RefreshRequest) (*goss.AuthData, error) {
+	authData, err := a.service.RefreshToken(req.RefreshToken, newClientInfo(ctx))
+	if err != nil {
+		a.logger.Error("Error refreshing a refresh token!", zap.Error(err))
+		return nil, err
+	}
+
+	return newAuthData(authData), nil
+}
+
+//GetUser returns current logged in user.
+func (a *adapter) GetUser(ctx context.Context, req *goss.GetUserRequest) (*goss.User, error) {
+	claims, err := a.authenticate(ctx, permissionProfileRead)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.service.GetUser(claims.UserID)
+	if err != nil {
+		a.logger.Error("Error getting the logged in user!",
+			zap.Any("claims", claims),
+			zap.Error(err))
+		return nil, err
+	}
+
+	return &goss.User{
+		Id:       user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		Disabled: user.Disabled,
+	}, nil
+}
+
+//Logout handles user logout from the current session.
+func (a *adapter) Logout(ctx context.Context, req *goss.LogoutRequest) (*goss.LogoutResponse, error) {
+	claims, err := a.authenticate(ctx, permissionProfileRead)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.service.Logout(claims.UserID, claims.SessionID, newClientInfo(ctx)); err != nil {
+		a.logger.Error("Logout error!",
+			zap.Any("claims", claims),
+			zap.Error(err))
+		return nil, err
+	}
+
+	return &goss.LogoutResponse{}, nil
+}
+
+//ValidateToken returns claims of the access token or API key, so other services can check tokens in one call.
+//Only confidential clients can validate tokens, the same way as with the HTTP introspection.
+func (a *adapter) ValidateToken(ctx context.Context, req *goss.ValidateTokenRequest) (*goss.TokenClaims, error) {
+	if _, err := a.authenticateClient(ctx); err != nil {
+		return nil, err
+	}
+
+	claims, err := a.getClaims(req.AccessToken, newClientInfo(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return &goss.TokenClaims{
+		UserId:    claims.UserID,
+		Role:      claims.Role,
+		SessionId: claims.SessionID,
+		ClientId:  claims.ClientID,
+		Scope:     claims.Scope,
+		ApiKeyId:  claims.APIKeyID,
+		ExpiresAt: claims.ExpiresAt,
+	}, nil
+}
+
+func newAuthData(authData *domain.AuthData) *goss.AuthData {
+	return &goss.AuthData{
+		AccessToken:  authData.AccessToken,
+		ExpiresAt:    authData.ExpiresAt,
+		RefreshToken: authData.RefreshToken,
+		MfaToken:     authData.MFAToken,
+	}
+}
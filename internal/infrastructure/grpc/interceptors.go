@@ -0,0 +1,222 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lzakharov/goss/internal/domain"
+	"github.com/lzakharov/goss/internal/infrastructure/http"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var errRateLimitExceeded = errors.New("rate limit exceeded")
+
+var errCode = map[error]codes.Code{
+	domain.ErrInternalStorage:      codes.Internal,
+	domain.ErrInternalSecurity:     codes.Internal,
+	domain.ErrNotFound:             codes.NotFound,
+	domain.ErrInvalidCredentials:   codes.Unauthenticated,
+	domain.ErrInvalidClient:        codes.Unauthenticated,
+	domain.ErrUnauthorizedClient:   codes.PermissionDenied,
+	domain.ErrInvalidAccessToken:   codes.Unauthenticated,
+	domain.ErrInvalidRefreshToken:  codes.InvalidArgument,
+	domain.ErrRefreshTokenReused:   codes.Unauthenticated,
+	domain.ErrInvalidMFAToken:      codes.Unauthenticated,
+	domain.ErrInvalidMFACode:       codes.Unauthenticated,
+	domain.ErrInvalidRequest:       codes.InvalidArgument,
+	domain.ErrInvalidUsername:      codes.InvalidArgument,
+	domain.ErrInvalidPassword:      codes.InvalidArgument,
+	domain.ErrInvalidScope:         codes.InvalidArgument,
+	domain.ErrUserAlreadyExists:    codes.AlreadyExists,
+	domain.ErrRegistrationDisabled: codes.PermissionDenied,
+	domain.ErrPermissionDenied:     codes.PermissionDenied,
+	domain.ErrUserDisabled:         codes.PermissionDenied,
+	domain.ErrTooManyAttempts:      codes.ResourceExhausted,
+	errRateLimitExceeded:           codes.ResourceExhausted,
+}
+
+//chainInterceptors runs the interceptors in order, the first one is the outermost.
+func chainInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+//loggerInterceptor logs requests with a new request id and converts domain errors to gRPC statuses.
+func loggerInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		u, err := uuid.NewRandom()
+		if err != nil {
+			logger.Error("Error generating a request id!",
+				zap.String("method", info.FullMethod),
+				zap.Error(err))
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		requestID := u.String()
+
+		logger.Info("Got request.",
+			zap.String("requestID", requestID),
+			zap.String("method", info.FullMethod))
+
+		resp, err := handler(context.WithValue(ctx, ctxRequestID, requestID), req)
+		if err != nil {
+			logger.Error("Error handling the request!",
+				zap.String("requestID", requestID),
+				zap.String("method", info.FullMethod),
+				zap.Error(err))
+			return nil, toStatus(ctx, err)
+		}
+
+		return resp, nil
+	}
+}
+
+//rateLimitInterceptor limits login and refresh methods with the HTTP auth limit and the others with the bearer limit.
+//Requests are limited by the client IP in the same buckets as HTTP requests, as they are checked before authentication.
+func rateLimitInterceptor(logger *zap.Logger, limiter http.RateLimiter, rateLimits *http.RateLimitConfig) grpc.UnaryServerInterceptor {
+	type methodLimit struct {
+		group string
+		limit *http.RateLimit
+	}
+
+	auth := methodLimit{group: rateLimitGroupAuth, limit: &rateLimits.Auth}
+	bearer := methodLimit{group: rateLimitGroupBearer, limit: &rateLimits.Bearer}
+
+	limits := map[string]methodLimit{
+		methodLogin:         auth,
+		methodLoginMFA:      auth,
+		methodRefresh:       auth,
+		methodGetUser:       bearer,
+		methodLogout:        bearer,
+		methodValidateToken: bearer,
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		method, ok := limits[info.FullMethod]
+		if !ok || method.limit.Requests == 0 {
+			return handler(ctx, req)
+		}
+
+		key := method.group + ":" + rateLimitKeyIP + ":" + newClientInfo(ctx).IP
+
+		result, err := limiter.Allow(key, method.limit)
+		if err != nil {
+			logger.Error("Error checking the rate limit!",
+				zap.String("key", key),
+				zap.Error(err))
+			return handler(ctx, req)
+		}
+
+		if !result.Allowed {
+			retryAfter := strconv.FormatInt(int64(math.Ceil(result.RetryAfter.Seconds())), 10)
+			_ = grpc.SetHeader(ctx, metadata.Pairs(retryAfterMetadata, retryAfter))
+			return nil, errRateLimitExceeded
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+//toStatus converts the domain error to a gRPC status error, lockout errors also set the retry-after header in seconds.
+func toStatus(ctx context.Context, err error) error {
+	var lockout *domain.LockoutError
+	if errors.As(err, &lockout) {
+		retryAfter := strconv.FormatInt(int64(math.Ceil(lockout.RetryAfter.Seconds())), 10)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(retryAfterMetadata, retryAfter))
+		err = domain.ErrTooManyAttempts
+	}
+
+	code, ok := errCode[err]
+	if !ok {
+		code = codes.Internal
+	}
+
+	return status.Error(code, err.Error())
+}
+
+func newClientInfo(ctx context.Context) *domain.ClientInfo {
+	clientInfo := new(domain.ClientInfo)
+	clientInfo.RequestID, _ = ctx.Value(ctxRequestID).(string)
+
+	if p, ok := peer.FromContext(ctx); ok {
+		clientInfo.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(clientInfo.IP); err == nil {
+			clientInfo.IP = host
+		}
+	}
+
+	clientInfo.UserAgent = firstMetadata(ctx, userAgentMetadata)
+
+	return clientInfo
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+//getClaims returns claims of the user access token or API key.
+func (a *adapter) getClaims(accessToken string, clientInfo *domain.ClientInfo) (*domain.AccessTokenClaims, error) {
+	if strings.HasPrefix(accessToken, domain.APIKeyPrefix) {
+		return a.service.GetAPIKeyClaims(accessToken, clientInfo)
+	}
+
+	return a.service.GetAccessTokenClaims(accessToken, clientInfo)
+}
+
+//authenticateClient authenticates the confidential client by its credentials in the Basic authorization metadata.
+func (a *adapter) authenticateClient(ctx context.Context) (*domain.Client, error) {
+	credentials, err := http.ParseClientBasicAuth(firstMetadata(ctx, authorizationMetadata))
+	if err != nil {
+		return nil, err
+	}
+
+	return a.service.AuthenticateConfidentialClient(credentials)
+}
+
+//authenticate returns claims of the bearer token in the authorization metadata, the token must be granted the scope.
+//Client credentials tokens are rejected the same way as by the HTTP adapter.
+func (a *adapter) authenticate(ctx context.Context, scope string) (*domain.AccessTokenClaims, error) {
+	accessToken := strings.TrimPrefix(firstMetadata(ctx, authorizationMetadata), bearerAuthPrefix)
+
+	claims, err := a.getClaims(accessToken, newClientInfo(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.ClientID != "" {
+		return nil, domain.ErrInvalidAccessToken
+	}
+
+	if !claims.HasScope(scope) {
+		return nil, domain.ErrPermissionDenied
+	}
+
+	return claims, nil
+}
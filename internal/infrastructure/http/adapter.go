@@ -14,12 +14,13 @@ type Adapter interface {
 }
 
 //NewAdapter creates a new HTTP adapter.
-func NewAdapter(logger *zap.Logger, config *Config, service domain.Service) Adapter {
+func NewAdapter(logger *zap.Logger, config *Config, service domain.Service, rateLimiter RateLimiter) Adapter {
 	adapter := &adapter{
-		logger:    logger,
-		validator: validator.New(),
-		config:    config,
-		service:   service,
+		logger:      logger,
+		validator:   validator.New(),
+		config:      config,
+		service:     service,
+		rateLimiter: rateLimiter,
 	}
 
 	adapter.server = &fasthttp.Server{
@@ -31,11 +32,12 @@ func NewAdapter(logger *zap.Logger, config *Config, service domain.Service) Adap
 }
 
 type adapter struct {
-	logger    *zap.Logger
-	config    *Config
-	validator *validator.Validate
-	service   domain.Service
-	server    *fasthttp.Server
+	logger      *zap.Logger
+	config      *Config
+	validator   *validator.Validate
+	service     domain.Service
+	rateLimiter RateLimiter
+	server      *fasthttp.Server
 }
 
 //Run starts listening and serving HTTP requests.
@@ -4,6 +4,17 @@ import "time"
 
 //Config contains a HTTP adapter configuration.
 type Config struct {
-	Address     string        `validate:"required"`
-	ReadTimeout time.Duration `validate:"required"`
+	Address     string           `validate:"required"`
+	ReadTimeout time.Duration    `validate:"required"`
+	RateLimit   *RateLimitConfig `validate:"required"`
+}
+
+//RateLimitConfig contains rate limits of the route groups, an empty limit disables limiting.
+//The bearer limit is checked before access tokens are validated, so it can only key requests by the client IP.
+type RateLimitConfig struct {
+	Backend string    `default:"memory" validate:"required,oneof=memory redis"`
+	Auth    RateLimit `default:"ip:20/1m"`
+	OAuth   RateLimit `default:"ip:60/1m"`
+	Bearer  RateLimit `default:"ip:600/1m"`
+	User    RateLimit `default:"user:300/1m"`
 }
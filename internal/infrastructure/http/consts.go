@@ -1,8 +1,93 @@
 package http
 
+import "time"
+
 const (
 	authorizationHeader = "Authorization"
+	cacheControlHeader  = "Cache-Control"
+	pragmaHeader        = "Pragma"
+	authenticateHeader  = "WWW-Authenticate"
+	retryAfterHeader    = "Retry-After"
+
+	rateLimitLimitHeader     = "RateLimit-Limit"
+	rateLimitRemainingHeader = "RateLimit-Remaining"
+	rateLimitResetHeader     = "RateLimit-Reset"
+
+	jwksCacheControl  = "public, max-age=300"
+	tokenCacheControl = "no-store"
+	tokenPragma       = "no-cache"
+
+	bearerAuthPrefix   = "Bearer "
+	basicAuthPrefix    = "Basic "
+	basicAuthChallenge = `Basic realm="goss"`
+
+	formGrantType     = "grant_type"
+	formScope         = "scope"
+	formClientID      = "client_id"
+	formClientSecret  = "client_secret"
+	formCode          = "code"
+	formRedirectURI   = "redirect_uri"
+	formCodeVerifier  = "code_verifier"
+	formToken         = "token"
+	formTokenTypeHint = "token_type_hint"
+
+	queryResponseType        = "response_type"
+	queryClientID            = "client_id"
+	queryRedirectURI         = "redirect_uri"
+	queryScope               = "scope"
+	queryState               = "state"
+	queryCodeChallenge       = "code_challenge"
+	queryCodeChallengeMethod = "code_challenge_method"
+	queryNonce               = "nonce"
+	queryCode                = "code"
+	queryError               = "error"
+	queryErrorDescription    = "error_description"
+	queryRole                = "role"
+	queryStatus              = "status"
+	queryUsername            = "username"
+	querySort                = "sort"
+	queryCursor              = "cursor"
+	queryLimit               = "limit"
+	queryFrom                = "from"
+	queryTo                  = "to"
+	queryUserID              = "userID"
+	queryAction              = "action"
+	queryOutcome             = "outcome"
+
+	oauthServerError  = "server_error"
+	oauthAccessDenied = "access_denied"
+	oauthUnavailable  = "temporarily_unavailable"
 
 	ctxRequestID = "requestID"
 	ctxClaims    = "claims"
 )
+
+const (
+	rateLimitBackendMemory = "memory"
+	rateLimitBackendRedis  = "redis"
+
+	rateLimitKeyIP     = "ip"
+	rateLimitKeyUser   = "user"
+	rateLimitKeyAPIKey = "apikey"
+
+	rateLimitKeyFormat     = "%sratelimit:%s"
+	rateLimitSweepInterval = time.Minute
+
+	rateLimitGroupAuth   = "auth"
+	rateLimitGroupOAuth  = "oauth"
+	rateLimitGroupUser   = "user"
+	rateLimitGroupBearer = "bearer"
+)
+
+const (
+	userStatusActive   = "active"
+	userStatusDisabled = "disabled"
+)
+
+const (
+	permissionProfileRead  = "profile:read"
+	permissionProfileWrite = "profile:write"
+	permissionUsersRead    = "users:read"
+	permissionUsersWrite   = "users:write"
+	permissionAuditRead    = "audit:read"
+)
@@ -2,7 +2,10 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/lzakharov/goss/internal/domain"
 	routing "github.com/qiangxue/fasthttp-routing"
@@ -14,23 +17,78 @@ func (a *adapter) newRouter() fasthttp.RequestHandler {
 	router := routing.New()
 	router.Use(loggerMiddleware(a.logger), jsonWriterMiddleware, errorHandlerMiddleware)
 
-	authMiddleware := authMiddleware(a.service.GetAccessTokenClaims)
+	authMiddleware := authMiddleware(a.service.GetAccessTokenClaims, a.service.GetAPIKeyClaims)
+	rateLimits := a.config.RateLimit
+	bearerRateLimit := rateLimitMiddleware(a.logger, a.rateLimiter, rateLimitGroupBearer, &rateLimits.Bearer)
+
+	router.Get("/.well-known/jwks.json", a.GetJSONWebKeySet)
+	router.Get("/.well-known/openid-configuration", a.GetOpenIDConfiguration)
 
 	v1 := router.Group("/v1")
 	{
 		v1.Get("/health", a.Health)
 
 		auth := v1.Group("/auth")
+		auth.Use(rateLimitMiddleware(a.logger, a.rateLimiter, rateLimitGroupAuth, &rateLimits.Auth))
 		{
+			auth.Post("/register", a.Register)
 			auth.Post("/login", a.Login)
+			auth.Post("/login/mfa", a.LoginMFA)
 			auth.Post("/refresh", a.Refresh)
 		}
 
+		oauth := v1.Group("/oauth")
+		oauth.Use(oauthErrorHandlerMiddleware, rateLimitMiddleware(a.logger, a.rateLimiter, rateLimitGroupOAuth, &rateLimits.OAuth))
+		{
+			oauth.Get("/authorize", a.Authorize)
+			oauth.Post("/token", a.Token)
+			oauth.Post("/introspect", a.Introspect)
+			oauth.Post("/revoke", a.Revoke)
+		}
+
+		oidc := v1.Group("/oidc")
+		oidc.Use(bearerRateLimit, authMiddleware, rateLimitMiddleware(a.logger, a.rateLimiter, rateLimitGroupUser, &rateLimits.User), requireScope(domain.ScopeOpenID))
+		{
+			oidc.To("GET,POST", "/userinfo", a.GetUserInfo)
+		}
+
+		writeProfile := requireScope(permissionProfileWrite)
+
 		user := v1.Group("/user")
-		user.Use(authMiddleware)
+		user.Use(bearerRateLimit, authMiddleware, rateLimitMiddleware(a.logger, a.rateLimiter, rateLimitGroupUser, &rateLimits.User), requireScope(permissionProfileRead))
 		{
 			user.Get("/self", a.GetUser)
+			user.Get("/permissions", a.GetPermissions)
+			user.Post("/mfa/totp", writeProfile, a.EnrollTOTP)
+			user.Post("/mfa/totp/confirm", writeProfile, a.ConfirmTOTP)
+			user.Delete("/mfa/totp", writeProfile, a.DisableTOTP)
+			user.Get("/mfa/recovery-codes", a.GetRecoveryCodes)
+			user.Post("/mfa/recovery-codes", writeProfile, a.RegenerateRecoveryCodes)
+			user.Get("/sessions", a.GetSessions)
+			user.Delete("/sessions/<id>", writeProfile, a.RevokeSession)
 			user.Post("/logout", a.Logout)
+			user.Post("/logout/all", a.LogoutEverywhere)
+			user.Get("/api-keys", requireSession, a.ListAPIKeys)
+			user.Post("/api-keys", requireSession, writeProfile, a.CreateAPIKey)
+			user.Delete("/api-keys/<id>", requireSession, writeProfile, a.DeleteAPIKey)
+		}
+
+		readUsers := requirePermission(a.service.CheckPermission, permissionUsersRead)
+		writeUsers := requirePermission(a.service.CheckPermission, permissionUsersWrite)
+		readAudit := requirePermission(a.service.CheckPermission, permissionAuditRead)
+
+		admin := v1.Group("/admin")
+		admin.Use(bearerRateLimit, authMiddleware, rateLimitMiddleware(a.logger, a.rateLimiter, rateLimitGroupUser, &rateLimits.User))
+		{
+			admin.Get("/users", readUsers, a.ListUsers)
+			admin.Post("/users", writeUsers, a.CreateUser)
+			admin.Get("/users/<id>", readUsers, a.GetUserByID)
+			admin.Patch("/users/<id>", writeUsers, a.UpdateUser)
+			admin.Delete("/users/<id>", writeUsers, a.DeleteUser)
+			admin.Post("/users/<id>/disable", writeUsers, a.DisableUser)
+			admin.Post("/users/<id>/enable", writeUsers, a.EnableUser)
+			admin.Put("/users/<id>/password", writeUsers, a.ResetPassword)
+			admin.Get("/audit-events", readAudit, a.ListAuditEvents)
 		}
 	}
 
@@ -43,11 +101,42 @@ func (a *adapter) Health(ctx *routing.Context) error {
 	return ctx.WriteData(health)
 }
 
+//GetJSONWebKeySet responses with public keys for token verification.
+func (a *adapter) GetJSONWebKeySet(ctx *routing.Context) error {
+	ctx.Response.Header.Set(cacheControlHeader, jwksCacheControl)
+	return ctx.WriteData(a.service.GetJSONWebKeySet())
+}
+
+//GetOpenIDConfiguration responses with OpenID Connect provider metadata.
+func (a *adapter) GetOpenIDConfiguration(ctx *routing.Context) error {
+	ctx.Response.Header.Set(cacheControlHeader, jwksCacheControl)
+	return ctx.WriteData(a.service.GetOpenIDConfiguration())
+}
+
+//Register handles user registration.
+func (a *adapter) Register(ctx *routing.Context) error {
+	credentials := new(domain.Credentials)
+
+	if err := json.Unmarshal(ctx.Request.Body(), credentials); err != nil {
+		a.logger.Error("Error unmarshalling credentials!", zap.Error(err))
+		return err
+	}
+
+	user, err := a.service.Register(credentials)
+	if err != nil {
+		a.logger.Error("Registration error!", zap.String("username", credentials.Username), zap.Error(err))
+		return err
+	}
+
+	ctx.SetStatusCode(http.StatusCreated)
+	return ctx.WriteData(user)
+}
+
 //Login handles user login.
 func (a *adapter) Login(ctx *routing.Context) error {
-	var credentials *domain.Credentials
+	credentials := new(domain.Credentials)
 
-	if err := json.Unmarshal(ctx.Request.Body(), &credentials); err != nil {
+	if err := json.Unmarshal(ctx.Request.Body(), credentials); err != nil {
 		a.logger.Error("Error unmarshalling credentials!", zap.Binary("body", ctx.Request.Body()), zap.Error(err))
 		return err
 	}
@@ -57,7 +146,7 @@ func (a *adapter) Login(ctx *routing.Context) error {
 		return err
 	}
 
-	authData, err := a.service.Login(credentials)
+	authData, err := a.service.Login(credentials, newClientInfo(ctx))
 	if err != nil {
 		a.logger.Error("Login error!", zap.Error(err))
 		return err
@@ -66,6 +155,24 @@ func (a *adapter) Login(ctx *routing.Context) error {
 	return ctx.WriteData(authData)
 }
 
+//LoginMFA handles the second login step of users with two-factor authentication.
+func (a *adapter) LoginMFA(ctx *routing.Context) error {
+	credentials := new(domain.MFACredentials)
+
+	if err := json.Unmarshal(ctx.Request.Body(), credentials); err != nil {
+		a.logger.Error("Error unmarshalling MFA credentials!", zap.Error(err))
+		return err
+	}
+
+	authData, err := a.service.LoginMFA(credentials, newClientInfo(ctx))
+	if err != nil {
+		a.logger.Error("MFA login error!", zap.Error(err))
+		return err
+	}
+
+	return ctx.WriteData(authData)
+}
+
 //Refresh handles user access token refresh.
 func (a *adapter) Refresh(ctx *routing.Context) error {
 	var refreshToken string
@@ -75,7 +182,7 @@ func (a *adapter) Refresh(ctx *routing.Context) error {
 		return err
 	}
 
-	authData, err := a.service.RefreshToken(refreshToken)
+	authData, err := a.service.RefreshToken(refreshToken, newClientInfo(ctx))
 	if err != nil {
 		a.logger.Error("Error refreshing a refresh token!", zap.String("refresh", refreshToken), zap.Error(err))
 		return err
@@ -84,6 +191,208 @@ func (a *adapter) Refresh(ctx *routing.Context) error {
 	return ctx.WriteData(authData)
 }
 
+//Authorize handles OAuth 2.0 authorization requests.
+//The user is authenticated with HTTP Basic and redirected back to the client with a code.
+//Errors are reported to the client by redirecting, unless the client or redirect uri is invalid.
+func (a *adapter) Authorize(ctx *routing.Context) error {
+	query := ctx.QueryArgs()
+
+	request := &domain.AuthorizationRequest{
+		ResponseType:        string(query.Peek(queryResponseType)),
+		ClientID:            string(query.Peek(queryClientID)),
+		RedirectURI:         string(query.Peek(queryRedirectURI)),
+		Scope:               string(query.Peek(queryScope)),
+		State:               string(query.Peek(queryState)),
+		CodeChallenge:       string(query.Peek(queryCodeChallenge)),
+		CodeChallengeMethod: string(query.Peek(queryCodeChallengeMethod)),
+		Nonce:               string(query.Peek(queryNonce)),
+	}
+
+	redirectURI, err := a.service.ResolveRedirectURI(request.ClientID, request.RedirectURI)
+	if err != nil {
+		a.logger.Error("Error resolving the redirect uri!",
+			zap.String("clientID", request.ClientID),
+			zap.String("redirectURI", request.RedirectURI),
+			zap.Error(err))
+
+		if err == domain.ErrInvalidClient {
+			ctx.SetStatusCode(http.StatusBadRequest)
+			return ctx.WriteData(&OAuthErrorResponse{Error: oauthErrCode[err], Description: err.Error()})
+		}
+		return err
+	}
+
+	username, password, ok := parseBasicAuth(string(ctx.Request.Header.Peek(authorizationHeader)))
+	if !ok {
+		return a.requestUserAuthentication(ctx)
+	}
+
+	credentials := &domain.Credentials{
+		Username: username,
+		Password: password,
+	}
+
+	params := map[string]string{queryState: request.State}
+
+	code, err := a.service.Authorize(request, credentials, newClientInfo(ctx))
+	switch {
+	case err == domain.ErrInvalidCredentials:
+		return a.requestUserAuthentication(ctx)
+	case errors.Is(err, domain.ErrTooManyAttempts):
+		return err
+	case err != nil:
+		a.logger.Error("Authorization error!",
+			zap.String("clientID", request.ClientID),
+			zap.String("username", username),
+			zap.Error(err))
+
+		params[queryError] = oauthServerError
+		if code, ok := oauthErrCode[err]; ok {
+			params[queryError] = code
+			params[queryErrorDescription] = err.Error()
+		}
+	default:
+		params[queryCode] = code
+	}
+
+	location, err := newRedirectURI(redirectURI, params)
+	if err != nil {
+		a.logger.Error("Error building the redirect uri!",
+			zap.String("redirectURI", redirectURI),
+			zap.Error(err))
+		return domain.ErrInvalidRedirectURI
+	}
+
+	ctx.Response.Header.Set(cacheControlHeader, tokenCacheControl)
+	ctx.Redirect(location, http.StatusFound)
+	return nil
+}
+
+func (a *adapter) requestUserAuthentication(ctx *routing.Context) error {
+	ctx.Response.Header.Set(authenticateHeader, basicAuthChallenge)
+	ctx.SetStatusCode(http.StatusUnauthorized)
+	return ctx.WriteData(&OAuthErrorResponse{
+		Error:       oauthAccessDenied,
+		Description: domain.ErrInvalidCredentials.Error(),
+	})
+}
+
+func newClientInfo(ctx *routing.Context) *domain.ClientInfo {
+	requestID, _ := ctx.Get(ctxRequestID).(string)
+
+	return &domain.ClientInfo{
+		IP:        ctx.RemoteIP().String(),
+		UserAgent: string(ctx.UserAgent()),
+		RequestID: requestID,
+	}
+}
+
+//Token handles OAuth 2.0 token requests.
+//Clients authenticate either with HTTP Basic or with credentials in the request body.
+func (a *adapter) Token(ctx *routing.Context) error {
+	ctx.Response.Header.Set(cacheControlHeader, tokenCacheControl)
+	ctx.Response.Header.Set(pragmaHeader, tokenPragma)
+
+	form := ctx.PostArgs()
+
+	request := &domain.TokenRequest{
+		GrantType:    string(form.Peek(formGrantType)),
+		Scope:        string(form.Peek(formScope)),
+		Code:         string(form.Peek(formCode)),
+		RedirectURI:  string(form.Peek(formRedirectURI)),
+		CodeVerifier: string(form.Peek(formCodeVerifier)),
+	}
+	if request.GrantType == "" {
+		return domain.ErrInvalidRequest
+	}
+
+	credentials, err := parseClientCredentials(ctx)
+	if err != nil {
+		a.logger.Error("Error parsing client credentials!", zap.Error(err))
+		return err
+	}
+	request.ClientCredentials = credentials
+
+	token, err := a.service.IssueToken(request)
+	if err != nil {
+		a.logger.Error("Error issuing a token!",
+			zap.String("grantType", request.GrantType),
+			zap.String("clientID", credentials.ClientID),
+			zap.Error(err))
+		return err
+	}
+
+	return ctx.WriteData(token)
+}
+
+//Introspect handles OAuth 2.0 token introspection requests (RFC 7662).
+//Clients authenticate the same way as for token requests.
+func (a *adapter) Introspect(ctx *routing.Context) error {
+	ctx.Response.Header.Set(cacheControlHeader, tokenCacheControl)
+	ctx.Response.Header.Set(pragmaHeader, tokenPragma)
+
+	form := ctx.PostArgs()
+
+	request := &domain.IntrospectionRequest{
+		Token:         string(form.Peek(formToken)),
+		TokenTypeHint: string(form.Peek(formTokenTypeHint)),
+	}
+	if request.Token == "" {
+		return domain.ErrInvalidRequest
+	}
+
+	credentials, err := parseClientCredentials(ctx)
+	if err != nil {
+		a.logger.Error("Error parsing client credentials!", zap.Error(err))
+		return err
+	}
+	request.ClientCredentials = credentials
+
+	introspection, err := a.service.IntrospectToken(request)
+	if err != nil {
+		a.logger.Error("Error introspecting a token!",
+			zap.String("clientID", credentials.ClientID),
+			zap.Error(err))
+		return err
+	}
+
+	return ctx.WriteData(introspection)
+}
+
+//Revoke handles OAuth 2.0 token revocation requests (RFC 7009).
+//Unknown tokens are considered revoked, so the response is successful for them too.
+func (a *adapter) Revoke(ctx *routing.Context) error {
+	ctx.Response.Header.Set(cacheControlHeader, tokenCacheControl)
+	ctx.Response.Header.Set(pragmaHeader, tokenPragma)
+
+	form := ctx.PostArgs()
+
+	request := &domain.RevocationRequest{
+		Token:         string(form.Peek(formToken)),
+		TokenTypeHint: string(form.Peek(formTokenTypeHint)),
+	}
+	if request.Token == "" {
+		return domain.ErrInvalidRequest
+	}
+
+	credentials, err := parseClientCredentials(ctx)
+	if err != nil {
+		a.logger.Error("Error parsing client credentials!", zap.Error(err))
+		return err
+	}
+	request.ClientCredentials = credentials
+
+	if err := a.service.RevokeToken(request); err != nil {
+		a.logger.Error("Error revoking a token!",
+			zap.String("clientID", credentials.ClientID),
+			zap.Error(err))
+		return err
+	}
+
+	ctx.SetStatusCode(http.StatusOK)
+	return nil
+}
+
 //GetUser returns current logged in user.
 func (a *adapter) GetUser(ctx *routing.Context) error {
 	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
@@ -99,11 +408,195 @@ func (a *adapter) GetUser(ctx *routing.Context) error {
 	return ctx.WriteData(user)
 }
 
-//Logout handles user logout.
+//GetPermissions returns permissions of current logged in user's role.
+func (a *adapter) GetPermissions(ctx *routing.Context) error {
+	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+	permissions, err := a.service.GetPermissions(claims.Role)
+	if err != nil {
+		a.logger.Error("Error getting permissions of the logged in user!",
+			zap.Any("claims", claims),
+			zap.Error(err))
+		return err
+	}
+
+	return ctx.WriteData(permissions)
+}
+
+//EnrollTOTP generates a new TOTP secret for current logged in user.
+func (a *adapter) EnrollTOTP(ctx *routing.Context) error {
+	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+	enrollment, err := a.service.EnrollTOTP(claims.UserID)
+	if err != nil {
+		a.logger.Error("Error enrolling TOTP!",
+			zap.Any("claims", claims),
+			zap.Error(err))
+		return err
+	}
+
+	ctx.Response.Header.Set(cacheControlHeader, tokenCacheControl)
+	return ctx.WriteData(enrollment)
+}
+
+//ConfirmTOTP enables two-factor authentication for current logged in user and returns recovery codes.
+func (a *adapter) ConfirmTOTP(ctx *routing.Context) error {
+	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+	code := new(domain.MFACode)
+
+	if err := json.Unmarshal(ctx.Request.Body(), code); err != nil {
+		a.logger.Error("Error unmarshalling an MFA code!", zap.Error(err))
+		return err
+	}
+
+	recoveryCodes, err := a.service.ConfirmTOTP(claims.UserID, code.Code)
+	if err != nil {
+		a.logger.Error("Error confirming TOTP!",
+			zap.Any("claims", claims),
+			zap.Error(err))
+		return err
+	}
+
+	ctx.Response.Header.Set(cacheControlHeader, tokenCacheControl)
+	return ctx.WriteData(recoveryCodes)
+}
+
+//DisableTOTP disables two-factor authentication for current logged in user.
+func (a *adapter) DisableTOTP(ctx *routing.Context) error {
+	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+	code := new(domain.MFACode)
+
+	if err := json.Unmarshal(ctx.Request.Body(), code); err != nil {
+		a.logger.Error("Error unmarshalling an MFA code!", zap.Error(err))
+		return err
+	}
+
+	if err := a.service.DisableTOTP(claims.UserID, code.Code); err != nil {
+		a.logger.Error("Error disabling TOTP!",
+			zap.Any("claims", claims),
+			zap.Error(err))
+		return err
+	}
+
+	ctx.SetStatusCode(http.StatusNoContent)
+	return nil
+}
+
+//GetUserInfo returns OpenID Connect claims about current logged in user.
+func (a *adapter) GetUserInfo(ctx *routing.Context) error {
+	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+	userInfo, err := a.service.GetUserInfo(claims.UserID)
+	if err != nil {
+		a.logger.Error("Error getting the logged in user info!",
+			zap.Any("claims", claims),
+			zap.Error(err))
+		return err
+	}
+
+	return ctx.WriteData(userInfo)
+}
+
+//GetSessions returns current logged in user's sessions.
+func (a *adapter) GetSessions(ctx *routing.Context) error {
+	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+	sessions, err := a.service.GetSessions(claims.UserID, claims.SessionID)
+	if err != nil {
+		a.logger.Error("Error getting the logged in user's sessions!",
+			zap.Any("claims", claims),
+			zap.Error(err))
+		return err
+	}
+
+	return ctx.WriteData(sessions)
+}
+
+//RevokeSession revokes current logged in user's session.
+func (a *adapter) RevokeSession(ctx *routing.Context) error {
+	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+	sessionID := ctx.Param("id")
+
+	if err := a.service.RevokeSession(claims.UserID, sessionID); err != nil {
+		a.logger.Error("Error revoking the logged in user's session!",
+			zap.Any("claims", claims),
+			zap.String("sessionID", sessionID),
+			zap.Error(err))
+		return err
+	}
+
+	ctx.SetStatusCode(http.StatusNoContent)
+	return nil
+}
+
+//ListAPIKeys returns current logged in user's API keys.
+func (a *adapter) ListAPIKeys(ctx *routing.Context) error {
+	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+	keys, err := a.service.ListAPIKeys(claims.UserID)
+	if err != nil {
+		a.logger.Error("Error listing the logged in user's API keys!",
+			zap.Any("claims", claims),
+			zap.Error(err))
+		return err
+	}
+
+	return ctx.WriteData(keys)
+}
+
+//CreateAPIKey creates an API key for current logged in user, the key is returned only once.
+func (a *adapter) CreateAPIKey(ctx *routing.Context) error {
+	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+	request := new(domain.APIKeyRequest)
+
+	if err := json.Unmarshal(ctx.Request.Body(), request); err != nil {
+		a.logger.Error("Error unmarshalling an API key request!", zap.Error(err))
+		return err
+	}
+
+	key, err := a.service.CreateAPIKey(claims.UserID, request)
+	if err != nil {
+		a.logger.Error("Error creating an API key!",
+			zap.Any("claims", claims),
+			zap.String("name", request.Name),
+			zap.Error(err))
+		return err
+	}
+
+	ctx.Response.Header.Set(cacheControlHeader, tokenCacheControl)
+	ctx.SetStatusCode(http.StatusCreated)
+	return ctx.WriteData(key)
+}
+
+//DeleteAPIKey deletes current logged in user's API key.
+func (a *adapter) DeleteAPIKey(ctx *routing.Context) error {
+	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+	keyID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return domain.ErrNotFound
+	}
+
+	if err := a.service.DeleteAPIKey(claims.UserID, keyID); err != nil {
+		a.logger.Error("Error deleting the logged in user's API key!",
+			zap.Any("claims", claims),
+			zap.Int64("apiKeyID", keyID),
+			zap.Error(err))
+		return err
+	}
+
+	ctx.SetStatusCode(http.StatusNoContent)
+	return nil
+}
+
+//Logout handles user logout from the current session.
 func (a *adapter) Logout(ctx *routing.Context) error {
 	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
 
-	if err := a.service.Logout(claims.UserID); err != nil {
+	if err := a.service.Logout(claims.UserID, claims.SessionID, newClientInfo(ctx)); err != nil {
 		a.logger.Error("Logout error!",
 			zap.Any("claims", claims),
 			zap.Error(err))
@@ -113,3 +606,273 @@ func (a *adapter) Logout(ctx *routing.Context) error {
 	ctx.SetStatusCode(http.StatusNoContent)
 	return nil
 }
+
+//LogoutEverywhere handles user logout from all sessions.
+func (a *adapter) LogoutEverywhere(ctx *routing.Context) error {
+	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+	if err := a.service.LogoutEverywhere(claims.UserID, newClientInfo(ctx)); err != nil {
+		a.logger.Error("Logout error!",
+			zap.Any("claims", claims),
+			zap.Error(err))
+		return err
+	}
+
+	ctx.SetStatusCode(http.StatusNoContent)
+	return nil
+}
+
+//GetRecoveryCodes returns the number of remaining recovery codes of current logged in user.
+func (a *adapter) GetRecoveryCodes(ctx *routing.Context) error {
+	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+	recoveryCodes, err := a.service.GetRecoveryCodes(claims.UserID)
+	if err != nil {
+		a.logger.Error("Error getting recovery codes!",
+			zap.Any("claims", claims),
+			zap.Error(err))
+		return err
+	}
+
+	return ctx.WriteData(recoveryCodes)
+}
+
+//RegenerateRecoveryCodes replaces recovery codes of current logged in user.
+func (a *adapter) RegenerateRecoveryCodes(ctx *routing.Context) error {
+	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+	code := new(domain.MFACode)
+
+	if err := json.Unmarshal(ctx.Request.Body(), code); err != nil {
+		a.logger.Error("Error unmarshalling an MFA code!", zap.Error(err))
+		return err
+	}
+
+	recoveryCodes, err := a.service.RegenerateRecoveryCodes(claims.UserID, code.Code)
+	if err != nil {
+		a.logger.Error("Error regenerating recovery codes!",
+			zap.Any("claims", claims),
+			zap.Error(err))
+		return err
+	}
+
+	ctx.Response.Header.Set(cacheControlHeader, tokenCacheControl)
+	return ctx.WriteData(recoveryCodes)
+}
+
+//ListUsers returns a page of users filtered by the query parameters.
+func (a *adapter) ListUsers(ctx *routing.Context) error {
+	query := ctx.QueryArgs()
+
+	filter := &domain.UserFilter{
+		Role:           string(query.Peek(queryRole)),
+		UsernamePrefix: string(query.Peek(queryUsername)),
+		Sort:           string(query.Peek(querySort)),
+		Cursor:         string(query.Peek(queryCursor)),
+	}
+
+	switch status := string(query.Peek(queryStatus)); status {
+	case "":
+	case userStatusActive, userStatusDisabled:
+		disabled := status == userStatusDisabled
+		filter.Disabled = &disabled
+	default:
+		return domain.ErrInvalidRequest
+	}
+
+	if limit := query.Peek(queryLimit); len(limit) != 0 {
+		n, err := strconv.Atoi(string(limit))
+		if err != nil {
+			return domain.ErrInvalidRequest
+		}
+		filter.Limit = n
+	}
+
+	page, err := a.service.ListUsers(filter)
+	if err != nil {
+		a.logger.Error("Error listing users!", zap.Any("filter", filter), zap.Error(err))
+		return err
+	}
+
+	return ctx.WriteData(page)
+}
+
+//CreateUser creates a new user on behalf of an administrator.
+func (a *adapter) CreateUser(ctx *routing.Context) error {
+	newUser := new(domain.NewUser)
+
+	if err := json.Unmarshal(ctx.Request.Body(), newUser); err != nil {
+		a.logger.Error("Error unmarshalling a new user!", zap.Error(err))
+		return err
+	}
+
+	user, err := a.service.CreateUser(newUser)
+	if err != nil {
+		a.logger.Error("Error creating a user!", zap.String("username", newUser.Username), zap.Error(err))
+		return err
+	}
+
+	ctx.SetStatusCode(http.StatusCreated)
+	return ctx.WriteData(user)
+}
+
+//GetUserByID returns the user with the id from the path.
+func (a *adapter) GetUserByID(ctx *routing.Context) error {
+	userID, err := parseUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.service.GetUser(userID)
+	if err != nil {
+		a.logger.Error("Error getting a user!", zap.Int64("userID", userID), zap.Error(err))
+		return err
+	}
+
+	return ctx.WriteData(user)
+}
+
+//UpdateUser changes the username or the role of the user with the id from the path.
+func (a *adapter) UpdateUser(ctx *routing.Context) error {
+	userID, err := parseUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	update := new(domain.UserUpdate)
+
+	if err := json.Unmarshal(ctx.Request.Body(), update); err != nil {
+		a.logger.Error("Error unmarshalling a user update!", zap.Error(err))
+		return err
+	}
+
+	user, err := a.service.UpdateUser(userID, update)
+	if err != nil {
+		a.logger.Error("Error updating a user!", zap.Int64("userID", userID), zap.Error(err))
+		return err
+	}
+
+	return ctx.WriteData(user)
+}
+
+//DeleteUser deletes the user with the id from the path.
+func (a *adapter) DeleteUser(ctx *routing.Context) error {
+	userID, err := parseUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := a.service.DeleteUser(userID); err != nil {
+		a.logger.Error("Error deleting a user!", zap.Int64("userID", userID), zap.Error(err))
+		return err
+	}
+
+	ctx.SetStatusCode(http.StatusNoContent)
+	return nil
+}
+
+//DisableUser disables the user with the id from the path.
+func (a *adapter) DisableUser(ctx *routing.Context) error {
+	return a.setUserDisabled(ctx, true)
+}
+
+//EnableUser enables the user with the id from the path.
+func (a *adapter) EnableUser(ctx *routing.Context) error {
+	return a.setUserDisabled(ctx, false)
+}
+
+func (a *adapter) setUserDisabled(ctx *routing.Context, disabled bool) error {
+	userID, err := parseUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := a.service.SetUserDisabled(userID, disabled); err != nil {
+		a.logger.Error("Error setting a user disabled!",
+			zap.Int64("userID", userID),
+			zap.Bool("disabled", disabled),
+			zap.Error(err))
+		return err
+	}
+
+	ctx.SetStatusCode(http.StatusNoContent)
+	return nil
+}
+
+//ResetPassword sets a new password of the user with the id from the path.
+func (a *adapter) ResetPassword(ctx *routing.Context) error {
+	userID, err := parseUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	reset := new(domain.PasswordReset)
+
+	if err := json.Unmarshal(ctx.Request.Body(), reset); err != nil {
+		a.logger.Error("Error unmarshalling a password reset!", zap.Error(err))
+		return err
+	}
+
+	if err := a.service.ResetPassword(userID, reset.Password); err != nil {
+		a.logger.Error("Error resetting a user's password!", zap.Int64("userID", userID), zap.Error(err))
+		return err
+	}
+
+	ctx.SetStatusCode(http.StatusNoContent)
+	return nil
+}
+
+func parseUserID(ctx *routing.Context) (int64, error) {
+	userID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		return 0, domain.ErrNotFound
+	}
+
+	return userID, nil
+}
+
+//ListAuditEvents returns a page of audit events filtered by the query parameters.
+//The time range is given in RFC 3339.
+func (a *adapter) ListAuditEvents(ctx *routing.Context) error {
+	query := ctx.QueryArgs()
+
+	filter := &domain.AuditFilter{
+		Action:  string(query.Peek(queryAction)),
+		Outcome: string(query.Peek(queryOutcome)),
+		Cursor:  string(query.Peek(queryCursor)),
+	}
+
+	var err error
+
+	if from := query.Peek(queryFrom); len(from) != 0 {
+		if filter.From, err = time.Parse(time.RFC3339, string(from)); err != nil {
+			return domain.ErrInvalidRequest
+		}
+	}
+
+	if to := query.Peek(queryTo); len(to) != 0 {
+		if filter.To, err = time.Parse(time.RFC3339, string(to)); err != nil {
+			return domain.ErrInvalidRequest
+		}
+	}
+
+	if userID := query.Peek(queryUserID); len(userID) != 0 {
+		if filter.UserID, err = strconv.ParseInt(string(userID), 10, 64); err != nil {
+			return domain.ErrInvalidRequest
+		}
+	}
+
+	if limit := query.Peek(queryLimit); len(limit) != 0 {
+		if filter.Limit, err = strconv.Atoi(string(limit)); err != nil {
+			return domain.ErrInvalidRequest
+		}
+	}
+
+	page, err := a.service.ListAuditEvents(filter)
+	if err != nil {
+		a.logger.Error("Error listing audit events!", zap.Any("filter", filter), zap.Error(err))
+		return err
+	}
+
+	return ctx.WriteData(page)
+}
@@ -2,7 +2,9 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/lzakharov/goss/internal/domain"
@@ -12,7 +14,9 @@ import (
 
 const mimeJSON = "application/json"
 
-type getClaims func(accessToken string) (*domain.AccessTokenClaims, error)
+type getClaims func(accessToken string, clientInfo *domain.ClientInfo) (*domain.AccessTokenClaims, error)
+
+type checkPermission func(role, permission string) error
 
 func jsonWriterMiddleware(ctx *routing.Context) error {
 	ctx.SetContentType(mimeJSON)
@@ -55,14 +59,23 @@ func loggerMiddleware(logger *zap.Logger) routing.Handler {
 	}
 }
 
-func authMiddleware(getClaims getClaims) routing.Handler {
+//authMiddleware accepts user access tokens and API keys as bearer tokens.
+func authMiddleware(getClaims, getAPIKeyClaims getClaims) routing.Handler {
 	return func(ctx *routing.Context) error {
-		accessToken := string(ctx.Request.Header.Peek(authorizationHeader))
+		accessToken := strings.TrimPrefix(string(ctx.Request.Header.Peek(authorizationHeader)), bearerAuthPrefix)
+
+		get := getClaims
+		if strings.HasPrefix(accessToken, domain.APIKeyPrefix) {
+			get = getAPIKeyClaims
+		}
 
-		claims, err := getClaims(accessToken)
+		claims, err := get(accessToken, newClientInfo(ctx))
 		if err != nil {
-			ctx.SetStatusCode(http.StatusUnauthorized)
-			return ctx.WriteData(err)
+			return err
+		}
+
+		if claims.ClientID != "" {
+			return domain.ErrInvalidAccessToken
 		}
 
 		ctx.Set(ctxClaims, claims)
@@ -70,9 +83,51 @@ func authMiddleware(getClaims getClaims) routing.Handler {
 	}
 }
 
+//requirePermission allows only requests with access tokens whose role has the permission,
+//scoped tokens must also be granted the permission as a scope.
+//It must follow authMiddleware.
+func requirePermission(checkPermission checkPermission, permission string) routing.Handler {
+	return func(ctx *routing.Context) error {
+		claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+		if !claims.HasScope(permission) {
+			return domain.ErrPermissionDenied
+		}
+
+		return checkPermission(claims.Role, permission)
+	}
+}
+
+//requireScope allows only requests with access tokens granted the scope or all scopes.
+//It must follow authMiddleware.
+func requireScope(scope string) routing.Handler {
+	return func(ctx *routing.Context) error {
+		claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+		if !claims.HasScope(scope) {
+			return domain.ErrPermissionDenied
+		}
+
+		return nil
+	}
+}
+
+//requireSession allows only requests with access tokens of a user session, so API keys can't manage API keys.
+//It must follow authMiddleware.
+func requireSession(ctx *routing.Context) error {
+	claims := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+	if claims.APIKeyID != 0 {
+		return domain.ErrPermissionDenied
+	}
+
+	return nil
+}
+
 func errorHandlerMiddleware(ctx *routing.Context) error {
 	if err := ctx.Next(); err != nil {
 		requestID := ctx.Get(ctxRequestID).(string)
+		err = setRetryAfter(ctx, err)
 
 		switch err {
 		case domain.ErrInternalStorage:
@@ -87,6 +142,22 @@ func errorHandlerMiddleware(ctx *routing.Context) error {
 			ctx.SetStatusCode(http.StatusUnauthorized)
 		case domain.ErrInvalidRefreshToken:
 			ctx.SetStatusCode(http.StatusBadRequest)
+		case domain.ErrRefreshTokenReused:
+			ctx.SetStatusCode(http.StatusUnauthorized)
+		case domain.ErrInvalidUsername, domain.ErrInvalidPassword, domain.ErrInvalidRole, domain.ErrInvalidRequest, domain.ErrInvalidScope:
+			ctx.SetStatusCode(http.StatusBadRequest)
+		case domain.ErrUserAlreadyExists:
+			ctx.SetStatusCode(http.StatusConflict)
+		case domain.ErrRegistrationDisabled, domain.ErrPermissionDenied, domain.ErrUserDisabled:
+			ctx.SetStatusCode(http.StatusForbidden)
+		case domain.ErrInvalidMFAToken, domain.ErrInvalidMFACode:
+			ctx.SetStatusCode(http.StatusUnauthorized)
+		case domain.ErrMFANotEnabled:
+			ctx.SetStatusCode(http.StatusBadRequest)
+		case domain.ErrMFAAlreadyEnabled:
+			ctx.SetStatusCode(http.StatusConflict)
+		case domain.ErrTooManyAttempts, errRateLimitExceeded:
+			ctx.SetStatusCode(http.StatusTooManyRequests)
 		default:
 			resp := &ErrorResponse{
 				Status:    http.StatusInternalServerError,
@@ -108,3 +179,48 @@ func errorHandlerMiddleware(ctx *routing.Context) error {
 
 	return nil
 }
+
+//oauthErrorHandlerMiddleware responses with OAuth 2.0 errors instead of the common error response.
+func oauthErrorHandlerMiddleware(ctx *routing.Context) error {
+	err := ctx.Next()
+	if err == nil {
+		return nil
+	}
+
+	err = setRetryAfter(ctx, err)
+
+	code, ok := oauthErrCode[err]
+	switch {
+	case err == domain.ErrTooManyAttempts, err == errRateLimitExceeded:
+		ctx.SetStatusCode(http.StatusTooManyRequests)
+	case err == domain.ErrInvalidClient:
+		ctx.SetStatusCode(http.StatusUnauthorized)
+		if len(ctx.Request.Header.Peek(authorizationHeader)) != 0 {
+			ctx.Response.Header.Set(authenticateHeader, basicAuthChallenge)
+		}
+	case ok:
+		ctx.SetStatusCode(http.StatusBadRequest)
+	default:
+		ctx.SetStatusCode(http.StatusInternalServerError)
+		return ctx.WriteData(&OAuthErrorResponse{Error: oauthServerError})
+	}
+
+	resp := &OAuthErrorResponse{
+		Error:       code,
+		Description: err.Error(),
+	}
+
+	return ctx.WriteData(resp)
+}
+
+//setRetryAfter sets the Retry-After header for lockout errors and replaces them with ErrTooManyAttempts.
+func setRetryAfter(ctx *routing.Context, err error) error {
+	var lockout *domain.LockoutError
+	if !errors.As(err, &lockout) {
+		return err
+	}
+
+	ctx.Response.Header.Set(retryAfterHeader, formatSeconds(lockout.RetryAfter))
+
+	return domain.ErrTooManyAttempts
+}
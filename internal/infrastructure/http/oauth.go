@@ -0,0 +1,94 @@
+package http
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+
+	"github.com/lzakharov/goss/internal/domain"
+	routing "github.com/qiangxue/fasthttp-routing"
+)
+
+//parseClientCredentials gets client credentials from the Authorization header or the request body.
+//Using both authentication methods at once is not allowed (RFC 6749, section 2.3).
+func parseClientCredentials(ctx *routing.Context) (*domain.ClientCredentials, error) {
+	form := ctx.PostArgs()
+	authorization := string(ctx.Request.Header.Peek(authorizationHeader))
+
+	if authorization == "" {
+		if !form.Has(formClientID) {
+			return nil, domain.ErrInvalidClient
+		}
+
+		return &domain.ClientCredentials{
+			ClientID:     string(form.Peek(formClientID)),
+			ClientSecret: string(form.Peek(formClientSecret)),
+		}, nil
+	}
+
+	if form.Has(formClientSecret) {
+		return nil, domain.ErrInvalidRequest
+	}
+
+	return ParseClientBasicAuth(authorization)
+}
+
+//ParseClientBasicAuth parses HTTP Basic credentials of a client.
+//The client id and secret are form-encoded before being joined (RFC 6749, section 2.3.1).
+func ParseClientBasicAuth(authorization string) (*domain.ClientCredentials, error) {
+	username, password, ok := parseBasicAuth(authorization)
+	if !ok {
+		return nil, domain.ErrInvalidClient
+	}
+
+	clientID, err := url.QueryUnescape(username)
+	if err != nil {
+		return nil, domain.ErrInvalidClient
+	}
+	clientSecret, err := url.QueryUnescape(password)
+	if err != nil {
+		return nil, domain.ErrInvalidClient
+	}
+
+	return &domain.ClientCredentials{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	}, nil
+}
+
+//parseBasicAuth parses HTTP Basic credentials.
+func parseBasicAuth(authorization string) (username, password string, ok bool) {
+	if !strings.HasPrefix(authorization, basicAuthPrefix) {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(authorization[len(basicAuthPrefix):])
+	if err != nil {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+//newRedirectURI adds parameters to the query of the client's redirect uri.
+func newRedirectURI(redirectURI string, params map[string]string) (string, error) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	for name, value := range params {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
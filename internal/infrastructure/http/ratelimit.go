@@ -0,0 +1,310 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/lzakharov/goss/internal/domain"
+	routing "github.com/qiangxue/fasthttp-routing"
+	"go.uber.org/zap"
+)
+
+var errRateLimitExceeded = errors.New("rate limit exceeded")
+
+//RateLimit is a token bucket limit written as "<key>:<requests>/<period>", e.g. "ip:20/1m".
+//The bucket holds up to Requests tokens and is refilled at Requests per Period.
+//Requests are keyed by the client IP, the user ID of the access token or the API key.
+type RateLimit struct {
+	Key      string
+	Requests int
+	Period   time.Duration
+}
+
+//Decode parses the limit from its string representation.
+func (l *RateLimit) Decode(value string) error {
+	if value == "" {
+		*l = RateLimit{}
+		return nil
+	}
+
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid rate limit '%s'", value)
+	}
+
+	switch parts[0] {
+	case rateLimitKeyIP, rateLimitKeyUser, rateLimitKeyAPIKey:
+	default:
+		return fmt.Errorf("unknown rate limit key '%s'", parts[0])
+	}
+
+	rate := strings.SplitN(parts[1], "/", 2)
+	if len(rate) != 2 {
+		return fmt.Errorf("invalid rate limit '%s'", value)
+	}
+
+	requests, err := strconv.Atoi(rate[0])
+	if err != nil || requests <= 0 {
+		return fmt.Errorf("invalid rate limit requests '%s'", rate[0])
+	}
+
+	period, err := time.ParseDuration(rate[1])
+	if err != nil || period <= 0 {
+		return fmt.Errorf("invalid rate limit period '%s'", rate[1])
+	}
+
+	*l = RateLimit{
+		Key:      parts[0],
+		Requests: requests,
+		Period:   period,
+	}
+
+	return nil
+}
+
+//rate returns the number of tokens added per nanosecond.
+func (l *RateLimit) rate() float64 {
+	return float64(l.Requests) / float64(l.Period)
+}
+
+func (l *RateLimit) refill(tokens float64, elapsed time.Duration) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(l.Requests), tokens+float64(elapsed)*l.rate())
+}
+
+//identify returns the client identity the limit is applied to.
+//Users and API keys are taken from the validated claims, so the limit must follow authMiddleware to apply to them.
+//Requests without a user or an API key are limited by the client IP.
+func (l *RateLimit) identify(ctx *routing.Context) string {
+	claims, _ := ctx.Get(ctxClaims).(*domain.AccessTokenClaims)
+
+	switch {
+	case claims == nil:
+	case l.Key == rateLimitKeyUser && claims.UserID != 0:
+		return rateLimitKeyUser + ":" + strconv.FormatInt(claims.UserID, 10)
+	case l.Key == rateLimitKeyAPIKey && claims.APIKeyID != 0:
+		return rateLimitKeyAPIKey + ":" + strconv.FormatInt(claims.APIKeyID, 10)
+	}
+
+	return rateLimitKeyIP + ":" + ctx.RemoteIP().String()
+}
+
+//RateLimitResult contains the rate limit state after a request.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+func newRateLimitResult(limit *RateLimit, allowed bool, tokens float64) *RateLimitResult {
+	rate := limit.rate()
+
+	result := &RateLimitResult{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: int(tokens),
+		Reset:     time.Duration((float64(limit.Requests) - tokens) / rate),
+	}
+
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rate)
+	}
+
+	return result
+}
+
+//RateLimiter represents a token bucket rate limiter.
+type RateLimiter interface {
+	Allow(key string, limit *RateLimit) (*RateLimitResult, error)
+}
+
+//RedisScripter represents a redis client able to run Lua scripts.
+type RedisScripter interface {
+	Eval(script string, keys []string, args ...interface{}) *redis.Cmd
+	EvalSha(sha1 string, keys []string, args ...interface{}) *redis.Cmd
+	ScriptExists(hashes ...string) *redis.BoolSliceCmd
+	ScriptLoad(script string) *redis.StringCmd
+}
+
+//NewRateLimiter creates a new rate limiter with the configured backend.
+//The in-memory backend limits each instance separately, the redis one shares limits between instances.
+//Redis keys start with the key prefix, so deployments sharing a redis server don't share buckets.
+func NewRateLimiter(config *RateLimitConfig, keyPrefix string, redisClient RedisScripter) (RateLimiter, error) {
+	switch config.Backend {
+	case rateLimitBackendMemory:
+		return &memoryRateLimiter{buckets: make(map[string]*tokenBucket)}, nil
+	case rateLimitBackendRedis:
+		return &redisRateLimiter{
+			client:    redisClient,
+			script:    redis.NewScript(tokenBucketScript),
+			keyPrefix: keyPrefix,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend '%s'", config.Backend)
+	}
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	period  time.Duration
+}
+
+type memoryRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+//Allow takes a token from the bucket if there is one.
+func (l *memoryRateLimiter) Allow(key string, limit *RateLimit) (*RateLimitResult, error) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{
+			tokens:  float64(limit.Requests),
+			updated: now,
+			period:  limit.Period,
+		}
+		l.buckets[key] = bucket
+	}
+
+	tokens := limit.refill(bucket.tokens, now.Sub(bucket.updated))
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+
+	bucket.tokens = tokens
+	bucket.updated = now
+
+	return newRateLimitResult(limit, allowed, tokens), nil
+}
+
+//sweep forgets buckets that have been refilled completely.
+func (l *memoryRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < rateLimitSweepInterval {
+		return
+	}
+
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.updated) >= bucket.period {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.swept = now
+}
+
+//tokenBucketScript refills the bucket, takes a token if there is one and returns the result with the tokens left.
+const tokenBucketScript = `
+local capacity = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(bucket[1])
+local updated = tonumber(bucket[2])
+if tokens == nil or updated == nil then
+	tokens = capacity
+	updated = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - updated) * capacity / period)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "updated", now)
+redis.call("PEXPIRE", KEYS[1], period)
+
+return {allowed, tostring(tokens)}
+`
+
+type redisRateLimiter struct {
+	client    RedisScripter
+	script    *redis.Script
+	keyPrefix string
+}
+
+//Allow takes a token from the bucket shared between instances.
+func (l *redisRateLimiter) Allow(key string, limit *RateLimit) (*RateLimitResult, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	period := int64(limit.Period / time.Millisecond)
+
+	key = fmt.Sprintf(rateLimitKeyFormat, l.keyPrefix, key)
+
+	reply, err := l.script.Run(l.client, []string{key}, limit.Requests, period, now).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return nil, fmt.Errorf("unexpected rate limit script reply %v", reply)
+	}
+
+	allowed, _ := values[0].(int64)
+	remaining, _ := values[1].(string)
+
+	tokens, err := strconv.ParseFloat(remaining, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRateLimitResult(limit, allowed == 1, tokens), nil
+}
+
+func rateLimitMiddleware(logger *zap.Logger, limiter RateLimiter, group string, limit *RateLimit) routing.Handler {
+	return func(ctx *routing.Context) error {
+		if limit.Requests == 0 {
+			return nil
+		}
+
+		key := group + ":" + limit.identify(ctx)
+
+		result, err := limiter.Allow(key, limit)
+		if err != nil {
+			logger.Error("Error checking the rate limit!",
+				zap.String("key", key),
+				zap.Error(err))
+			return nil
+		}
+
+		header := &ctx.Response.Header
+		header.Set(rateLimitLimitHeader, strconv.Itoa(result.Limit))
+		header.Set(rateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+		header.Set(rateLimitResetHeader, formatSeconds(result.Reset))
+
+		if !result.Allowed {
+			header.Set(retryAfterHeader, formatSeconds(result.RetryAfter))
+			return errRateLimitExceeded
+		}
+
+		return nil
+	}
+}
+
+//formatSeconds formats the duration as a number of seconds rounded up.
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
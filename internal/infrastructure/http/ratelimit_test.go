@@ -0,0 +1,236 @@
+package http
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"bou.ke/monkey"
+	"github.com/go-redis/redis"
+	"github.com/lzakharov/goss/internal/domain"
+	routing "github.com/qiangxue/fasthttp-routing"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+	"go.uber.org/zap"
+)
+
+func newTestContext(claims *domain.AccessTokenClaims) *routing.Context {
+	requestCtx := new(fasthttp.RequestCtx)
+	requestCtx.Init(new(fasthttp.Request), &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1234}, nil)
+
+	ctx := &routing.Context{RequestCtx: requestCtx}
+	if claims != nil {
+		ctx.Set(ctxClaims, claims)
+	}
+
+	return ctx
+}
+
+func TestRateLimit_Decode(t *testing.T) {
+	for _, c := range []struct {
+		value    string
+		expected RateLimit
+		invalid  bool
+	}{
+		{value: "", expected: RateLimit{}},
+		{value: "ip:20/1m", expected: RateLimit{Key: "ip", Requests: 20, Period: time.Minute}},
+		{value: "user:300/1h", expected: RateLimit{Key: "user", Requests: 300, Period: time.Hour}},
+		{value: "apikey:5/1s", expected: RateLimit{Key: "apikey", Requests: 5, Period: time.Second}},
+		{value: "ip", invalid: true},
+		{value: "host:20/1m", invalid: true},
+		{value: "ip:20", invalid: true},
+		{value: "ip:0/1m", invalid: true},
+		{value: "ip:-1/1m", invalid: true},
+		{value: "ip:many/1m", invalid: true},
+		{value: "ip:20/0s", invalid: true},
+		{value: "ip:20/soon", invalid: true},
+	} {
+		t.Run(c.value, func(t *testing.T) {
+			limit := RateLimit{Key: "ip", Requests: 1, Period: time.Second}
+
+			err := limit.Decode(c.value)
+			if c.invalid {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, c.expected, limit)
+		})
+	}
+}
+
+func TestRateLimit_identify(t *testing.T) {
+	session := &domain.AccessTokenClaims{UserID: 42, SessionID: "session"}
+	apiKey := &domain.AccessTokenClaims{UserID: 42, APIKeyID: 7}
+	client := &domain.AccessTokenClaims{ClientID: "backend"}
+
+	for _, c := range []struct {
+		name     string
+		key      string
+		claims   *domain.AccessTokenClaims
+		expected string
+	}{
+		{"ip", rateLimitKeyIP, session, "ip:192.0.2.1"},
+		{"user", rateLimitKeyUser, session, "user:42"},
+		{"user of an API key", rateLimitKeyUser, apiKey, "user:42"},
+		{"user without claims", rateLimitKeyUser, nil, "ip:192.0.2.1"},
+		{"user of a client token", rateLimitKeyUser, client, "ip:192.0.2.1"},
+		{"API key", rateLimitKeyAPIKey, apiKey, "apikey:7"},
+		{"API key of a session token", rateLimitKeyAPIKey, session, "ip:192.0.2.1"},
+		{"API key without claims", rateLimitKeyAPIKey, nil, "ip:192.0.2.1"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			limit := &RateLimit{Key: c.key, Requests: 1, Period: time.Second}
+			require.Equal(t, c.expected, limit.identify(newTestContext(c.claims)))
+		})
+	}
+
+	t.Run("API key with a forged authorization header", func(t *testing.T) {
+		limit := &RateLimit{Key: rateLimitKeyAPIKey, Requests: 1, Period: time.Second}
+
+		ctx := newTestContext(nil)
+		ctx.Request.Header.Set(authorizationHeader, "Bearer goss_forged")
+
+		require.Equal(t, "ip:192.0.2.1", limit.identify(ctx))
+	})
+}
+
+func TestMemoryRateLimiter_Allow(t *testing.T) {
+	now := time.Date(2019, 11, 1, 12, 0, 0, 0, time.UTC)
+	timePatch := monkey.Patch(time.Now, func() time.Time {
+		return now
+	})
+	defer timePatch.Unpatch()
+
+	limiter := &memoryRateLimiter{buckets: make(map[string]*tokenBucket), swept: now}
+	limit := &RateLimit{Key: rateLimitKeyIP, Requests: 2, Period: time.Minute}
+
+	for _, c := range []struct {
+		name     string
+		elapsed  time.Duration
+		key      string
+		expected *RateLimitResult
+	}{
+		{"first request", 0, "a", &RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, Reset: 30 * time.Second}},
+		{"second request", 0, "a", &RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, Reset: time.Minute}},
+		{"exhausted bucket", 0, "a", &RateLimitResult{Allowed: false, Limit: 2, Remaining: 0, Reset: time.Minute, RetryAfter: 30 * time.Second}},
+		{"another key", 0, "b", &RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, Reset: 30 * time.Second}},
+		{"partially refilled bucket", 15 * time.Second, "a", &RateLimitResult{Allowed: false, Limit: 2, Remaining: 0, Reset: 45 * time.Second, RetryAfter: 15 * time.Second}},
+		{"refilled token", 15 * time.Second, "a", &RateLimitResult{Allowed: true, Limit: 2, Remaining: 0, Reset: time.Minute}},
+		{"completely refilled bucket", time.Hour, "a", &RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, Reset: 30 * time.Second}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			now = now.Add(c.elapsed)
+
+			actual, err := limiter.Allow(c.key, limit)
+			require.NoError(t, err)
+			require.Equal(t, c.expected, actual)
+		})
+	}
+}
+
+func TestMemoryRateLimiter_sweep(t *testing.T) {
+	now := time.Date(2019, 11, 1, 12, 0, 0, 0, time.UTC)
+	timePatch := monkey.Patch(time.Now, func() time.Time {
+		return now
+	})
+	defer timePatch.Unpatch()
+
+	limiter := &memoryRateLimiter{buckets: make(map[string]*tokenBucket), swept: now}
+
+	_, err := limiter.Allow("short", &RateLimit{Key: rateLimitKeyIP, Requests: 1, Period: time.Second})
+	require.NoError(t, err)
+	_, err = limiter.Allow("long", &RateLimit{Key: rateLimitKeyIP, Requests: 1, Period: time.Hour})
+	require.NoError(t, err)
+
+	t.Run("before the sweep interval", func(t *testing.T) {
+		now = now.Add(rateLimitSweepInterval / 2)
+		limiter.sweep(now)
+		require.Len(t, limiter.buckets, 2)
+	})
+
+	t.Run("after the sweep interval", func(t *testing.T) {
+		now = now.Add(rateLimitSweepInterval)
+		limiter.sweep(now)
+		require.Len(t, limiter.buckets, 1)
+		require.Contains(t, limiter.buckets, "long")
+	})
+}
+
+//scriptRecorder records the keys of the scripts run and replies with an allowed request.
+type scriptRecorder struct {
+	keys []string
+}
+
+func (r *scriptRecorder) Eval(_ string, keys []string, _ ...interface{}) *redis.Cmd {
+	return r.EvalSha("", keys)
+}
+
+func (r *scriptRecorder) EvalSha(_ string, keys []string, _ ...interface{}) *redis.Cmd {
+	r.keys = append(r.keys, keys...)
+	return redis.NewCmdResult([]interface{}{int64(1), "1"}, nil)
+}
+
+func (r *scriptRecorder) ScriptExists(hashes ...string) *redis.BoolSliceCmd {
+	return redis.NewBoolSliceResult(make([]bool, len(hashes)), nil)
+}
+
+func (r *scriptRecorder) ScriptLoad(_ string) *redis.StringCmd {
+	return redis.NewStringResult("", nil)
+}
+
+func TestRedisRateLimiter_Allow(t *testing.T) {
+	client := new(scriptRecorder)
+
+	limiter, err := NewRateLimiter(&RateLimitConfig{Backend: rateLimitBackendRedis}, "auth:", client)
+	require.NoError(t, err)
+
+	actual, err := limiter.Allow("auth:ip:192.0.2.1", &RateLimit{Key: rateLimitKeyIP, Requests: 2, Period: time.Minute})
+	require.NoError(t, err)
+	require.Equal(t, &RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, Reset: 30 * time.Second}, actual)
+	require.Equal(t, []string{"auth:ratelimit:auth:ip:192.0.2.1"}, client.keys)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	now := time.Date(2019, 11, 1, 12, 0, 0, 0, time.UTC)
+	timePatch := monkey.Patch(time.Now, func() time.Time {
+		return now
+	})
+	defer timePatch.Unpatch()
+
+	logger := zap.NewExample()
+
+	t.Run("headers", func(t *testing.T) {
+		limiter := &memoryRateLimiter{buckets: make(map[string]*tokenBucket), swept: now}
+		middleware := rateLimitMiddleware(logger, limiter, rateLimitGroupAuth, &RateLimit{Key: rateLimitKeyIP, Requests: 1, Period: 90 * time.Second})
+
+		ctx := newTestContext(nil)
+		require.NoError(t, middleware(ctx))
+		require.Equal(t, "1", string(ctx.Response.Header.Peek(rateLimitLimitHeader)))
+		require.Equal(t, "0", string(ctx.Response.Header.Peek(rateLimitRemainingHeader)))
+		require.Equal(t, "90", string(ctx.Response.Header.Peek(rateLimitResetHeader)))
+		require.Empty(t, ctx.Response.Header.Peek(retryAfterHeader))
+
+		ctx = newTestContext(nil)
+		require.Equal(t, errRateLimitExceeded, middleware(ctx))
+		require.Equal(t, "90", string(ctx.Response.Header.Peek(retryAfterHeader)))
+	})
+
+	t.Run("disabled limit", func(t *testing.T) {
+		limiter := &memoryRateLimiter{buckets: make(map[string]*tokenBucket), swept: now}
+		middleware := rateLimitMiddleware(logger, limiter, rateLimitGroupAuth, &RateLimit{})
+
+		ctx := newTestContext(nil)
+		require.NoError(t, middleware(ctx))
+		require.Empty(t, ctx.Response.Header.Peek(rateLimitLimitHeader))
+		require.Empty(t, limiter.buckets)
+	})
+}
+
+func TestFormatSeconds(t *testing.T) {
+	require.Equal(t, "0", formatSeconds(0))
+	require.Equal(t, "1", formatSeconds(time.Millisecond))
+	require.Equal(t, "60", formatSeconds(time.Minute))
+	require.Equal(t, "61", formatSeconds(time.Minute+time.Nanosecond))
+}
@@ -5,18 +5,55 @@ import (
 )
 
 var errStatus = map[error]int{
-	domain.ErrInvalidCredentials:  4011,
-	domain.ErrInvalidAccessToken:  4012,
-	domain.ErrInvalidRefreshToken: 4013,
-	domain.ErrNotFound:            4040,
+	domain.ErrInvalidUsername:      4001,
+	domain.ErrInvalidPassword:      4002,
+	domain.ErrMFANotEnabled:        4003,
+	domain.ErrInvalidRole:          4004,
+	domain.ErrInvalidRequest:       4005,
+	domain.ErrInvalidScope:         4006,
+	domain.ErrInvalidCredentials:   4011,
+	domain.ErrInvalidAccessToken:   4012,
+	domain.ErrInvalidRefreshToken:  4013,
+	domain.ErrRefreshTokenReused:   4014,
+	domain.ErrInvalidMFAToken:      4015,
+	domain.ErrInvalidMFACode:       4016,
+	domain.ErrRegistrationDisabled: 4031,
+	domain.ErrPermissionDenied:     4032,
+	domain.ErrUserDisabled:         4033,
+	domain.ErrNotFound:             4040,
+	domain.ErrUserAlreadyExists:    4091,
+	domain.ErrMFAAlreadyEnabled:    4092,
+	domain.ErrTooManyAttempts:      4291,
+	errRateLimitExceeded:           4292,
 
 	domain.ErrInternalStorage:  5001,
 	domain.ErrInternalSecurity: 5002,
 }
 
+var oauthErrCode = map[error]string{
+	domain.ErrInvalidRequest:          "invalid_request",
+	domain.ErrInvalidRedirectURI:      "invalid_request",
+	domain.ErrInvalidClient:           "invalid_client",
+	domain.ErrInvalidGrant:            "invalid_grant",
+	domain.ErrUnauthorizedClient:      "unauthorized_client",
+	domain.ErrInvalidScope:            "invalid_scope",
+	domain.ErrUnsupportedGrantType:    "unsupported_grant_type",
+	domain.ErrUnsupportedResponseType: "unsupported_response_type",
+	domain.ErrMFARequired:             oauthAccessDenied,
+	domain.ErrUserDisabled:            oauthAccessDenied,
+	domain.ErrTooManyAttempts:         oauthUnavailable,
+	errRateLimitExceeded:              oauthUnavailable,
+}
+
 //ErrorResponse is an error response.
 type ErrorResponse struct {
 	Status    int    `json:"status"`
 	Message   string `json:"message"`
 	RequestID string `json:"requestID"`
 }
+
+//OAuthErrorResponse is an OAuth 2.0 error response (RFC 6749, section 5.2).
+type OAuthErrorResponse struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
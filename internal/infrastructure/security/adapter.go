@@ -1,22 +1,40 @@
 package security
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/go-redis/redis"
+	"github.com/google/uuid"
 	"github.com/lzakharov/goss/internal/domain"
 	"go.uber.org/zap"
 )
 
+var errTokenRevoked = errors.New("token is revoked")
+
+//replaceSessionScript replaces the session with the rotated one only if it is unchanged since it was read.
+var replaceSessionScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+return 1
+`)
+
 //NewAdapter creates a new security adapter.
-func NewAdapter(logger *zap.Logger, config *Config, redisClient RedisClient) domain.Security {
+func NewAdapter(logger *zap.Logger, config *Config, redisClient RedisClient, keyring Keyring) domain.Security {
 	adapter := &adapter{
 		logger:      logger,
 		config:      config,
 		redisClient: redisClient,
+		keyring:     keyring,
 	}
 
 	return adapter
@@ -26,6 +44,24 @@ type adapter struct {
 	logger      *zap.Logger
 	config      *Config
 	redisClient RedisClient
+	keyring     Keyring
+}
+
+type sessionData struct {
+	domain.Session
+	Scope        string `json:"scope,omitempty"`
+	ClientID     string `json:"clientID,omitempty"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+//tokenClaims contains claims common to access and refresh tokens.
+type tokenClaims struct {
+	Type      string `json:"typ"`
+	UserID    int64  `json:"userID"`
+	SessionID string `json:"sid"`
+	ClientID  string `json:"clientID"`
+	jwt.StandardClaims
 }
 
 //IsAlive returns true if the adapter can ping redis.
@@ -33,49 +69,227 @@ func (a *adapter) IsAlive() bool {
 	return a.redisClient.Ping().Err() == nil
 }
 
-//CreateAuthData generates auth data for the specified user.
-func (a *adapter) CreateAuthData(user *domain.User) (*domain.AuthData, error) {
+//CreateAuthData starts a new session and generates auth data for the specified user.
+//Access tokens of the session are limited to the scopes, only the "*" scope of direct logins leaves them limited by the user's role alone.
+//The session remembers the client it is started for, so only that client can revoke its tokens.
+func (a *adapter) CreateAuthData(user *domain.User, clientID string, scopes []string) (*domain.AuthData, error) {
+	u, err := uuid.NewRandom()
+	if err != nil {
+		a.logger.Error("Error generating a session id!",
+			zap.Int64("userID", user.ID),
+			zap.Error(err))
+		return nil, domain.ErrInternalSecurity
+	}
+
 	now := time.Now()
 
-	accessToken, err := a.newAccessToken(now, user)
+	session := &sessionData{
+		Session: domain.Session{
+			ID:        u.String(),
+			CreatedAt: now.Unix(),
+		},
+		Scope:    strings.Join(scopes, " "),
+		ClientID: clientID,
+	}
+
+	return a.saveSession(now, user, session, "")
+}
+
+//RefreshAuthData rotates tokens of the existing user's session.
+//Each session is a refresh token family: only its latest refresh token is valid.
+//Rotated access tokens keep the scopes granted when the session started.
+//The session is replaced only if the refresh token is still its latest one, so of concurrent refreshes with the same token
+//only one rotates the session and the others get ErrRefreshTokenReused.
+func (a *adapter) RefreshAuthData(user *domain.User, sessionID, refreshToken string) (*domain.AuthData, error) {
+	session, stored, err := a.getStoredSession(user.ID, sessionID)
 	if err != nil {
-		a.logger.Error("Error creating a new access token!",
+		if err == redis.Nil {
+			return nil, domain.ErrInvalidRefreshToken
+		}
+		return nil, domain.ErrInternalSecurity
+	}
+
+	if session.RefreshToken != refreshToken {
+		a.logger.Warn("Refresh token rotated by a concurrent refresh!",
 			zap.Int64("userID", user.ID),
+			zap.String("sessionID", sessionID))
+		return nil, domain.ErrRefreshTokenReused
+	}
+
+	if err := a.revokeSessionToken(session.AccessToken, domain.TokenTypeAccessToken); err != nil {
+		return nil, err
+	}
+
+	return a.saveSession(time.Now(), user, session, stored)
+}
+
+//CreateClientAuthData generates auth data for the OAuth 2.0 client with the granted scopes.
+//Client tokens are not bound to a session and are valid until they expire.
+func (a *adapter) CreateClientAuthData(client *domain.Client, scopes []string) (*domain.AuthData, error) {
+	tokenID, err := uuid.NewRandom()
+	if err != nil {
+		a.logger.Error("Error generating a client access token id!",
+			zap.String("clientID", client.ClientID),
 			zap.Error(err))
 		return nil, domain.ErrInternalSecurity
 	}
 
-	refreshToken, err := a.newRefreshToken(now, user.ID)
+	now := time.Now()
+
+	accessToken, err := a.newClientAccessToken(now, client, scopes, tokenID.String())
 	if err != nil {
-		a.logger.Error("Error creating a new refresh token!",
-			zap.Int64("userID", user.ID),
+		a.logger.Error("Error creating a new client access token!",
+			zap.String("clientID", client.ClientID),
 			zap.Error(err))
 		return nil, domain.ErrInternalSecurity
 	}
 
 	authData := &domain.AuthData{
-		AccessToken:  accessToken,
-		ExpiresAt:    now.Add(a.config.AccessTokenLifetime).Unix(),
-		RefreshToken: refreshToken,
+		AccessToken: accessToken,
+		ExpiresAt:   now.Add(a.config.ClientTokenLifetime).Unix(),
 	}
 
-	key := a.newKey(user.ID)
-	value, err := json.Marshal(authData)
+	return authData, nil
+}
+
+//CreateAuthorizationCode generates a random authorization code and stores the grant bound to it.
+func (a *adapter) CreateAuthorizationCode(code *domain.AuthorizationCode) (string, error) {
+	value, err := newRandomToken()
 	if err != nil {
-		a.logger.Error("Error saving user's auth data!",
-			zap.Int64("userID", user.ID),
+		a.logger.Error("Error generating an authorization code!",
+			zap.String("clientID", code.ClientID),
+			zap.Int64("userID", code.UserID),
+			zap.Error(err))
+		return "", domain.ErrInternalSecurity
+	}
+
+	data, err := json.Marshal(code)
+	if err != nil {
+		a.logger.Error("Error saving an authorization code!",
+			zap.String("clientID", code.ClientID),
+			zap.Error(err))
+		return "", domain.ErrInternalSecurity
+	}
+
+	key := a.newAuthorizationCodeKey(value)
+
+	if err := a.redisClient.Set(key, data, a.config.AuthorizationCodeLifetime).Err(); err != nil {
+		a.logger.Error("Error saving an authorization code!",
+			zap.String("clientID", code.ClientID),
 			zap.Error(err))
+		return "", domain.ErrInternalSecurity
+	}
+
+	return value, nil
+}
+
+//ConsumeAuthorizationCode gets the grant bound to the authorization code and deletes the code.
+//A code can be consumed only once, concurrent attempts fail except for the one deleting it.
+func (a *adapter) ConsumeAuthorizationCode(code string) (*domain.AuthorizationCode, error) {
+	key := a.newAuthorizationCodeKey(code)
+
+	data, err := a.redisClient.Get(key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, domain.ErrInvalidGrant
+		}
+		a.logger.Error("Error getting an authorization code!", zap.Error(err))
+		return nil, domain.ErrInternalSecurity
+	}
+
+	deleted, err := a.redisClient.Del(key).Result()
+	if err != nil {
+		a.logger.Error("Error deleting an authorization code!", zap.Error(err))
+		return nil, domain.ErrInternalSecurity
+	}
+	if deleted == 0 {
+		return nil, domain.ErrInvalidGrant
+	}
+
+	authorizationCode := new(domain.AuthorizationCode)
+
+	if err := json.Unmarshal([]byte(data), authorizationCode); err != nil {
+		a.logger.Error("Error getting an authorization code!", zap.Error(err))
 		return nil, domain.ErrInternalSecurity
 	}
 
-	if err := a.redisClient.Set(key, value, a.config.RefreshTokenLifetime).Err(); err != nil {
-		a.logger.Error("Error saving user's auth data!",
+	return authorizationCode, nil
+}
+
+//CreateIDToken signs OpenID Connect ID token claims with the current signing key.
+//The HS256 secret is refused, as clients can't verify ID tokens without the secret.
+func (a *adapter) CreateIDToken(claims *domain.IDTokenClaims) (string, error) {
+	if a.keyring.signingKey().method.Alg() == algorithmHS256 {
+		a.logger.Error("Error signing an ID token with the HS256 secret!", zap.String("subject", claims.Subject))
+		return "", domain.ErrInternalSecurity
+	}
+
+	tokenID, err := uuid.NewRandom()
+	if err != nil {
+		a.logger.Error("Error generating an ID token id!",
+			zap.String("subject", claims.Subject),
+			zap.Error(err))
+		return "", domain.ErrInternalSecurity
+	}
+	claims.Id = tokenID.String()
+	claims.Type = tokenTypeID
+
+	idToken, err := a.signToken(claims)
+	if err != nil {
+		a.logger.Error("Error signing an ID token!",
+			zap.String("subject", claims.Subject),
+			zap.Error(err))
+		return "", domain.ErrInternalSecurity
+	}
+
+	return idToken, nil
+}
+
+//CreateMFAToken generates a short-lived token identifying the user between the login steps.
+func (a *adapter) CreateMFAToken(user *domain.User) (string, error) {
+	mfaToken, err := newRandomToken()
+	if err != nil {
+		a.logger.Error("Error generating an MFA token!",
 			zap.Int64("userID", user.ID),
 			zap.Error(err))
-		return nil, domain.ErrInternalSecurity
+		return "", domain.ErrInternalSecurity
 	}
 
-	return authData, nil
+	key := a.newMFATokenKey(mfaToken)
+
+	if err := a.redisClient.Set(key, user.ID, a.config.MFATokenLifetime).Err(); err != nil {
+		a.logger.Error("Error saving an MFA token!",
+			zap.Int64("userID", user.ID),
+			zap.Error(err))
+		return "", domain.ErrInternalSecurity
+	}
+
+	return mfaToken, nil
+}
+
+//ConsumeMFAToken gets the user id bound to the MFA token and deletes the token.
+func (a *adapter) ConsumeMFAToken(mfaToken string) (int64, error) {
+	key := a.newMFATokenKey(mfaToken)
+
+	userID, err := a.redisClient.Get(key).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, domain.ErrInvalidMFAToken
+		}
+		a.logger.Error("Error getting an MFA token!", zap.Error(err))
+		return 0, domain.ErrInternalSecurity
+	}
+
+	deleted, err := a.redisClient.Del(key).Result()
+	if err != nil {
+		a.logger.Error("Error deleting an MFA token!", zap.Error(err))
+		return 0, domain.ErrInternalSecurity
+	}
+	if deleted == 0 {
+		return 0, domain.ErrInvalidMFAToken
+	}
+
+	return userID, nil
 }
 
 //GetAccessTokenClaims gets access token claims.
@@ -96,37 +310,46 @@ func (a *adapter) GetAccessTokenClaims(accessToken string) (*domain.AccessTokenC
 		return nil, domain.ErrInvalidAccessToken
 	}
 
-	key := a.newKey(claims.UserID)
-	data, err := a.redisClient.Get(key).Result()
-	if err != nil {
-		a.logger.Error("Error getting access token!",
-			zap.String("key", key),
-			zap.Error(err))
-
-		if err == redis.Nil {
+	if err := a.checkRevoked(claims.Id); err != nil {
+		if err == errTokenRevoked {
 			return nil, domain.ErrInvalidAccessToken
 		}
 		return nil, domain.ErrInternalSecurity
 	}
 
-	authData := new(domain.AuthData)
+	//Tokens of ended sessions and rotated tokens are revoked, so the session is checked only for tokens issued without type:
+	//client access tokens are the only ones with the client id, user tokens must be the session's access token.
+	switch {
+	case claims.Type == tokenTypeAccess:
+		return claims, nil
+	case claims.Type != "":
+		a.logger.Warn("Token of another type used as an access token!",
+			zap.String("type", claims.Type),
+			zap.String("tokenID", claims.Id))
+		return nil, domain.ErrInvalidAccessToken
+	case claims.ClientID != "":
+		return claims, nil
+	}
 
-	if err := json.Unmarshal([]byte(data), &authData); err != nil {
-		a.logger.Error("Error getting access token!",
-			zap.String("key", key),
-			zap.Error(err))
+	session, err := a.getSession(claims.UserID, claims.SessionID)
+	if err != nil {
+		if err == redis.Nil {
+			return nil, domain.ErrInvalidAccessToken
+		}
 		return nil, domain.ErrInternalSecurity
 	}
 
-	if accessToken != authData.AccessToken {
-		a.logger.Warn("Transferred token does not match stored!", zap.String("key", key))
+	if accessToken != session.AccessToken {
+		a.logger.Warn("Transferred token does not match stored!",
+			zap.Int64("userID", claims.UserID),
+			zap.String("sessionID", claims.SessionID))
 		return nil, domain.ErrInvalidAccessToken
 	}
 
 	return claims, nil
 }
 
-//GetAccessTokenClaims gets refresh token claims.
+//GetRefreshTokenClaims gets refresh token claims.
 func (a *adapter) GetRefreshTokenClaims(refreshToken string) (*domain.RefreshTokenClaims, error) {
 	claims := new(domain.RefreshTokenClaims)
 
@@ -144,90 +367,533 @@ func (a *adapter) GetRefreshTokenClaims(refreshToken string) (*domain.RefreshTok
 		return nil, domain.ErrInvalidRefreshToken
 	}
 
-	key := a.newKey(claims.UserID)
-	data, err := a.redisClient.Get(key).Result()
-	if err != nil {
-		a.logger.Error("Error getting refresh token!",
-			zap.String("key", key),
-			zap.Error(err))
+	//Tokens issued without type are told apart by the session below.
+	if claims.Type != "" && claims.Type != tokenTypeRefresh {
+		a.logger.Warn("Token of another type used as a refresh token!",
+			zap.String("type", claims.Type),
+			zap.String("tokenID", claims.Id))
+		return nil, domain.ErrInvalidRefreshToken
+	}
+
+	if err := a.checkRevoked(claims.Id); err != nil {
+		if err == errTokenRevoked {
+			return nil, domain.ErrInvalidRefreshToken
+		}
+		return nil, domain.ErrInternalSecurity
+	}
 
+	session, err := a.getSession(claims.UserID, claims.SessionID)
+	if err != nil {
 		if err == redis.Nil {
 			return nil, domain.ErrInvalidRefreshToken
 		}
 		return nil, domain.ErrInternalSecurity
 	}
 
-	authData := new(domain.AuthData)
+	if refreshToken != session.RefreshToken {
+		a.logger.Error("Security incident: an already rotated refresh token reused, revoking the session!",
+			zap.Int64("userID", claims.UserID),
+			zap.String("sessionID", claims.SessionID),
+			zap.String("tokenID", claims.Id))
 
-	if err := json.Unmarshal([]byte(data), &authData); err != nil {
-		a.logger.Error("Error getting refresh token!",
+		if err := a.deleteSession(claims.UserID, session); err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrRefreshTokenReused
+	}
+
+	return claims, nil
+}
+
+//IdentifyToken identifies the access or refresh token.
+//User tokens are identified by the session, so tokens of ended sessions and rotated tokens are unknown.
+func (a *adapter) IdentifyToken(token string) (*domain.TokenInfo, error) {
+	claims := new(tokenClaims)
+
+	parsed, err := jwt.ParseWithClaims(token, claims, a.jwtKeyFunc)
+	if err != nil || !parsed.Valid || claims.Id == "" {
+		return nil, domain.ErrNotFound
+	}
+
+	if claims.Type != "" && claims.Type != tokenTypeAccess && claims.Type != tokenTypeRefresh {
+		return nil, domain.ErrNotFound
+	}
+
+	if err := a.checkRevoked(claims.Id); err != nil {
+		if err == errTokenRevoked {
+			return nil, domain.ErrNotFound
+		}
+		return nil, domain.ErrInternalSecurity
+	}
+
+	info := &domain.TokenInfo{
+		ID:        claims.Id,
+		Type:      domain.TokenTypeAccessToken,
+		UserID:    claims.UserID,
+		ClientID:  claims.ClientID,
+		ExpiresAt: claims.ExpiresAt,
+	}
+
+	if claims.ClientID != "" {
+		return info, nil
+	}
+
+	session, err := a.getSession(claims.UserID, claims.SessionID)
+	if err != nil {
+		if err == redis.Nil {
+			return nil, domain.ErrNotFound
+		}
+		return nil, domain.ErrInternalSecurity
+	}
+
+	info.ClientID = session.ClientID
+
+	switch token {
+	case session.AccessToken:
+		return info, nil
+	case session.RefreshToken:
+		info.Type = domain.TokenTypeRefreshToken
+		return info, nil
+	default:
+		return nil, domain.ErrNotFound
+	}
+}
+
+//RevokeToken adds the token id to the revoked ones until the token expires.
+func (a *adapter) RevokeToken(info *domain.TokenInfo) error {
+	ttl := time.Unix(info.ExpiresAt, 0).Sub(time.Now())
+	if ttl <= 0 {
+		return nil
+	}
+
+	key := a.newRevokedTokenKey(info.ID)
+
+	if err := a.redisClient.Set(key, info.Type, ttl).Err(); err != nil {
+		a.logger.Error("Error revoking a token!",
 			zap.String("key", key),
 			zap.Error(err))
+		return domain.ErrInternalSecurity
+	}
+
+	return nil
+}
+
+//GetSessions gets user's active sessions sorted by creation time.
+func (a *adapter) GetSessions(userID int64) ([]*domain.Session, error) {
+	sessionsKey := a.newSessionsKey(userID)
+
+	sessionIDs, err := a.redisClient.SMembers(sessionsKey).Result()
+	if err != nil {
+		a.logger.Error("Error getting user's session ids!",
+			zap.String("key", sessionsKey),
+			zap.Error(err))
 		return nil, domain.ErrInternalSecurity
 	}
 
-	if refreshToken != authData.RefreshToken {
-		a.logger.Warn("Transferred token does not match stored!", zap.String("key", key))
-		return nil, domain.ErrInvalidRefreshToken
+	sessions := make([]*domain.Session, 0, len(sessionIDs))
+
+	for _, sessionID := range sessionIDs {
+		session, err := a.getSession(userID, sessionID)
+		if err == redis.Nil {
+			a.forgetSession(userID, sessionID)
+			continue
+		}
+		if err != nil {
+			return nil, domain.ErrInternalSecurity
+		}
+
+		sessions = append(sessions, &session.Session)
 	}
 
-	return claims, nil
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt < sessions[j].CreatedAt
+	})
+
+	return sessions, nil
 }
 
-//InvalidateUserAuthData invalidates user's auth data.
+//InvalidateSession invalidates the user's session and revokes its tokens.
+func (a *adapter) InvalidateSession(userID int64, sessionID string) error {
+	session, err := a.getSession(userID, sessionID)
+	if err != nil {
+		if err == redis.Nil {
+			a.forgetSession(userID, sessionID)
+			return domain.ErrNotFound
+		}
+		return domain.ErrInternalSecurity
+	}
+
+	return a.deleteSession(userID, session)
+}
+
+//InvalidateUserAuthData invalidates all user's sessions.
 func (a *adapter) InvalidateUserAuthData(userID int64) error {
-	key := a.newKey(userID)
-	if _, err := a.redisClient.Del(key).Result(); err != nil {
+	sessionsKey := a.newSessionsKey(userID)
+
+	sessionIDs, err := a.redisClient.SMembers(sessionsKey).Result()
+	if err != nil {
+		a.logger.Error("Error getting user's session ids!",
+			zap.String("key", sessionsKey),
+			zap.Error(err))
+		return domain.ErrInternalSecurity
+	}
+
+	keys := make([]string, 0, len(sessionIDs)+1)
+	for _, sessionID := range sessionIDs {
+		session, err := a.getSession(userID, sessionID)
+		if err != nil && err != redis.Nil {
+			return domain.ErrInternalSecurity
+		}
+		if session != nil {
+			if err := a.revokeSessionTokens(session); err != nil {
+				return err
+			}
+		}
+
+		keys = append(keys, a.newSessionKey(userID, sessionID))
+	}
+	keys = append(keys, sessionsKey)
+
+	if _, err := a.redisClient.Del(keys...).Result(); err != nil {
 		a.logger.Error("Error deleting user's auth data!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return domain.ErrInternalSecurity
+	}
+
+	return nil
+}
+
+//GetJSONWebKeySet returns public keys for token verification.
+func (a *adapter) GetJSONWebKeySet() *domain.JSONWebKeySet {
+	keys := a.keyring.publicKeys()
+
+	set := &domain.JSONWebKeySet{
+		Keys: make([]*domain.JSONWebKey, 0, len(keys)),
+	}
+	for _, key := range keys {
+		set.Keys = append(set.Keys, newJSONWebKey(key))
+	}
+
+	return set
+}
+
+//GetSigningAlgorithm returns the algorithm of the current signing key.
+func (a *adapter) GetSigningAlgorithm() string {
+	return a.keyring.signingKey().method.Alg()
+}
+
+//saveSession rotates tokens of the session and saves it.
+//A new session is saved unconditionally, while a stored one is replaced only if it's still the previous value.
+func (a *adapter) saveSession(now time.Time, user *domain.User, session *sessionData, previous string) (*domain.AuthData, error) {
+	accessTokenID, err := uuid.NewRandom()
+	if err != nil {
+		a.logger.Error("Error generating an access token id!",
+			zap.Int64("userID", user.ID),
+			zap.Error(err))
+		return nil, domain.ErrInternalSecurity
+	}
+
+	accessToken, err := a.newAccessToken(now, user, session, accessTokenID.String())
+	if err != nil {
+		a.logger.Error("Error creating a new access token!",
+			zap.Int64("userID", user.ID),
+			zap.Error(err))
+		return nil, domain.ErrInternalSecurity
+	}
+
+	tokenID, err := uuid.NewRandom()
+	if err != nil {
+		a.logger.Error("Error generating a refresh token id!",
+			zap.Int64("userID", user.ID),
+			zap.Error(err))
+		return nil, domain.ErrInternalSecurity
+	}
+
+	refreshToken, err := a.newRefreshToken(now, user.ID, session.ID, tokenID.String())
+	if err != nil {
+		a.logger.Error("Error creating a new refresh token!",
+			zap.Int64("userID", user.ID),
+			zap.Error(err))
+		return nil, domain.ErrInternalSecurity
+	}
+
+	session.RefreshedAt = now.Unix()
+	session.ExpiresAt = now.Add(a.config.RefreshTokenLifetime).Unix()
+	session.AccessToken = accessToken
+	session.RefreshToken = refreshToken
+
+	key := a.newSessionKey(user.ID, session.ID)
+	value, err := json.Marshal(session)
+	if err != nil {
+		a.logger.Error("Error saving user's session!",
+			zap.String("key", key),
+			zap.Error(err))
+		return nil, domain.ErrInternalSecurity
+	}
+
+	if previous == "" {
+		if err := a.redisClient.Set(key, value, a.config.RefreshTokenLifetime).Err(); err != nil {
+			a.logger.Error("Error saving user's session!",
+				zap.String("key", key),
+				zap.Error(err))
+			return nil, domain.ErrInternalSecurity
+		}
+	} else {
+		replaced, err := replaceSessionScript.Run(a.redisClient, []string{key},
+			previous, value, int64(a.config.RefreshTokenLifetime/time.Millisecond)).Int64()
+		if err != nil {
+			a.logger.Error("Error replacing user's session!",
+				zap.String("key", key),
+				zap.Error(err))
+			return nil, domain.ErrInternalSecurity
+		}
+		if replaced == 0 {
+			a.logger.Warn("User's session rotated by a concurrent refresh!", zap.String("key", key))
+			return nil, domain.ErrRefreshTokenReused
+		}
+	}
+
+	if err := a.saveSessionID(user.ID, session.ID); err != nil {
+		return nil, err
+	}
+
+	authData := &domain.AuthData{
+		AccessToken:  accessToken,
+		ExpiresAt:    now.Add(a.config.AccessTokenLifetime).Unix(),
+		RefreshToken: refreshToken,
+	}
+
+	return authData, nil
+}
+
+//saveSessionID adds the session id to the user's session ids and extends their expiration on every rotation,
+//so refreshed sessions outliving the first refresh token lifetime are still found by the user-wide invalidation.
+func (a *adapter) saveSessionID(userID int64, sessionID string) error {
+	sessionsKey := a.newSessionsKey(userID)
+
+	if err := a.redisClient.SAdd(sessionsKey, sessionID).Err(); err != nil {
+		a.logger.Error("Error saving user's session id!",
+			zap.String("key", sessionsKey),
+			zap.Error(err))
+		return domain.ErrInternalSecurity
+	}
+
+	if err := a.redisClient.Expire(sessionsKey, a.config.RefreshTokenLifetime).Err(); err != nil {
+		a.logger.Error("Error updating user's session ids expiration!",
+			zap.String("key", sessionsKey),
+			zap.Error(err))
+		return domain.ErrInternalSecurity
+	}
+
+	return nil
+}
+
+func (a *adapter) getSession(userID int64, sessionID string) (*sessionData, error) {
+	session, _, err := a.getStoredSession(userID, sessionID)
+	return session, err
+}
+
+//getStoredSession returns the session and its stored value, so it can be replaced only if unchanged.
+func (a *adapter) getStoredSession(userID int64, sessionID string) (*sessionData, string, error) {
+	key := a.newSessionKey(userID, sessionID)
+
+	data, err := a.redisClient.Get(key).Result()
+	if err != nil {
+		a.logger.Error("Error getting user's session!",
+			zap.String("key", key),
+			zap.Error(err))
+		return nil, "", err
+	}
+
+	session := new(sessionData)
+
+	if err := json.Unmarshal([]byte(data), session); err != nil {
+		a.logger.Error("Error getting user's session!",
+			zap.String("key", key),
+			zap.Error(err))
+		return nil, "", err
+	}
+
+	return session, data, nil
+}
+
+//checkRevoked returns errTokenRevoked if the token id is revoked, tokens without id can't be revoked.
+func (a *adapter) checkRevoked(tokenID string) error {
+	if tokenID == "" {
+		return nil
+	}
+
+	key := a.newRevokedTokenKey(tokenID)
+
+	revoked, err := a.redisClient.Exists(key).Result()
+	if err != nil {
+		a.logger.Error("Error checking a revoked token!",
+			zap.String("key", key),
+			zap.Error(err))
+		return err
+	}
+	if revoked > 0 {
+		return errTokenRevoked
+	}
+
+	return nil
+}
+
+//deleteSession revokes the session tokens and deletes the session.
+func (a *adapter) deleteSession(userID int64, session *sessionData) error {
+	if err := a.revokeSessionTokens(session); err != nil {
+		return err
+	}
+
+	key := a.newSessionKey(userID, session.ID)
+
+	if err := a.redisClient.Del(key).Err(); err != nil {
+		a.logger.Error("Error deleting user's session!",
 			zap.String("key", key),
 			zap.Error(err))
 		return domain.ErrInternalSecurity
 	}
 
+	a.forgetSession(userID, session.ID)
+
 	return nil
 }
 
-func (a *adapter) newAccessToken(now time.Time, user *domain.User) (string, error) {
+func (a *adapter) revokeSessionTokens(session *sessionData) error {
+	if err := a.revokeSessionToken(session.AccessToken, domain.TokenTypeAccessToken); err != nil {
+		return err
+	}
+	return a.revokeSessionToken(session.RefreshToken, domain.TokenTypeRefreshToken)
+}
+
+//revokeSessionToken revokes the token stored in the session, tokens issued without id are left to the session check.
+func (a *adapter) revokeSessionToken(token, tokenType string) error {
+	claims := new(tokenClaims)
+
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+		a.logger.Error("Error parsing a session token!",
+			zap.String("tokenType", tokenType),
+			zap.Error(err))
+		return domain.ErrInternalSecurity
+	}
+	if claims.Id == "" {
+		return nil
+	}
+
+	return a.RevokeToken(&domain.TokenInfo{
+		ID:        claims.Id,
+		Type:      tokenType,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt,
+	})
+}
+
+func (a *adapter) forgetSession(userID int64, sessionID string) {
+	sessionsKey := a.newSessionsKey(userID)
+
+	if err := a.redisClient.SRem(sessionsKey, sessionID).Err(); err != nil {
+		a.logger.Warn("Error deleting user's session id!",
+			zap.String("key", sessionsKey),
+			zap.String("sessionID", sessionID),
+			zap.Error(err))
+	}
+}
+
+func (a *adapter) newAccessToken(now time.Time, user *domain.User, session *sessionData, tokenID string) (string, error) {
 	claims := &domain.AccessTokenClaims{
-		UserID: user.ID,
-		Role:   user.Role,
+		Type:      tokenTypeAccess,
+		UserID:    user.ID,
+		Role:      user.Role,
+		SessionID: session.ID,
+		Scope:     session.Scope,
 		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID,
 			ExpiresAt: now.Add(a.config.AccessTokenLifetime).Unix(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return a.signToken(claims)
+}
 
-	accessToken, err := token.SignedString(a.config.Secret)
-	if err != nil {
-		return "", err
+func (a *adapter) newClientAccessToken(now time.Time, client *domain.Client, scopes []string, tokenID string) (string, error) {
+	claims := &domain.AccessTokenClaims{
+		Type:     tokenTypeAccess,
+		ClientID: client.ClientID,
+		Scope:    strings.Join(scopes, " "),
+		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID,
+			Subject:   client.ClientID,
+			ExpiresAt: now.Add(a.config.ClientTokenLifetime).Unix(),
+		},
 	}
 
-	return accessToken, nil
+	return a.signToken(claims)
 }
 
-func (a *adapter) newRefreshToken(now time.Time, userID int64) (string, error) {
+func (a *adapter) newRefreshToken(now time.Time, userID int64, sessionID, tokenID string) (string, error) {
 	claims := &domain.RefreshTokenClaims{
-		UserID: userID,
+		Type:      tokenTypeRefresh,
+		UserID:    userID,
+		SessionID: sessionID,
 		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID,
 			ExpiresAt: now.Add(a.config.RefreshTokenLifetime).Unix(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return a.signToken(claims)
+}
 
-	refreshToken, err := token.SignedString(a.config.Secret)
-	if err != nil {
-		return "", err
-	}
+func (a *adapter) newSessionKey(userID int64, sessionID string) string {
+	return fmt.Sprintf(sessionKeyFormat, a.config.KeyPrefix, userID, sessionID)
+}
 
-	return refreshToken, nil
+func (a *adapter) newSessionsKey(userID int64) string {
+	return fmt.Sprintf(sessionsKeyFormat, a.config.KeyPrefix, userID)
 }
 
-func (a *adapter) newKey(userID int64) string {
-	return fmt.Sprintf(keyFormat, a.config.KeyPrefix, userID)
+func (a *adapter) newAuthorizationCodeKey(code string) string {
+	return fmt.Sprintf(authorizationCodeKeyFormat, a.config.KeyPrefix, code)
+}
+
+func (a *adapter) newMFATokenKey(mfaToken string) string {
+	return fmt.Sprintf(mfaTokenKeyFormat, a.config.KeyPrefix, mfaToken)
+}
+
+func (a *adapter) newRevokedTokenKey(tokenID string) string {
+	return fmt.Sprintf(revokedTokenKeyFormat, a.config.KeyPrefix, tokenID)
+}
+
+func (a *adapter) signToken(claims jwt.Claims) (string, error) {
+	key := a.keyring.signingKey()
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header[keyIDHeader] = key.id
+
+	return token.SignedString(key.private)
 }
 
 func (a *adapter) jwtKeyFunc(token *jwt.Token) (interface{}, error) {
-	return a.config.Secret, nil
+	kid, _ := token.Header[keyIDHeader].(string)
+
+	key, ok := a.keyring.verificationKey(kid)
+	if !ok {
+		return nil, errUnknownKey
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errAlgorithmMismatch
+	}
+
+	return key.public, nil
+}
+
+func newRandomToken() (string, error) {
+	buf := make([]byte, randomTokenLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
 }
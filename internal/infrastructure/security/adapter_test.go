@@ -1,8 +1,13 @@
 package security
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
@@ -10,6 +15,7 @@ import (
 	"github.com/dgrijalva/jwt-go"
 	"github.com/go-redis/redis"
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"github.com/lzakharov/goss/internal/domain"
 	"go.uber.org/zap"
@@ -18,13 +24,35 @@ import (
 var (
 	timePoint = time.Now()
 
+	sessionID         = uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	tokenID           = uuid.MustParse("6ba7b811-9dad-11d1-80b4-00c04fd430c8")
+	accessTokenID     = uuid.MustParse("6ba7b812-9dad-11d1-80b4-00c04fd430c8")
+	clientTokenID     = uuid.MustParse("6ba7b814-9dad-11d1-80b4-00c04fd430c8")
+	idTokenID         = uuid.MustParse("6ba7b815-9dad-11d1-80b4-00c04fd430c8")
+	accessRevokedKey  = "authrevoked:" + accessTokenID.String()
+	refreshRevokedKey = "authrevoked:" + tokenID.String()
+	clientRevokedKey  = "authrevoked:" + clientTokenID.String()
+
 	config = &Config{
-		KeyPrefix:            "auth",
-		Secret:               []byte("secret"),
-		AccessTokenLifetime:  24 * time.Hour,
-		RefreshTokenLifetime: 30 * 24 * time.Hour,
+		KeyPrefix:                 "auth",
+		Secret:                    []byte("secret"),
+		SigningAlgorithm:          "HS256",
+		AccessTokenLifetime:       24 * time.Hour,
+		RefreshTokenLifetime:      30 * 24 * time.Hour,
+		ClientTokenLifetime:       time.Hour,
+		AuthorizationCodeLifetime: time.Minute,
+		MFATokenLifetime:          5 * time.Minute,
+		Lockout: &LockoutConfig{
+			MaxAttempts:      3,
+			MaxAttemptsPerIP: 10,
+			Window:           15 * time.Minute,
+			Duration:         time.Minute,
+			MaxDuration:      10 * time.Minute,
+		},
 	}
 
+	testKeyring, _ = NewKeyring(zap.NewNop(), config)
+
 	alice = &domain.User{
 		ID:       42,
 		Username: "alice",
@@ -32,33 +60,99 @@ var (
 	}
 
 	aliceAccessTokenClaims = &domain.AccessTokenClaims{
-		UserID: alice.ID,
-		Role:   alice.Role,
+		Type:      tokenTypeAccess,
+		UserID:    alice.ID,
+		Role:      alice.Role,
+		SessionID: sessionID.String(),
 		StandardClaims: jwt.StandardClaims{
+			Id:        accessTokenID.String(),
 			ExpiresAt: timePoint.Add(config.AccessTokenLifetime).Unix(),
 		},
 	}
 
 	aliceRefreshTokenClaims = &domain.RefreshTokenClaims{
-		UserID: alice.ID,
+		Type:      tokenTypeRefresh,
+		UserID:    alice.ID,
+		SessionID: sessionID.String(),
 		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID.String(),
 			ExpiresAt: timePoint.Add(config.RefreshTokenLifetime).Unix(),
 		},
 	}
 
+	backend = &domain.Client{
+		ID:       1,
+		ClientID: "backend",
+		Scopes:   []string{"users:read", "users:write"},
+	}
+
+	backendAccessTokenClaims = &domain.AccessTokenClaims{
+		Type:     tokenTypeAccess,
+		ClientID: backend.ClientID,
+		Scope:    "users:read",
+		StandardClaims: jwt.StandardClaims{
+			Id:        clientTokenID.String(),
+			Subject:   backend.ClientID,
+			ExpiresAt: timePoint.Add(config.ClientTokenLifetime).Unix(),
+		},
+	}
+
+	backendAccessToken string
+
+	aliceAuthorizationCode = &domain.AuthorizationCode{
+		ClientID:      "spa",
+		UserID:        alice.ID,
+		RedirectURI:   "https://app.example.com/callback",
+		Scopes:        []string{"users:read"},
+		CodeChallenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+	}
+
 	aliceAccessToken  string
 	aliceRefreshToken string
 	aliceAuthData     *domain.AuthData
+	aliceSession      *sessionData
+	aliceSessionKey   = "auth42:" + sessionID.String()
+	aliceSessionsKey  = "authsessions:42"
 )
 
 func init() {
-	aliceAccessToken, _ = jwt.NewWithClaims(jwt.SigningMethodHS256, aliceAccessTokenClaims).SignedString(config.Secret)
-	aliceRefreshToken, _ = jwt.NewWithClaims(jwt.SigningMethodHS256, aliceRefreshTokenClaims).SignedString(config.Secret)
+	aliceAccessToken = newTestToken(aliceAccessTokenClaims)
+	aliceRefreshToken = newTestToken(aliceRefreshTokenClaims)
+	backendAccessToken = newTestToken(backendAccessTokenClaims)
 	aliceAuthData = &domain.AuthData{
 		AccessToken:  aliceAccessToken,
 		ExpiresAt:    timePoint.Add(config.AccessTokenLifetime).Unix(),
 		RefreshToken: aliceRefreshToken,
 	}
+	aliceSession = &sessionData{
+		Session: domain.Session{
+			ID:          sessionID.String(),
+			CreatedAt:   timePoint.Unix(),
+			RefreshedAt: timePoint.Unix(),
+			ExpiresAt:   timePoint.Add(config.RefreshTokenLifetime).Unix(),
+		},
+		AccessToken:  aliceAccessToken,
+		RefreshToken: aliceRefreshToken,
+	}
+}
+
+func newTestToken(claims jwt.Claims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = testKeyring.signingKey().id
+
+	signed, _ := token.SignedString(config.Secret)
+	return signed
+}
+
+func expectRevoked(redisClient *MockRedisClient, key, tokenType string, expiresAt int64) {
+	redisClient.EXPECT().
+		Set(key, tokenType, time.Unix(expiresAt, 0).Sub(timePoint)).
+		Return(redis.NewStatusResult("ok", nil))
+}
+
+func expectSessionRevoked(redisClient *MockRedisClient) {
+	expectRevoked(redisClient, accessRevokedKey, domain.TokenTypeAccessToken, aliceAccessTokenClaims.ExpiresAt)
+	expectRevoked(redisClient, refreshRevokedKey, domain.TokenTypeRefreshToken, aliceRefreshTokenClaims.ExpiresAt)
 }
 
 func TestNewAdapter(t *testing.T) {
@@ -70,9 +164,10 @@ func TestNewAdapter(t *testing.T) {
 		logger:      logger,
 		config:      config,
 		redisClient: redisClient,
+		keyring:     testKeyring,
 	}
 
-	actual := NewAdapter(logger, config, redisClient)
+	actual := NewAdapter(logger, config, redisClient, testKeyring)
 	require.Equal(t, expected, actual)
 }
 
@@ -81,8 +176,9 @@ func TestAdapter_IsAlive(t *testing.T) {
 	logger := zap.NewExample()
 
 	adapter := &adapter{
-		logger: logger,
-		config: config,
+		logger:  logger,
+		config:  config,
+		keyring: testKeyring,
 	}
 
 	t.Run("alive", func(t *testing.T) {
@@ -105,33 +201,216 @@ func TestAdapter_IsAlive(t *testing.T) {
 func TestAdapter_CreateAuthData(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
-	patch := monkey.Patch(time.Now, func() time.Time {
+	timePatch := monkey.Patch(time.Now, func() time.Time {
 		return timePoint
 	})
-	defer patch.Unpatch()
+	defer timePatch.Unpatch()
+
+	ids := []uuid.UUID{sessionID, accessTokenID, tokenID}
+	uuidPatch := monkey.Patch(uuid.NewRandom, func() (uuid.UUID, error) {
+		id := ids[0]
+		ids = ids[1:]
+		return id, nil
+	})
+	defer uuidPatch.Unpatch()
 
 	logger := zap.NewExample()
 
 	adapter := &adapter{
-		logger: logger,
-		config: config,
+		logger:  logger,
+		config:  config,
+		keyring: testKeyring,
 	}
 
 	t.Run("normal", func(t *testing.T) {
+		sessionJSON, err := json.Marshal(aliceSession)
+		require.NoError(t, err)
+
 		redisClient := NewMockRedisClient(ctrl)
 		redisClient.EXPECT().
-			Set("auth42", gomock.Any(), config.RefreshTokenLifetime).
+			Set(aliceSessionKey, sessionJSON, config.RefreshTokenLifetime).
 			Return(redis.NewStatusResult("ok", nil))
+		redisClient.EXPECT().
+			SAdd(aliceSessionsKey, sessionID.String()).
+			Return(redis.NewIntResult(1, nil))
+		redisClient.EXPECT().
+			Expire(aliceSessionsKey, config.RefreshTokenLifetime).
+			Return(redis.NewBoolResult(true, nil))
 
 		adapter.redisClient = redisClient
 
-		actual, err := adapter.CreateAuthData(alice)
+		actual, err := adapter.CreateAuthData(alice, "", nil)
 		require.NoError(t, err)
 		require.Equal(t, aliceAuthData, actual)
 	})
+
+	t.Run("for client with scopes", func(t *testing.T) {
+		ids = []uuid.UUID{sessionID, accessTokenID, tokenID}
+
+		scopedClaims := *aliceAccessTokenClaims
+		scopedClaims.Scope = "users:read openid"
+
+		scopedSession := *aliceSession
+		scopedSession.Scope = scopedClaims.Scope
+		scopedSession.ClientID = "spa"
+		scopedSession.AccessToken = newTestToken(&scopedClaims)
+		sessionJSON, err := json.Marshal(scopedSession)
+		require.NoError(t, err)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Set(aliceSessionKey, sessionJSON, config.RefreshTokenLifetime).
+			Return(redis.NewStatusResult("ok", nil))
+		redisClient.EXPECT().
+			SAdd(aliceSessionsKey, sessionID.String()).
+			Return(redis.NewIntResult(1, nil))
+		redisClient.EXPECT().
+			Expire(aliceSessionsKey, config.RefreshTokenLifetime).
+			Return(redis.NewBoolResult(true, nil))
+
+		adapter.redisClient = redisClient
+
+		actual, err := adapter.CreateAuthData(alice, "spa", []string{"users:read", "openid"})
+		require.NoError(t, err)
+		require.Equal(t, scopedSession.AccessToken, actual.AccessToken)
+	})
 }
 
-func TestAdapter_GetAccessTokenClaims(t *testing.T) {
+func TestAdapter_RefreshAuthData(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	timePatch := monkey.Patch(time.Now, func() time.Time {
+		return timePoint
+	})
+	defer timePatch.Unpatch()
+
+	var ids []uuid.UUID
+	uuidPatch := monkey.Patch(uuid.NewRandom, func() (uuid.UUID, error) {
+		id := ids[0]
+		ids = ids[1:]
+		return id, nil
+	})
+	defer uuidPatch.Unpatch()
+
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger:  logger,
+		config:  config,
+		keyring: testKeyring,
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		ids = []uuid.UUID{accessTokenID, tokenID}
+
+		sessionJSON, err := json.Marshal(aliceSession)
+		require.NoError(t, err)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult(string(sessionJSON), nil))
+		expectRevoked(redisClient, accessRevokedKey, domain.TokenTypeAccessToken, aliceAccessTokenClaims.ExpiresAt)
+		redisClient.EXPECT().
+			EvalSha(replaceSessionScript.Hash(), []string{aliceSessionKey},
+				string(sessionJSON), sessionJSON, int64(config.RefreshTokenLifetime/time.Millisecond)).
+			Return(redis.NewCmdResult(int64(1), nil))
+		redisClient.EXPECT().
+			SAdd(aliceSessionsKey, sessionID.String()).
+			Return(redis.NewIntResult(0, nil))
+		redisClient.EXPECT().
+			Expire(aliceSessionsKey, config.RefreshTokenLifetime).
+			Return(redis.NewBoolResult(true, nil))
+
+		adapter.redisClient = redisClient
+
+		actual, err := adapter.RefreshAuthData(alice, sessionID.String(), aliceRefreshToken)
+		require.NoError(t, err)
+		require.Equal(t, aliceAuthData, actual)
+	})
+
+	t.Run("rotated refresh token", func(t *testing.T) {
+		sessionJSON, err := json.Marshal(aliceSession)
+		require.NoError(t, err)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult(string(sessionJSON), nil))
+
+		adapter.redisClient = redisClient
+
+		_, err = adapter.RefreshAuthData(alice, sessionID.String(), "previousRefreshToken")
+		require.Equal(t, domain.ErrRefreshTokenReused, err)
+	})
+
+	t.Run("concurrent refresh", func(t *testing.T) {
+		ids = []uuid.UUID{accessTokenID, tokenID}
+
+		sessionJSON, err := json.Marshal(aliceSession)
+		require.NoError(t, err)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult(string(sessionJSON), nil))
+		expectRevoked(redisClient, accessRevokedKey, domain.TokenTypeAccessToken, aliceAccessTokenClaims.ExpiresAt)
+		redisClient.EXPECT().
+			EvalSha(replaceSessionScript.Hash(), []string{aliceSessionKey},
+				string(sessionJSON), sessionJSON, int64(config.RefreshTokenLifetime/time.Millisecond)).
+			Return(redis.NewCmdResult(int64(0), nil))
+
+		adapter.redisClient = redisClient
+
+		_, err = adapter.RefreshAuthData(alice, sessionID.String(), aliceRefreshToken)
+		require.Equal(t, domain.ErrRefreshTokenReused, err)
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult("", redis.Nil))
+
+		adapter.redisClient = redisClient
+
+		_, err := adapter.RefreshAuthData(alice, sessionID.String(), aliceRefreshToken)
+		require.Equal(t, domain.ErrInvalidRefreshToken, err)
+	})
+}
+
+func TestAdapter_CreateClientAuthData(t *testing.T) {
+	timePatch := monkey.Patch(time.Now, func() time.Time {
+		return timePoint
+	})
+	defer timePatch.Unpatch()
+
+	uuidPatch := monkey.Patch(uuid.NewRandom, func() (uuid.UUID, error) {
+		return clientTokenID, nil
+	})
+	defer uuidPatch.Unpatch()
+
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger:  logger,
+		config:  config,
+		keyring: testKeyring,
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		expected := &domain.AuthData{
+			AccessToken: backendAccessToken,
+			ExpiresAt:   timePoint.Add(config.ClientTokenLifetime).Unix(),
+		}
+
+		actual, err := adapter.CreateClientAuthData(backend, []string{"users:read"})
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+}
+
+func TestAdapter_CreateAuthorizationCode(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
 	logger := zap.NewExample()
@@ -142,13 +421,236 @@ func TestAdapter_GetAccessTokenClaims(t *testing.T) {
 	}
 
 	t.Run("normal", func(t *testing.T) {
-		authDataJSON, err := json.Marshal(aliceAuthData)
+		data, err := json.Marshal(aliceAuthorizationCode)
 		require.NoError(t, err)
 
+		var key string
+
 		redisClient := NewMockRedisClient(ctrl)
 		redisClient.EXPECT().
-			Get("auth42").
-			Return(redis.NewStringResult(string(authDataJSON), nil))
+			Set(gomock.Any(), data, config.AuthorizationCodeLifetime).
+			DoAndReturn(func(k string, _ interface{}, _ time.Duration) *redis.StatusCmd {
+				key = k
+				return redis.NewStatusResult("ok", nil)
+			})
+
+		adapter.redisClient = redisClient
+
+		code, err := adapter.CreateAuthorizationCode(aliceAuthorizationCode)
+		require.NoError(t, err)
+		require.Len(t, code, 43)
+		require.Equal(t, "authcode:"+code, key)
+	})
+}
+
+func TestAdapter_ConsumeAuthorizationCode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+		config: config,
+	}
+
+	data, err := json.Marshal(aliceAuthorizationCode)
+	require.NoError(t, err)
+
+	t.Run("normal", func(t *testing.T) {
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Get("authcode:code").
+			Return(redis.NewStringResult(string(data), nil))
+		redisClient.EXPECT().
+			Del("authcode:code").
+			Return(redis.NewIntResult(1, nil))
+
+		adapter.redisClient = redisClient
+
+		actual, err := adapter.ConsumeAuthorizationCode("code")
+		require.NoError(t, err)
+		require.Equal(t, aliceAuthorizationCode, actual)
+	})
+
+	t.Run("unknown code", func(t *testing.T) {
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Get("authcode:code").
+			Return(redis.NewStringResult("", redis.Nil))
+
+		adapter.redisClient = redisClient
+
+		_, err := adapter.ConsumeAuthorizationCode("code")
+		require.Equal(t, domain.ErrInvalidGrant, err)
+	})
+
+	t.Run("concurrently consumed code", func(t *testing.T) {
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Get("authcode:code").
+			Return(redis.NewStringResult(string(data), nil))
+		redisClient.EXPECT().
+			Del("authcode:code").
+			Return(redis.NewIntResult(0, nil))
+
+		adapter.redisClient = redisClient
+
+		_, err := adapter.ConsumeAuthorizationCode("code")
+		require.Equal(t, domain.ErrInvalidGrant, err)
+	})
+}
+
+func TestAdapter_CreateIDToken(t *testing.T) {
+	uuidPatch := monkey.Patch(uuid.NewRandom, func() (uuid.UUID, error) {
+		return idTokenID, nil
+	})
+	defer uuidPatch.Unpatch()
+
+	logger := zap.NewExample()
+
+	hmacAdapter := &adapter{
+		logger:  logger,
+		config:  config,
+		keyring: testKeyring,
+	}
+
+	dir, err := ioutil.TempDir("", "keys")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	keyring, err := NewKeyring(zap.NewNop(), &Config{
+		SigningAlgorithm: "ES256",
+		SigningKeyFile:   writePrivateKey(t, dir, ecdsaKey),
+	})
+	require.NoError(t, err)
+
+	adapter := &adapter{
+		logger:  logger,
+		config:  config,
+		keyring: keyring,
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		claims := &domain.IDTokenClaims{
+			Nonce:             "nonce",
+			PreferredUsername: alice.Username,
+			StandardClaims: jwt.StandardClaims{
+				Issuer:    "https://goss.example.com",
+				Subject:   "42",
+				Audience:  "spa",
+				IssuedAt:  timePoint.Unix(),
+				ExpiresAt: timePoint.Add(time.Hour).Unix(),
+			},
+		}
+
+		expected := *claims
+		expected.Id = idTokenID.String()
+		expected.Type = tokenTypeID
+
+		signed, err := adapter.CreateIDToken(claims)
+		require.NoError(t, err)
+
+		actual := new(domain.IDTokenClaims)
+		token, err := jwt.ParseWithClaims(signed, actual, adapter.jwtKeyFunc)
+		require.NoError(t, err)
+		require.Equal(t, "ES256", token.Method.Alg())
+		require.Equal(t, &expected, actual)
+	})
+
+	t.Run("with HS256 secret", func(t *testing.T) {
+		_, err := hmacAdapter.CreateIDToken(&domain.IDTokenClaims{})
+		require.Equal(t, domain.ErrInternalSecurity, err)
+	})
+}
+
+func TestAdapter_CreateMFAToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+		config: config,
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		var key string
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Set(gomock.Any(), alice.ID, config.MFATokenLifetime).
+			DoAndReturn(func(k string, _ interface{}, _ time.Duration) *redis.StatusCmd {
+				key = k
+				return redis.NewStatusResult("ok", nil)
+			})
+
+		adapter.redisClient = redisClient
+
+		mfaToken, err := adapter.CreateMFAToken(alice)
+		require.NoError(t, err)
+		require.Len(t, mfaToken, 43)
+		require.Equal(t, "authmfa:"+mfaToken, key)
+	})
+}
+
+func TestAdapter_ConsumeMFAToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+		config: config,
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Get("authmfa:token").
+			Return(redis.NewStringResult("42", nil))
+		redisClient.EXPECT().
+			Del("authmfa:token").
+			Return(redis.NewIntResult(1, nil))
+
+		adapter.redisClient = redisClient
+
+		actual, err := adapter.ConsumeMFAToken("token")
+		require.NoError(t, err)
+		require.Equal(t, alice.ID, actual)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Get("authmfa:token").
+			Return(redis.NewStringResult("", redis.Nil))
+
+		adapter.redisClient = redisClient
+
+		_, err := adapter.ConsumeMFAToken("token")
+		require.Equal(t, domain.ErrInvalidMFAToken, err)
+	})
+}
+
+func TestAdapter_GetAccessTokenClaims(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger:  logger,
+		config:  config,
+		keyring: testKeyring,
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Exists(accessRevokedKey).
+			Return(redis.NewIntResult(0, nil))
 
 		adapter.redisClient = redisClient
 
@@ -156,26 +658,155 @@ func TestAdapter_GetAccessTokenClaims(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, aliceAccessTokenClaims, actual)
 	})
+
+	t.Run("token without id and type", func(t *testing.T) {
+		legacyClaims := *aliceAccessTokenClaims
+		legacyClaims.Type = ""
+		legacyClaims.Id = ""
+		legacyToken := newTestToken(&legacyClaims)
+
+		legacySession := *aliceSession
+		legacySession.AccessToken = legacyToken
+		sessionJSON, err := json.Marshal(legacySession)
+		require.NoError(t, err)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult(string(sessionJSON), nil))
+
+		adapter.redisClient = redisClient
+
+		actual, err := adapter.GetAccessTokenClaims(legacyToken)
+		require.NoError(t, err)
+		require.Equal(t, &legacyClaims, actual)
+	})
+
+	t.Run("logged out token without id and type", func(t *testing.T) {
+		legacyClaims := *aliceAccessTokenClaims
+		legacyClaims.Type = ""
+		legacyClaims.Id = ""
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult("", redis.Nil))
+
+		adapter.redisClient = redisClient
+
+		_, err := adapter.GetAccessTokenClaims(newTestToken(&legacyClaims))
+		require.Equal(t, domain.ErrInvalidAccessToken, err)
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Exists(accessRevokedKey).
+			Return(redis.NewIntResult(1, nil))
+
+		adapter.redisClient = redisClient
+
+		_, err := adapter.GetAccessTokenClaims(aliceAccessToken)
+		require.Equal(t, domain.ErrInvalidAccessToken, err)
+	})
+
+	t.Run("client token", func(t *testing.T) {
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Exists(clientRevokedKey).
+			Return(redis.NewIntResult(0, nil))
+
+		adapter.redisClient = redisClient
+
+		actual, err := adapter.GetAccessTokenClaims(backendAccessToken)
+		require.NoError(t, err)
+		require.Equal(t, backendAccessTokenClaims, actual)
+	})
+
+	t.Run("refresh token", func(t *testing.T) {
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Exists(refreshRevokedKey).
+			Return(redis.NewIntResult(0, nil))
+
+		adapter.redisClient = redisClient
+
+		_, err := adapter.GetAccessTokenClaims(aliceRefreshToken)
+		require.Equal(t, domain.ErrInvalidAccessToken, err)
+	})
+
+	t.Run("refresh token without type", func(t *testing.T) {
+		legacyClaims := *aliceRefreshTokenClaims
+		legacyClaims.Type = ""
+
+		sessionJSON, err := json.Marshal(aliceSession)
+		require.NoError(t, err)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Exists(refreshRevokedKey).
+			Return(redis.NewIntResult(0, nil))
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult(string(sessionJSON), nil))
+
+		adapter.redisClient = redisClient
+
+		_, err = adapter.GetAccessTokenClaims(newTestToken(&legacyClaims))
+		require.Equal(t, domain.ErrInvalidAccessToken, err)
+	})
+
+	t.Run("ID token", func(t *testing.T) {
+		idToken := newTestToken(&domain.IDTokenClaims{
+			Type:              tokenTypeID,
+			PreferredUsername: alice.Username,
+			StandardClaims: jwt.StandardClaims{
+				Id:        idTokenID.String(),
+				Subject:   "42",
+				Audience:  "spa",
+				ExpiresAt: timePoint.Add(time.Hour).Unix(),
+			},
+		})
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Exists("authrevoked:" + idTokenID.String()).
+			Return(redis.NewIntResult(0, nil))
+
+		adapter.redisClient = redisClient
+
+		_, err := adapter.GetAccessTokenClaims(idToken)
+		require.Equal(t, domain.ErrInvalidAccessToken, err)
+	})
 }
 
 func TestAdapter_GetRefreshTokenClaims(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
+	timePatch := monkey.Patch(time.Now, func() time.Time {
+		return timePoint
+	})
+	defer timePatch.Unpatch()
+
 	logger := zap.NewExample()
 
 	adapter := &adapter{
-		logger: logger,
-		config: config,
+		logger:  logger,
+		config:  config,
+		keyring: testKeyring,
 	}
 
 	t.Run("normal", func(t *testing.T) {
-		authDataJSON, err := json.Marshal(aliceAuthData)
+		sessionJSON, err := json.Marshal(aliceSession)
 		require.NoError(t, err)
 
 		redisClient := NewMockRedisClient(ctrl)
 		redisClient.EXPECT().
-			Get("auth42").
-			Return(redis.NewStringResult(string(authDataJSON), nil))
+			Exists(refreshRevokedKey).
+			Return(redis.NewIntResult(0, nil))
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult(string(sessionJSON), nil))
 
 		adapter.redisClient = redisClient
 
@@ -183,23 +814,387 @@ func TestAdapter_GetRefreshTokenClaims(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, aliceRefreshTokenClaims, actual)
 	})
+
+	t.Run("rotated token", func(t *testing.T) {
+		rotatedClaims := *aliceRefreshTokenClaims
+		rotatedClaims.Id = idTokenID.String()
+
+		rotatedSession := *aliceSession
+		rotatedSession.RefreshToken = newTestToken(&rotatedClaims)
+		sessionJSON, err := json.Marshal(rotatedSession)
+		require.NoError(t, err)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Exists(refreshRevokedKey).
+			Return(redis.NewIntResult(0, nil))
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult(string(sessionJSON), nil))
+		expectRevoked(redisClient, accessRevokedKey, domain.TokenTypeAccessToken, aliceAccessTokenClaims.ExpiresAt)
+		expectRevoked(redisClient, "authrevoked:"+idTokenID.String(), domain.TokenTypeRefreshToken, rotatedClaims.ExpiresAt)
+		redisClient.EXPECT().
+			Del(aliceSessionKey).
+			Return(redis.NewIntResult(1, nil))
+		redisClient.EXPECT().
+			SRem(aliceSessionsKey, sessionID.String()).
+			Return(redis.NewIntResult(1, nil))
+
+		adapter.redisClient = redisClient
+
+		_, err = adapter.GetRefreshTokenClaims(aliceRefreshToken)
+		require.Equal(t, domain.ErrRefreshTokenReused, err)
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Exists(refreshRevokedKey).
+			Return(redis.NewIntResult(1, nil))
+
+		adapter.redisClient = redisClient
+
+		_, err := adapter.GetRefreshTokenClaims(aliceRefreshToken)
+		require.Equal(t, domain.ErrInvalidRefreshToken, err)
+	})
+
+	t.Run("access token", func(t *testing.T) {
+		adapter.redisClient = NewMockRedisClient(ctrl)
+
+		_, err := adapter.GetRefreshTokenClaims(aliceAccessToken)
+		require.Equal(t, domain.ErrInvalidRefreshToken, err)
+	})
+}
+
+func TestAdapter_IdentifyToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger:  logger,
+		config:  config,
+		keyring: testKeyring,
+	}
+
+	t.Run("access token", func(t *testing.T) {
+		sessionJSON, err := json.Marshal(aliceSession)
+		require.NoError(t, err)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Exists(accessRevokedKey).
+			Return(redis.NewIntResult(0, nil))
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult(string(sessionJSON), nil))
+
+		adapter.redisClient = redisClient
+
+		expected := &domain.TokenInfo{
+			ID:        accessTokenID.String(),
+			Type:      domain.TokenTypeAccessToken,
+			UserID:    alice.ID,
+			ExpiresAt: aliceAccessTokenClaims.ExpiresAt,
+		}
+
+		actual, err := adapter.IdentifyToken(aliceAccessToken)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("refresh token", func(t *testing.T) {
+		sessionJSON, err := json.Marshal(aliceSession)
+		require.NoError(t, err)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Exists(refreshRevokedKey).
+			Return(redis.NewIntResult(0, nil))
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult(string(sessionJSON), nil))
+
+		adapter.redisClient = redisClient
+
+		expected := &domain.TokenInfo{
+			ID:        tokenID.String(),
+			Type:      domain.TokenTypeRefreshToken,
+			UserID:    alice.ID,
+			ExpiresAt: aliceRefreshTokenClaims.ExpiresAt,
+		}
+
+		actual, err := adapter.IdentifyToken(aliceRefreshToken)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("token of authorized client", func(t *testing.T) {
+		clientSession := *aliceSession
+		clientSession.ClientID = "spa"
+		sessionJSON, err := json.Marshal(clientSession)
+		require.NoError(t, err)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Exists(accessRevokedKey).
+			Return(redis.NewIntResult(0, nil))
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult(string(sessionJSON), nil))
+
+		adapter.redisClient = redisClient
+
+		expected := &domain.TokenInfo{
+			ID:        accessTokenID.String(),
+			Type:      domain.TokenTypeAccessToken,
+			UserID:    alice.ID,
+			ClientID:  "spa",
+			ExpiresAt: aliceAccessTokenClaims.ExpiresAt,
+		}
+
+		actual, err := adapter.IdentifyToken(aliceAccessToken)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("client token", func(t *testing.T) {
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Exists(clientRevokedKey).
+			Return(redis.NewIntResult(0, nil))
+
+		adapter.redisClient = redisClient
+
+		expected := &domain.TokenInfo{
+			ID:        clientTokenID.String(),
+			Type:      domain.TokenTypeAccessToken,
+			ClientID:  backend.ClientID,
+			ExpiresAt: backendAccessTokenClaims.ExpiresAt,
+		}
+
+		actual, err := adapter.IdentifyToken(backendAccessToken)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("logged out", func(t *testing.T) {
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Exists(accessRevokedKey).
+			Return(redis.NewIntResult(0, nil))
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult("", redis.Nil))
+
+		adapter.redisClient = redisClient
+
+		_, err := adapter.IdentifyToken(aliceAccessToken)
+		require.Equal(t, domain.ErrNotFound, err)
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Exists(accessRevokedKey).
+			Return(redis.NewIntResult(1, nil))
+
+		adapter.redisClient = redisClient
+
+		_, err := adapter.IdentifyToken(aliceAccessToken)
+		require.Equal(t, domain.ErrNotFound, err)
+	})
+
+	t.Run("ID token", func(t *testing.T) {
+		adapter.redisClient = NewMockRedisClient(ctrl)
+
+		_, err := adapter.IdentifyToken(newTestToken(&domain.IDTokenClaims{
+			Type: tokenTypeID,
+			StandardClaims: jwt.StandardClaims{
+				Id:        idTokenID.String(),
+				Subject:   "42",
+				ExpiresAt: timePoint.Add(time.Hour).Unix(),
+			},
+		}))
+		require.Equal(t, domain.ErrNotFound, err)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		adapter.redisClient = NewMockRedisClient(ctrl)
+
+		_, err := adapter.IdentifyToken("invalid")
+		require.Equal(t, domain.ErrNotFound, err)
+	})
+}
+
+func TestAdapter_RevokeToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	timePatch := monkey.Patch(time.Now, func() time.Time {
+		return timePoint
+	})
+	defer timePatch.Unpatch()
+
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger:  logger,
+		config:  config,
+		keyring: testKeyring,
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		info := &domain.TokenInfo{
+			ID:        accessTokenID.String(),
+			Type:      domain.TokenTypeAccessToken,
+			UserID:    alice.ID,
+			ExpiresAt: timePoint.Add(time.Hour).Unix(),
+		}
+
+		redisClient := NewMockRedisClient(ctrl)
+		expectRevoked(redisClient, accessRevokedKey, domain.TokenTypeAccessToken, info.ExpiresAt)
+
+		adapter.redisClient = redisClient
+
+		err := adapter.RevokeToken(info)
+		require.NoError(t, err)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		info := &domain.TokenInfo{
+			ID:        accessTokenID.String(),
+			Type:      domain.TokenTypeAccessToken,
+			UserID:    alice.ID,
+			ExpiresAt: timePoint.Add(-time.Hour).Unix(),
+		}
+
+		adapter.redisClient = NewMockRedisClient(ctrl)
+
+		err := adapter.RevokeToken(info)
+		require.NoError(t, err)
+	})
+}
+
+func TestAdapter_GetSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger:  logger,
+		config:  config,
+		keyring: testKeyring,
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		sessionJSON, err := json.Marshal(aliceSession)
+		require.NoError(t, err)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			SMembers(aliceSessionsKey).
+			Return(redis.NewStringSliceResult([]string{sessionID.String(), "expired"}, nil))
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult(string(sessionJSON), nil))
+		redisClient.EXPECT().
+			Get("auth42:expired").
+			Return(redis.NewStringResult("", redis.Nil))
+		redisClient.EXPECT().
+			SRem(aliceSessionsKey, "expired").
+			Return(redis.NewIntResult(1, nil))
+
+		adapter.redisClient = redisClient
+
+		actual, err := adapter.GetSessions(alice.ID)
+		require.NoError(t, err)
+		require.Equal(t, []*domain.Session{&aliceSession.Session}, actual)
+	})
+}
+
+func TestAdapter_InvalidateSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	timePatch := monkey.Patch(time.Now, func() time.Time {
+		return timePoint
+	})
+	defer timePatch.Unpatch()
+
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger:  logger,
+		config:  config,
+		keyring: testKeyring,
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		sessionJSON, err := json.Marshal(aliceSession)
+		require.NoError(t, err)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult(string(sessionJSON), nil))
+		expectSessionRevoked(redisClient)
+		redisClient.EXPECT().
+			Del(aliceSessionKey).
+			Return(redis.NewIntResult(1, nil))
+		redisClient.EXPECT().
+			SRem(aliceSessionsKey, sessionID.String()).
+			Return(redis.NewIntResult(1, nil))
+
+		adapter.redisClient = redisClient
+
+		require.NoError(t, adapter.InvalidateSession(alice.ID, sessionID.String()))
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult("", redis.Nil))
+		redisClient.EXPECT().
+			SRem(aliceSessionsKey, sessionID.String()).
+			Return(redis.NewIntResult(0, nil))
+
+		adapter.redisClient = redisClient
+
+		require.Equal(t, domain.ErrNotFound, adapter.InvalidateSession(alice.ID, sessionID.String()))
+	})
 }
 
 func TestAdapter_InvalidateUserAuthData(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
+	timePatch := monkey.Patch(time.Now, func() time.Time {
+		return timePoint
+	})
+	defer timePatch.Unpatch()
+
 	logger := zap.NewExample()
 
 	adapter := &adapter{
-		logger: logger,
-		config: config,
+		logger:  logger,
+		config:  config,
+		keyring: testKeyring,
 	}
 
 	t.Run("normal", func(t *testing.T) {
+		sessionJSON, err := json.Marshal(aliceSession)
+		require.NoError(t, err)
+
 		redisClient := NewMockRedisClient(ctrl)
 		redisClient.EXPECT().
-			Del("auth42").
-			Return(redis.NewIntCmd(1, nil))
+			SMembers(aliceSessionsKey).
+			Return(redis.NewStringSliceResult([]string{sessionID.String()}, nil))
+		redisClient.EXPECT().
+			Get(aliceSessionKey).
+			Return(redis.NewStringResult(string(sessionJSON), nil))
+		expectSessionRevoked(redisClient)
+		redisClient.EXPECT().
+			Del(aliceSessionKey, aliceSessionsKey).
+			Return(redis.NewIntResult(2, nil))
 
 		adapter.redisClient = redisClient
 
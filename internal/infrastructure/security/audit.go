@@ -0,0 +1,20 @@
+package security
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+//NewAuditKey decodes the base64 encoded key the audit log chain is hashed with.
+//The key is kept outside the database, so whoever can change audit events can't recompute the chain.
+func NewAuditKey(key string) ([]byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) < auditKeyMinLength {
+		return nil, fmt.Errorf("the audit key must be at least %d bytes long", auditKeyMinLength)
+	}
+
+	return decoded, nil
+}
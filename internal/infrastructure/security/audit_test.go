@@ -0,0 +1,33 @@
+package security
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAuditKey(t *testing.T) {
+	t.Run("valid key", func(t *testing.T) {
+		key := []byte("0123456789abcdef0123456789abcdef")
+
+		actual, err := NewAuditKey(base64.StdEncoding.EncodeToString(key))
+		require.NoError(t, err)
+		require.Equal(t, key, actual)
+	})
+
+	t.Run("short key", func(t *testing.T) {
+		_, err := NewAuditKey(base64.StdEncoding.EncodeToString([]byte("key")))
+		require.Error(t, err)
+	})
+
+	t.Run("empty key", func(t *testing.T) {
+		_, err := NewAuditKey("")
+		require.Error(t, err)
+	})
+
+	t.Run("not base64 key", func(t *testing.T) {
+		_, err := NewAuditKey("not base64")
+		require.Error(t, err)
+	})
+}
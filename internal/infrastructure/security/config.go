@@ -4,9 +4,24 @@ import "time"
 
 //Config contains a security configuration adapter.
 type Config struct {
-	KeyPrefix            string             `validate:"required"`
-	Secret               []byte             `validate:"required"`
-	AccessTokenLifetime  time.Duration      `validate:"required"`
-	RefreshTokenLifetime time.Duration      `validate:"required"`
-	RedisClient          *RedisClientConfig `validate:"required"`
+	KeyPrefix                 string `validate:"required"`
+	Secret                    []byte
+	PreviousSecrets           []string
+	SigningAlgorithm          string `default:"HS256" validate:"required,oneof=HS256 RS256 ES256 EdDSA"`
+	SigningKeyFile            string
+	SigningKeyID              string
+	KeysFile                  string
+	KeysReloadInterval        time.Duration `default:"1m"`
+	SecretsAcceptedUntil      time.Time
+	AccessTokenLifetime       time.Duration `validate:"required"`
+	RefreshTokenLifetime      time.Duration `validate:"required"`
+	ClientTokenLifetime       time.Duration `default:"1h"`
+	AuthorizationCodeLifetime time.Duration `default:"1m"`
+	MFATokenLifetime          time.Duration `default:"5m"`
+	EncryptionKey             string
+	PreviousEncryptionKeys    []string
+	AuditKey                  string             `validate:"required"`
+	RedisClient               *RedisClientConfig `validate:"required"`
+	Password                  *PasswordConfig    `validate:"required"`
+	Lockout                   *LockoutConfig     `validate:"required"`
 }
@@ -1,3 +1,56 @@
 package security
 
-const keyFormat = "%s%d"
+const (
+	sessionKeyFormat  = "%s%d:%s"
+	sessionsKeyFormat = "%ssessions:%d"
+
+	authorizationCodeKeyFormat = "%scode:%s"
+	mfaTokenKeyFormat          = "%smfa:%s"
+	revokedTokenKeyFormat      = "%srevoked:%s"
+	randomTokenLength          = 32
+)
+
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+	tokenTypeID      = "id"
+)
+
+const (
+	userLoginAttemptsKeyFormat = "%sattempts:user:%s"
+	ipLoginAttemptsKeyFormat   = "%sattempts:ip:%s"
+	userLockoutKeyFormat       = "%slockout:user:%s"
+	ipLockoutKeyFormat         = "%slockout:ip:%s"
+)
+
+const encryptionKeyLength = 32
+
+const auditKeyMinLength = 32
+
+const (
+	algorithmHS256 = "HS256"
+	algorithmRS256 = "RS256"
+	algorithmES256 = "ES256"
+	algorithmEdDSA = "EdDSA"
+
+	keyIDHeader = "kid"
+	keyIDLength = 12
+
+	keyStatusVerify  = "verify"
+	keyStatusSign    = "sign"
+	keyStatusRetired = "retired"
+
+	jwkUseSignature = "sig"
+)
+
+const (
+	algorithmArgon2id = "argon2id"
+
+	argon2idPrefix   = "$argon2id$"
+	argon2idFormat   = "$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s"
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+	argon2MaxTime    = 16
+	argon2MaxMemory  = 1024 * 1024
+	argon2MaxThreads = 64
+)
@@ -0,0 +1,52 @@
+package security
+
+import (
+	"crypto/ed25519"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+//signingMethodEdDSA implements the EdDSA signing method with Ed25519 keys.
+type signingMethodEdDSA struct{}
+
+var signingMethodEd25519 = &signingMethodEdDSA{}
+
+func init() {
+	jwt.RegisterSigningMethod(signingMethodEd25519.Alg(), func() jwt.SigningMethod {
+		return signingMethodEd25519
+	})
+}
+
+//Alg returns the algorithm name.
+func (m *signingMethodEdDSA) Alg() string {
+	return algorithmEdDSA
+}
+
+//Verify verifies the signature of the signing string with the public key.
+func (m *signingMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return jwt.ErrSignatureInvalid
+	}
+
+	return nil
+}
+
+//Sign signs the signing string with the private key.
+func (m *signingMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
@@ -0,0 +1,95 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/lzakharov/goss/internal/domain"
+)
+
+var errCiphertextTooShort = errors.New("ciphertext too short")
+
+//NewEncrypter creates a new AES-256-GCM encrypter with the base64 encoded key.
+//Previous keys only decrypt, so secrets encrypted before the key rotation stay readable until re-encrypted.
+func NewEncrypter(key string, previousKeys []string) (domain.Encrypter, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &encrypter{aead: aead}
+
+	for _, previousKey := range previousKeys {
+		previous, err := newAEAD(previousKey)
+		if err != nil {
+			return nil, fmt.Errorf("previous encryption key: %w", err)
+		}
+		e.previous = append(e.previous, previous)
+	}
+
+	return e, nil
+}
+
+func newAEAD(key string) (cipher.AEAD, error) {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) != encryptionKeyLength {
+		return nil, fmt.Errorf("the encryption key must be %d bytes long", encryptionKeyLength)
+	}
+
+	block, err := aes.NewCipher(decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+type encrypter struct {
+	aead     cipher.AEAD
+	previous []cipher.AEAD
+}
+
+//Encrypt encrypts the plaintext with a random nonce prepended to the ciphertext.
+func (e *encrypter) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := e.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+//Decrypt decrypts and authenticates the ciphertext with the current key, then with the previous ones.
+func (e *encrypter) Decrypt(ciphertext string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	size := e.aead.NonceSize()
+	if len(sealed) < size {
+		return "", errCiphertextTooShort
+	}
+
+	plaintext, err := e.aead.Open(nil, sealed[:size], sealed[size:], nil)
+	for _, previous := range e.previous {
+		if err == nil {
+			break
+		}
+		plaintext, err = previous.Open(nil, sealed[:size], sealed[size:], nil)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
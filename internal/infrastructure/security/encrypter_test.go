@@ -0,0 +1,100 @@
+package security
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	encryptionKey        = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", encryptionKeyLength)))
+	anotherEncryptionKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", encryptionKeyLength)))
+)
+
+func TestNewEncrypter(t *testing.T) {
+	t.Run("valid key", func(t *testing.T) {
+		_, err := NewEncrypter(encryptionKey, nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("short key", func(t *testing.T) {
+		_, err := NewEncrypter(base64.StdEncoding.EncodeToString([]byte("key")), nil)
+		require.Error(t, err)
+	})
+
+	t.Run("not base64 key", func(t *testing.T) {
+		_, err := NewEncrypter("not base64", nil)
+		require.Error(t, err)
+	})
+
+	t.Run("invalid previous key", func(t *testing.T) {
+		_, err := NewEncrypter(encryptionKey, []string{"not base64"})
+		require.Error(t, err)
+	})
+}
+
+func TestEncrypter(t *testing.T) {
+	encrypter, err := NewEncrypter(encryptionKey, nil)
+	require.NoError(t, err)
+
+	t.Run("round trip", func(t *testing.T) {
+		ciphertext, err := encrypter.Encrypt("secret")
+		require.NoError(t, err)
+		require.NotContains(t, ciphertext, "secret")
+
+		plaintext, err := encrypter.Decrypt(ciphertext)
+		require.NoError(t, err)
+		require.Equal(t, "secret", plaintext)
+	})
+
+	t.Run("random nonce", func(t *testing.T) {
+		first, err := encrypter.Encrypt("secret")
+		require.NoError(t, err)
+		second, err := encrypter.Encrypt("secret")
+		require.NoError(t, err)
+		require.NotEqual(t, first, second)
+	})
+
+	t.Run("tampered ciphertext", func(t *testing.T) {
+		ciphertext, err := encrypter.Encrypt("secret")
+		require.NoError(t, err)
+
+		sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+		require.NoError(t, err)
+		sealed[len(sealed)-1] ^= 1
+
+		_, err = encrypter.Decrypt(base64.StdEncoding.EncodeToString(sealed))
+		require.Error(t, err)
+	})
+
+	t.Run("another key", func(t *testing.T) {
+		ciphertext, err := encrypter.Encrypt("secret")
+		require.NoError(t, err)
+
+		another, err := NewEncrypter(anotherEncryptionKey, nil)
+		require.NoError(t, err)
+
+		_, err = another.Decrypt(ciphertext)
+		require.Error(t, err)
+	})
+
+	t.Run("previous key", func(t *testing.T) {
+		ciphertext, err := encrypter.Encrypt("secret")
+		require.NoError(t, err)
+
+		rotated, err := NewEncrypter(anotherEncryptionKey, []string{encryptionKey})
+		require.NoError(t, err)
+
+		plaintext, err := rotated.Decrypt(ciphertext)
+		require.NoError(t, err)
+		require.Equal(t, "secret", plaintext)
+
+		reencrypted, err := rotated.Encrypt(plaintext)
+		require.NoError(t, err)
+
+		_, err = encrypter.Decrypt(reencrypted)
+		require.Error(t, err)
+	})
+}
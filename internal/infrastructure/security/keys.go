@@ -0,0 +1,426 @@
+package security
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/lzakharov/goss/internal/domain"
+	"go.uber.org/zap"
+)
+
+var (
+	errUnknownKey        = errors.New("unknown signing key")
+	errAlgorithmMismatch = errors.New("signing algorithm does not match the key")
+)
+
+//Keyring represents a set of token signing and verification keys.
+type Keyring interface {
+	signingKey() *key
+	verificationKey(kid string) (*key, bool)
+	publicKeys() []*key
+}
+
+type key struct {
+	id      string
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+//keySet contains keys loaded at once.
+//The secret key is the primary HS256 secret, previous secrets are only accepted for verification.
+type keySet struct {
+	signing *key
+	keys    map[string]*key
+	secret  *key
+}
+
+//keysManifest describes rotated keys.
+//A key is announced with the "verify" status, then starts signing tokens with the "sign" status
+//and finally gets the "retired" status (or is removed) when tokens signed with it expire.
+type keysManifest struct {
+	Keys []*keysManifestEntry `json:"keys"`
+}
+
+type keysManifestEntry struct {
+	ID        string `json:"id"`
+	Algorithm string `json:"algorithm"`
+	File      string `json:"file"`
+	Status    string `json:"status"`
+}
+
+type keyring struct {
+	logger *zap.Logger
+	config *Config
+
+	mu        sync.RWMutex
+	set       *keySet
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+//NewKeyring creates a new keyring from the security configuration.
+//Keys listed in the keys file are reloaded when the file changes.
+func NewKeyring(logger *zap.Logger, config *Config) (Keyring, error) {
+	k := &keyring{
+		logger: logger,
+		config: config,
+	}
+
+	if config.KeysFile != "" {
+		info, err := os.Stat(config.KeysFile)
+		if err != nil {
+			return nil, err
+		}
+		k.modTime = info.ModTime()
+		k.checkedAt = time.Now()
+	}
+
+	set, err := loadKeySet(config)
+	if err != nil {
+		return nil, err
+	}
+	k.set = set
+
+	return k, nil
+}
+
+func (k *keyring) signingKey() *key {
+	return k.keySet().signing
+}
+
+//verificationKey finds a key by id.
+//Tokens without a key id were issued before key ids were introduced and are verified with the secret.
+//Secrets verify tokens only while tokens are signed with HS256 or until the configured migration deadline,
+//so a leaked secret can't forge tokens after switching to asymmetric keys.
+func (k *keyring) verificationKey(kid string) (*key, bool) {
+	set := k.keySet()
+
+	if kid == "" {
+		return set.secret, set.secret != nil && k.acceptsSecrets(set)
+	}
+
+	key, ok := set.keys[kid]
+	if ok && key.symmetric() && !k.acceptsSecrets(set) {
+		return nil, false
+	}
+
+	return key, ok
+}
+
+func (k *keyring) acceptsSecrets(set *keySet) bool {
+	return set.signing.symmetric() || time.Now().Before(k.config.SecretsAcceptedUntil)
+}
+
+//publicKeys returns asymmetric verification keys sorted by id.
+func (k *keyring) publicKeys() []*key {
+	set := k.keySet()
+
+	keys := make([]*key, 0, len(set.keys))
+	for _, key := range set.keys {
+		if !key.symmetric() {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].id < keys[j].id
+	})
+
+	return keys
+}
+
+func (k *keyring) keySet() *keySet {
+	k.mu.RLock()
+	set, stale := k.set, k.config.KeysFile != "" && time.Since(k.checkedAt) > k.config.KeysReloadInterval
+	k.mu.RUnlock()
+
+	if stale {
+		return k.reload()
+	}
+
+	return set
+}
+
+func (k *keyring) reload() *keySet {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if time.Since(k.checkedAt) <= k.config.KeysReloadInterval {
+		return k.set
+	}
+	k.checkedAt = time.Now()
+
+	info, err := os.Stat(k.config.KeysFile)
+	if err != nil {
+		k.logger.Error("Error checking the keys file, keeping the current keys!",
+			zap.String("file", k.config.KeysFile),
+			zap.Error(err))
+		return k.set
+	}
+
+	if info.ModTime().Equal(k.modTime) {
+		return k.set
+	}
+
+	set, err := loadKeySet(k.config)
+	if err != nil {
+		k.logger.Error("Error reloading keys, keeping the current keys!",
+			zap.String("file", k.config.KeysFile),
+			zap.Error(err))
+		return k.set
+	}
+
+	k.set = set
+	k.modTime = info.ModTime()
+
+	k.logger.Info("Keys reloaded.",
+		zap.String("file", k.config.KeysFile),
+		zap.String("signingKeyID", set.signing.id))
+
+	return k.set
+}
+
+func loadKeySet(config *Config) (*keySet, error) {
+	set := &keySet{
+		keys: make(map[string]*key),
+	}
+
+	if len(config.Secret) != 0 {
+		set.secret = newSecretKey(config.Secret)
+		set.keys[set.secret.id] = set.secret
+	}
+
+	for _, secret := range config.PreviousSecrets {
+		key := newSecretKey([]byte(secret))
+		if _, ok := set.keys[key.id]; !ok {
+			set.keys[key.id] = key
+		}
+	}
+
+	switch {
+	case config.KeysFile != "":
+		if err := set.loadManifest(config.KeysFile); err != nil {
+			return nil, err
+		}
+	case config.SigningAlgorithm == algorithmHS256:
+		if set.secret == nil {
+			return nil, fmt.Errorf("a secret is required for the %s signing algorithm", algorithmHS256)
+		}
+
+		set.signing = set.secret
+	default:
+		signing, err := loadPrivateKey(config.SigningAlgorithm, config.SigningKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		set.signing = signing
+		set.keys[signing.id] = signing
+	}
+
+	if config.KeysFile == "" && config.SigningKeyID != "" {
+		delete(set.keys, set.signing.id)
+		set.signing.id = config.SigningKeyID
+		set.keys[set.signing.id] = set.signing
+	}
+
+	return set, nil
+}
+
+func (s *keySet) loadManifest(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	manifest := new(keysManifest)
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return err
+	}
+
+	for _, entry := range manifest.Keys {
+		if entry.Status == keyStatusRetired {
+			continue
+		}
+
+		file := entry.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+
+		key, err := loadPrivateKey(entry.Algorithm, file)
+		if err != nil {
+			return fmt.Errorf("key '%s': %v", entry.ID, err)
+		}
+		if entry.ID != "" {
+			key.id = entry.ID
+		}
+
+		if _, ok := s.keys[key.id]; ok {
+			return fmt.Errorf("duplicate key id '%s'", key.id)
+		}
+		s.keys[key.id] = key
+
+		switch entry.Status {
+		case keyStatusSign:
+			if s.signing != nil {
+				return fmt.Errorf("more than one key with the '%s' status", keyStatusSign)
+			}
+			s.signing = key
+		case keyStatusVerify:
+		default:
+			return fmt.Errorf("key '%s': unknown status '%s'", key.id, entry.Status)
+		}
+	}
+
+	if s.signing == nil {
+		return fmt.Errorf("no key with the '%s' status", keyStatusSign)
+	}
+
+	return nil
+}
+
+func (k *key) symmetric() bool {
+	_, ok := k.public.([]byte)
+	return ok
+}
+
+func newSecretKey(secret []byte) *key {
+	return &key{
+		id:      fingerprint(secret),
+		method:  jwt.SigningMethodHS256,
+		private: secret,
+		public:  secret,
+	}
+}
+
+func loadPrivateKey(algorithm, path string) (*key, error) {
+	if path == "" {
+		return nil, fmt.Errorf("a signing key file is required for the %s signing algorithm", algorithm)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in '%s'", path)
+	}
+
+	var private interface{}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block type '%s'", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newPrivateKey(algorithm, private)
+}
+
+func newPrivateKey(algorithm string, private interface{}) (*key, error) {
+	var method jwt.SigningMethod
+
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		if algorithm == algorithmRS256 {
+			method = jwt.SigningMethodRS256
+		}
+	case *ecdsa.PrivateKey:
+		if algorithm == algorithmES256 && private.Curve == elliptic.P256() {
+			method = jwt.SigningMethodES256
+		}
+	case ed25519.PrivateKey:
+		if algorithm == algorithmEdDSA {
+			method = signingMethodEd25519
+		}
+	}
+	if method == nil {
+		return nil, fmt.Errorf("the private key is not suitable for the %s signing algorithm", algorithm)
+	}
+
+	public := private.(crypto.Signer).Public()
+
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key{
+		id:      fingerprint(der),
+		method:  method,
+		private: private,
+		public:  public,
+	}, nil
+}
+
+func newJSONWebKey(key *key) *domain.JSONWebKey {
+	jwk := &domain.JSONWebKey{
+		KeyID:     key.id,
+		Use:       jwkUseSignature,
+		Algorithm: key.method.Alg(),
+	}
+
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = public.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(padLeft(public.X.Bytes(), size))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(padLeft(public.Y.Bytes(), size))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+
+	return jwk
+}
+
+func padLeft(data []byte, size int) []byte {
+	if len(data) >= size {
+		return data
+	}
+
+	padded := make([]byte, size)
+	copy(padded[size-len(data):], data)
+	return padded
+}
+
+func fingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:keyIDLength])
+}
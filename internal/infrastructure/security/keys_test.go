@@ -0,0 +1,324 @@
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func writePrivateKey(t *testing.T, dir string, private interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	return path
+}
+
+func TestNewKeyring(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for _, c := range []struct {
+		algorithm string
+		private   interface{}
+	}{
+		{"RS256", rsaKey},
+		{"ES256", ecdsaKey},
+		{"EdDSA", ed25519Key},
+	} {
+		t.Run(c.algorithm, func(t *testing.T) {
+			config := &Config{
+				SigningAlgorithm: c.algorithm,
+				SigningKeyFile:   writePrivateKey(t, dir, c.private),
+			}
+
+			keyring, err := NewKeyring(zap.NewNop(), config)
+			require.NoError(t, err)
+
+			adapter := &adapter{
+				logger:  zap.NewExample(),
+				config:  config,
+				keyring: keyring,
+			}
+
+			signed, err := adapter.signToken(&jwt.StandardClaims{Subject: "alice"})
+			require.NoError(t, err)
+
+			claims := new(jwt.StandardClaims)
+			token, err := jwt.ParseWithClaims(signed, claims, adapter.jwtKeyFunc)
+			require.NoError(t, err)
+			require.True(t, token.Valid)
+			require.Equal(t, c.algorithm, token.Method.Alg())
+			require.Equal(t, keyring.signingKey().id, token.Header["kid"])
+			require.Equal(t, "alice", claims.Subject)
+		})
+	}
+
+	t.Run("mismatched algorithm", func(t *testing.T) {
+		config := &Config{
+			SigningAlgorithm: "ES256",
+			SigningKeyFile:   writePrivateKey(t, dir, rsaKey),
+		}
+
+		_, err := NewKeyring(zap.NewNop(), config)
+		require.Error(t, err)
+	})
+
+	t.Run("missing secret", func(t *testing.T) {
+		_, err := NewKeyring(zap.NewNop(), &Config{SigningAlgorithm: "HS256"})
+		require.Error(t, err)
+	})
+}
+
+func TestAdapter_jwtKeyFunc(t *testing.T) {
+	adapter := &adapter{
+		logger:  zap.NewExample(),
+		config:  config,
+		keyring: testKeyring,
+	}
+
+	t.Run("without key id", func(t *testing.T) {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.StandardClaims{}).SignedString(config.Secret)
+		require.NoError(t, err)
+
+		_, err = jwt.Parse(signed, adapter.jwtKeyFunc)
+		require.NoError(t, err)
+	})
+
+	t.Run("with unknown key id", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.StandardClaims{})
+		token.Header["kid"] = "unknown"
+		signed, err := token.SignedString(config.Secret)
+		require.NoError(t, err)
+
+		_, err = jwt.Parse(signed, adapter.jwtKeyFunc)
+		require.Error(t, err)
+	})
+
+	t.Run("with mismatched algorithm", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS512, &jwt.StandardClaims{})
+		token.Header["kid"] = testKeyring.signingKey().id
+		signed, err := token.SignedString(config.Secret)
+		require.NoError(t, err)
+
+		_, err = jwt.Parse(signed, adapter.jwtKeyFunc)
+		require.Error(t, err)
+	})
+}
+
+func TestKeyring_previousSecrets(t *testing.T) {
+	config := &Config{
+		Secret:           []byte("new secret"),
+		PreviousSecrets:  []string{"old secret"},
+		SigningAlgorithm: "HS256",
+	}
+
+	keyring, err := NewKeyring(zap.NewNop(), config)
+	require.NoError(t, err)
+
+	adapter := &adapter{
+		logger:  zap.NewExample(),
+		config:  config,
+		keyring: keyring,
+	}
+
+	t.Run("previous secret", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.StandardClaims{})
+		token.Header["kid"] = fingerprint([]byte("old secret"))
+		signed, err := token.SignedString([]byte("old secret"))
+		require.NoError(t, err)
+
+		_, err = jwt.Parse(signed, adapter.jwtKeyFunc)
+		require.NoError(t, err)
+	})
+
+	t.Run("signing with the primary secret", func(t *testing.T) {
+		signed, err := adapter.signToken(&jwt.StandardClaims{})
+		require.NoError(t, err)
+
+		token, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) {
+			return []byte("new secret"), nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, fingerprint([]byte("new secret")), token.Header["kid"])
+	})
+
+	t.Run("forgotten secret", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.StandardClaims{})
+		token.Header["kid"] = fingerprint([]byte("older secret"))
+		signed, err := token.SignedString([]byte("older secret"))
+		require.NoError(t, err)
+
+		_, err = jwt.Parse(signed, adapter.jwtKeyFunc)
+		require.Error(t, err)
+	})
+}
+
+func TestKeyring_secretsAfterSwitchingToAsymmetricKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	config := &Config{
+		Secret:           []byte("legacy secret"),
+		PreviousSecrets:  []string{"old secret"},
+		SigningAlgorithm: "RS256",
+		SigningKeyFile:   writePrivateKey(t, dir, rsaKey),
+	}
+
+	keyring, err := NewKeyring(zap.NewNop(), config)
+	require.NoError(t, err)
+
+	adapter := &adapter{
+		logger:  zap.NewExample(),
+		config:  config,
+		keyring: keyring,
+	}
+
+	forge := func(kid, secret string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.StandardClaims{Subject: "mallory"})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString([]byte(secret))
+		require.NoError(t, err)
+		return signed
+	}
+
+	t.Run("forged without key id", func(t *testing.T) {
+		_, err := jwt.Parse(forge("", "legacy secret"), adapter.jwtKeyFunc)
+		require.Error(t, err)
+	})
+
+	t.Run("forged with a secret key id", func(t *testing.T) {
+		_, err := jwt.Parse(forge(fingerprint([]byte("legacy secret")), "legacy secret"), adapter.jwtKeyFunc)
+		require.Error(t, err)
+
+		_, err = jwt.Parse(forge(fingerprint([]byte("old secret")), "old secret"), adapter.jwtKeyFunc)
+		require.Error(t, err)
+	})
+
+	t.Run("during the migration", func(t *testing.T) {
+		config.SecretsAcceptedUntil = time.Now().Add(time.Hour)
+		defer func() { config.SecretsAcceptedUntil = time.Time{} }()
+
+		_, err := jwt.Parse(forge("", "legacy secret"), adapter.jwtKeyFunc)
+		require.NoError(t, err)
+	})
+
+	t.Run("after the migration", func(t *testing.T) {
+		config.SecretsAcceptedUntil = time.Now().Add(-time.Second)
+		defer func() { config.SecretsAcceptedUntil = time.Time{} }()
+
+		_, err := jwt.Parse(forge("", "legacy secret"), adapter.jwtKeyFunc)
+		require.Error(t, err)
+	})
+}
+
+func writeKeysManifest(t *testing.T, path string, manifest *keysManifest, modTime time.Time) {
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestKeyring_rotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, id := range []string{"old", "new"} {
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		require.NoError(t, os.Rename(writePrivateKey(t, dir, private), filepath.Join(dir, id+".pem")))
+	}
+
+	path := filepath.Join(dir, "keys.json")
+	config := &Config{
+		KeysFile:           path,
+		KeysReloadInterval: time.Nanosecond,
+	}
+
+	writeKeysManifest(t, path, &keysManifest{
+		Keys: []*keysManifestEntry{
+			{ID: "old", Algorithm: "EdDSA", File: "old.pem", Status: "sign"},
+			{ID: "new", Algorithm: "EdDSA", File: "new.pem", Status: "verify"},
+		},
+	}, time.Unix(1, 0))
+
+	keyring, err := NewKeyring(zap.NewNop(), config)
+	require.NoError(t, err)
+
+	t.Run("announce", func(t *testing.T) {
+		require.Equal(t, "old", keyring.signingKey().id)
+		_, ok := keyring.verificationKey("new")
+		require.True(t, ok)
+
+		adapter := &adapter{keyring: keyring}
+		jwks := adapter.GetJSONWebKeySet()
+		require.Len(t, jwks.Keys, 2)
+		require.Equal(t, "new", jwks.Keys[0].KeyID)
+		require.Equal(t, "OKP", jwks.Keys[0].KeyType)
+		require.Equal(t, "EdDSA", jwks.Keys[0].Algorithm)
+	})
+
+	t.Run("sign", func(t *testing.T) {
+		writeKeysManifest(t, path, &keysManifest{
+			Keys: []*keysManifestEntry{
+				{ID: "old", Algorithm: "EdDSA", File: "old.pem", Status: "verify"},
+				{ID: "new", Algorithm: "EdDSA", File: "new.pem", Status: "sign"},
+			},
+		}, time.Unix(2, 0))
+
+		require.Equal(t, "new", keyring.signingKey().id)
+		_, ok := keyring.verificationKey("old")
+		require.True(t, ok)
+	})
+
+	t.Run("retire", func(t *testing.T) {
+		writeKeysManifest(t, path, &keysManifest{
+			Keys: []*keysManifestEntry{
+				{ID: "old", Algorithm: "EdDSA", File: "old.pem", Status: "retired"},
+				{ID: "new", Algorithm: "EdDSA", File: "new.pem", Status: "sign"},
+			},
+		}, time.Unix(3, 0))
+
+		_, ok := keyring.verificationKey("old")
+		require.False(t, ok)
+		require.Len(t, keyring.publicKeys(), 1)
+	})
+
+	t.Run("broken manifest", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(path, []byte("{"), 0600))
+		require.NoError(t, os.Chtimes(path, time.Unix(4, 0), time.Unix(4, 0)))
+
+		require.Equal(t, "new", keyring.signingKey().id)
+	})
+}
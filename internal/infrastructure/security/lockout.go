@@ -0,0 +1,135 @@
+package security
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lzakharov/goss/internal/domain"
+	"go.uber.org/zap"
+)
+
+//LockoutConfig contains a login lockout configuration.
+//Reaching the maximum number of failed attempts locks the login out for Duration,
+//each next failed attempt doubles it up to MaxDuration.
+type LockoutConfig struct {
+	MaxAttempts      int64         `default:"5" validate:"min=1"`
+	MaxAttemptsPerIP int64         `default:"50" validate:"min=1"`
+	Window           time.Duration `default:"15m" validate:"required"`
+	Duration         time.Duration `default:"1m" validate:"required"`
+	MaxDuration      time.Duration `default:"1h" validate:"required"`
+}
+
+//CheckLoginAttempts returns a lockout error if logins for the username or from the ip are locked out.
+func (a *adapter) CheckLoginAttempts(username, ip string) error {
+	var retryAfter time.Duration
+
+	for _, key := range []string{
+		fmt.Sprintf(userLockoutKeyFormat, a.config.KeyPrefix, username),
+		fmt.Sprintf(ipLockoutKeyFormat, a.config.KeyPrefix, ip),
+	} {
+		ttl, err := a.redisClient.PTTL(key).Result()
+		if err != nil {
+			a.logger.Error("Error getting a lockout ttl!",
+				zap.String("key", key),
+				zap.Error(err))
+			return domain.ErrInternalSecurity
+		}
+
+		if ttl > retryAfter {
+			retryAfter = ttl
+		}
+	}
+
+	if retryAfter > 0 {
+		return &domain.LockoutError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+//AddFailedLoginAttempt counts the failed attempt and locks logins out once the limit is reached.
+func (a *adapter) AddFailedLoginAttempt(username, ip string) error {
+	if err := a.addFailedLoginAttempt(
+		fmt.Sprintf(userLoginAttemptsKeyFormat, a.config.KeyPrefix, username),
+		fmt.Sprintf(userLockoutKeyFormat, a.config.KeyPrefix, username),
+		a.config.Lockout.MaxAttempts,
+	); err != nil {
+		return err
+	}
+
+	return a.addFailedLoginAttempt(
+		fmt.Sprintf(ipLoginAttemptsKeyFormat, a.config.KeyPrefix, ip),
+		fmt.Sprintf(ipLockoutKeyFormat, a.config.KeyPrefix, ip),
+		a.config.Lockout.MaxAttemptsPerIP,
+	)
+}
+
+//ResetLoginAttempts forgets failed attempts for the username.
+func (a *adapter) ResetLoginAttempts(username string) error {
+	if err := a.redisClient.Del(
+		fmt.Sprintf(userLoginAttemptsKeyFormat, a.config.KeyPrefix, username),
+		fmt.Sprintf(userLockoutKeyFormat, a.config.KeyPrefix, username),
+	).Err(); err != nil {
+		a.logger.Error("Error deleting login attempts!",
+			zap.String("username", username),
+			zap.Error(err))
+		return domain.ErrInternalSecurity
+	}
+
+	return nil
+}
+
+func (a *adapter) addFailedLoginAttempt(attemptsKey, lockoutKey string, maxAttempts int64) error {
+	attempts, err := a.redisClient.Incr(attemptsKey).Result()
+	if err != nil {
+		a.logger.Error("Error incrementing login attempts!",
+			zap.String("key", attemptsKey),
+			zap.Error(err))
+		return domain.ErrInternalSecurity
+	}
+
+	var duration time.Duration
+	if attempts >= maxAttempts {
+		duration = lockoutDuration(a.config.Lockout, attempts-maxAttempts)
+	}
+
+	//The attempts outlive the lockout by the window, so the next failed attempt after it doubles the duration
+	//instead of starting over.
+	if err := a.redisClient.Expire(attemptsKey, duration+a.config.Lockout.Window).Err(); err != nil {
+		a.logger.Error("Error setting login attempts expiration!",
+			zap.String("key", attemptsKey),
+			zap.Error(err))
+		return domain.ErrInternalSecurity
+	}
+
+	if attempts < maxAttempts {
+		return nil
+	}
+
+	if err := a.redisClient.Set(lockoutKey, attempts, duration).Err(); err != nil {
+		a.logger.Error("Error setting a lockout!",
+			zap.String("key", lockoutKey),
+			zap.Error(err))
+		return domain.ErrInternalSecurity
+	}
+
+	a.logger.Warn("Login locked out.",
+		zap.String("key", lockoutKey),
+		zap.Int64("attempts", attempts),
+		zap.Duration("duration", duration))
+
+	return nil
+}
+
+//lockoutDuration returns the lockout duration doubled for each attempt over the limit.
+func lockoutDuration(config *LockoutConfig, excess int64) time.Duration {
+	duration := config.Duration
+	for i := int64(0); i < excess && duration < config.MaxDuration; i++ {
+		duration *= 2
+	}
+
+	if duration > config.MaxDuration {
+		return config.MaxDuration
+	}
+	return duration
+}
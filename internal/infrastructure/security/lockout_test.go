@@ -0,0 +1,186 @@
+package security
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"github.com/lzakharov/goss/internal/domain"
+	"go.uber.org/zap"
+)
+
+func TestAdapter_CheckLoginAttempts(t *testing.T) {
+	logger := zap.NewExample()
+
+	t.Run("without lockout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().PTTL("authlockout:user:alice").Return(redis.NewDurationResult(-2*time.Millisecond, nil))
+		redisClient.EXPECT().PTTL("authlockout:ip:192.0.2.1").Return(redis.NewDurationResult(-2*time.Millisecond, nil))
+		adapter := &adapter{
+			logger:      logger,
+			config:      config,
+			redisClient: redisClient,
+		}
+
+		require.NoError(t, adapter.CheckLoginAttempts("alice", "192.0.2.1"))
+	})
+
+	t.Run("with locked out ip", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().PTTL("authlockout:user:alice").Return(redis.NewDurationResult(10*time.Second, nil))
+		redisClient.EXPECT().PTTL("authlockout:ip:192.0.2.1").Return(redis.NewDurationResult(time.Minute, nil))
+		adapter := &adapter{
+			logger:      logger,
+			config:      config,
+			redisClient: redisClient,
+		}
+
+		err := adapter.CheckLoginAttempts("alice", "192.0.2.1")
+		require.Equal(t, &domain.LockoutError{RetryAfter: time.Minute}, err)
+		require.True(t, errors.Is(err, domain.ErrTooManyAttempts))
+	})
+
+	t.Run("with broken redis", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().PTTL("authlockout:user:alice").Return(redis.NewDurationResult(0, errors.New("redis error")))
+		adapter := &adapter{
+			logger:      logger,
+			config:      config,
+			redisClient: redisClient,
+		}
+
+		require.Equal(t, domain.ErrInternalSecurity, adapter.CheckLoginAttempts("alice", "192.0.2.1"))
+	})
+}
+
+func TestAdapter_AddFailedLoginAttempt(t *testing.T) {
+	logger := zap.NewExample()
+
+	t.Run("below the limit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().Incr("authattempts:user:alice").Return(redis.NewIntResult(1, nil))
+		redisClient.EXPECT().Expire("authattempts:user:alice", config.Lockout.Window).Return(redis.NewBoolResult(true, nil))
+		redisClient.EXPECT().Incr("authattempts:ip:192.0.2.1").Return(redis.NewIntResult(1, nil))
+		redisClient.EXPECT().Expire("authattempts:ip:192.0.2.1", config.Lockout.Window).Return(redis.NewBoolResult(true, nil))
+		adapter := &adapter{
+			logger:      logger,
+			config:      config,
+			redisClient: redisClient,
+		}
+
+		require.NoError(t, adapter.AddFailedLoginAttempt("alice", "192.0.2.1"))
+	})
+
+	t.Run("over the limit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().Incr("authattempts:user:alice").Return(redis.NewIntResult(5, nil))
+		redisClient.EXPECT().Expire("authattempts:user:alice", 4*time.Minute+config.Lockout.Window).Return(redis.NewBoolResult(true, nil))
+		redisClient.EXPECT().Set("authlockout:user:alice", int64(5), 4*time.Minute).Return(redis.NewStatusResult("ok", nil))
+		redisClient.EXPECT().Incr("authattempts:ip:192.0.2.1").Return(redis.NewIntResult(5, nil))
+		redisClient.EXPECT().Expire("authattempts:ip:192.0.2.1", config.Lockout.Window).Return(redis.NewBoolResult(true, nil))
+		adapter := &adapter{
+			logger:      logger,
+			config:      config,
+			redisClient: redisClient,
+		}
+
+		require.NoError(t, adapter.AddFailedLoginAttempt("alice", "192.0.2.1"))
+	})
+
+	t.Run("escalation past the window", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		lockoutConfig := &LockoutConfig{
+			MaxAttempts:      5,
+			MaxAttemptsPerIP: 50,
+			Window:           15 * time.Minute,
+			Duration:         time.Minute,
+			MaxDuration:      time.Hour,
+		}
+		securityConfig := *config
+		securityConfig.Lockout = lockoutConfig
+
+		var attempts int64
+		var attemptsTTL, duration time.Duration
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().Incr("authattempts:user:alice").DoAndReturn(func(string) *redis.IntCmd {
+			attempts++
+			return redis.NewIntResult(attempts, nil)
+		}).AnyTimes()
+		redisClient.EXPECT().Expire("authattempts:user:alice", gomock.Any()).DoAndReturn(func(_ string, ttl time.Duration) *redis.BoolCmd {
+			attemptsTTL = ttl
+			return redis.NewBoolResult(true, nil)
+		}).AnyTimes()
+		redisClient.EXPECT().Set("authlockout:user:alice", gomock.Any(), gomock.Any()).DoAndReturn(func(_ string, _ interface{}, ttl time.Duration) *redis.StatusCmd {
+			duration = ttl
+			return redis.NewStatusResult("ok", nil)
+		}).AnyTimes()
+		redisClient.EXPECT().Incr("authattempts:ip:192.0.2.1").Return(redis.NewIntResult(1, nil)).AnyTimes()
+		redisClient.EXPECT().Expire("authattempts:ip:192.0.2.1", lockoutConfig.Window).Return(redis.NewBoolResult(true, nil)).AnyTimes()
+		adapter := &adapter{
+			logger:      logger,
+			config:      &securityConfig,
+			redisClient: redisClient,
+		}
+
+		for i := int64(0); i < lockoutConfig.MaxAttempts+10; i++ {
+			require.NoError(t, adapter.AddFailedLoginAttempt("alice", "192.0.2.1"))
+
+			//The next attempt comes right after the lockout expires, the attempts must still be counted.
+			require.True(t, attemptsTTL > duration)
+		}
+
+		require.Equal(t, lockoutConfig.MaxDuration, duration)
+		require.Equal(t, lockoutConfig.MaxDuration+lockoutConfig.Window, attemptsTTL)
+	})
+
+	t.Run("with broken redis", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		redisClient := NewMockRedisClient(ctrl)
+		redisClient.EXPECT().Incr("authattempts:user:alice").Return(redis.NewIntResult(0, errors.New("redis error")))
+		adapter := &adapter{
+			logger:      logger,
+			config:      config,
+			redisClient: redisClient,
+		}
+
+		require.Equal(t, domain.ErrInternalSecurity, adapter.AddFailedLoginAttempt("alice", "192.0.2.1"))
+	})
+}
+
+func TestAdapter_ResetLoginAttempts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	redisClient := NewMockRedisClient(ctrl)
+	redisClient.EXPECT().Del("authattempts:user:alice", "authlockout:user:alice").Return(redis.NewIntResult(2, nil))
+	adapter := &adapter{
+		logger:      zap.NewExample(),
+		config:      config,
+		redisClient: redisClient,
+	}
+
+	require.NoError(t, adapter.ResetLoginAttempts("alice"))
+}
+
+func TestLockoutDuration(t *testing.T) {
+	require.Equal(t, time.Minute, lockoutDuration(config.Lockout, 0))
+	require.Equal(t, 2*time.Minute, lockoutDuration(config.Lockout, 1))
+	require.Equal(t, 8*time.Minute, lockoutDuration(config.Lockout, 3))
+	require.Equal(t, 10*time.Minute, lockoutDuration(config.Lockout, 4))
+	require.Equal(t, 10*time.Minute, lockoutDuration(config.Lockout, 1000))
+}
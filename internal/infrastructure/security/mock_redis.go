@@ -34,32 +34,92 @@ func (m *MockRedisClient) EXPECT() *MockRedisClientMockRecorder {
 	return m.recorder
 }
 
-// Ping mocks base method
-func (m *MockRedisClient) Ping() *redis.StatusCmd {
+// Del mocks base method
+func (m *MockRedisClient) Del(keys ...string) *redis.IntCmd {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Ping")
-	ret0, _ := ret[0].(*redis.StatusCmd)
+	varargs := []interface{}{}
+	for _, a := range keys {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Del", varargs...)
+	ret0, _ := ret[0].(*redis.IntCmd)
 	return ret0
 }
 
-// Ping indicates an expected call of Ping
-func (mr *MockRedisClientMockRecorder) Ping() *gomock.Call {
+// Del indicates an expected call of Del
+func (mr *MockRedisClientMockRecorder) Del(keys ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockRedisClient)(nil).Ping))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Del", reflect.TypeOf((*MockRedisClient)(nil).Del), keys...)
 }
 
-// Set mocks base method
-func (m *MockRedisClient) Set(key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+// Eval mocks base method
+func (m *MockRedisClient) Eval(script string, keys []string, args ...interface{}) *redis.Cmd {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Set", key, value, expiration)
-	ret0, _ := ret[0].(*redis.StatusCmd)
+	varargs := []interface{}{script, keys}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Eval", varargs...)
+	ret0, _ := ret[0].(*redis.Cmd)
 	return ret0
 }
 
-// Set indicates an expected call of Set
-func (mr *MockRedisClientMockRecorder) Set(key, value, expiration interface{}) *gomock.Call {
+// Eval indicates an expected call of Eval
+func (mr *MockRedisClientMockRecorder) Eval(script, keys interface{}, args ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockRedisClient)(nil).Set), key, value, expiration)
+	varargs := append([]interface{}{script, keys}, args...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Eval", reflect.TypeOf((*MockRedisClient)(nil).Eval), varargs...)
+}
+
+// EvalSha mocks base method
+func (m *MockRedisClient) EvalSha(sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{sha1, keys}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "EvalSha", varargs...)
+	ret0, _ := ret[0].(*redis.Cmd)
+	return ret0
+}
+
+// EvalSha indicates an expected call of EvalSha
+func (mr *MockRedisClientMockRecorder) EvalSha(sha1, keys interface{}, args ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{sha1, keys}, args...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EvalSha", reflect.TypeOf((*MockRedisClient)(nil).EvalSha), varargs...)
+}
+
+// Exists mocks base method
+func (m *MockRedisClient) Exists(keys ...string) *redis.IntCmd {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range keys {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Exists", varargs...)
+	ret0, _ := ret[0].(*redis.IntCmd)
+	return ret0
+}
+
+// Exists indicates an expected call of Exists
+func (mr *MockRedisClientMockRecorder) Exists(keys ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exists", reflect.TypeOf((*MockRedisClient)(nil).Exists), keys...)
+}
+
+// Expire mocks base method
+func (m *MockRedisClient) Expire(key string, expiration time.Duration) *redis.BoolCmd {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Expire", key, expiration)
+	ret0, _ := ret[0].(*redis.BoolCmd)
+	return ret0
+}
+
+// Expire indicates an expected call of Expire
+func (mr *MockRedisClientMockRecorder) Expire(key, expiration interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Expire", reflect.TypeOf((*MockRedisClient)(nil).Expire), key, expiration)
 }
 
 // Get mocks base method
@@ -76,20 +136,142 @@ func (mr *MockRedisClientMockRecorder) Get(key interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRedisClient)(nil).Get), key)
 }
 
-// Del mocks base method
-func (m *MockRedisClient) Del(keys ...string) *redis.IntCmd {
+// Incr mocks base method
+func (m *MockRedisClient) Incr(key string) *redis.IntCmd {
 	m.ctrl.T.Helper()
-	varargs := []interface{}{}
-	for _, a := range keys {
+	ret := m.ctrl.Call(m, "Incr", key)
+	ret0, _ := ret[0].(*redis.IntCmd)
+	return ret0
+}
+
+// Incr indicates an expected call of Incr
+func (mr *MockRedisClientMockRecorder) Incr(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Incr", reflect.TypeOf((*MockRedisClient)(nil).Incr), key)
+}
+
+// PTTL mocks base method
+func (m *MockRedisClient) PTTL(key string) *redis.DurationCmd {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PTTL", key)
+	ret0, _ := ret[0].(*redis.DurationCmd)
+	return ret0
+}
+
+// PTTL indicates an expected call of PTTL
+func (mr *MockRedisClientMockRecorder) PTTL(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PTTL", reflect.TypeOf((*MockRedisClient)(nil).PTTL), key)
+}
+
+// Ping mocks base method
+func (m *MockRedisClient) Ping() *redis.StatusCmd {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping")
+	ret0, _ := ret[0].(*redis.StatusCmd)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping
+func (mr *MockRedisClientMockRecorder) Ping() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockRedisClient)(nil).Ping))
+}
+
+// SAdd mocks base method
+func (m *MockRedisClient) SAdd(key string, members ...interface{}) *redis.IntCmd {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{key}
+	for _, a := range members {
 		varargs = append(varargs, a)
 	}
-	ret := m.ctrl.Call(m, "Del", varargs...)
+	ret := m.ctrl.Call(m, "SAdd", varargs...)
 	ret0, _ := ret[0].(*redis.IntCmd)
 	return ret0
 }
 
-// Del indicates an expected call of Del
-func (mr *MockRedisClientMockRecorder) Del(keys ...interface{}) *gomock.Call {
+// SAdd indicates an expected call of SAdd
+func (mr *MockRedisClientMockRecorder) SAdd(key interface{}, members ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Del", reflect.TypeOf((*MockRedisClient)(nil).Del), keys...)
+	varargs := append([]interface{}{key}, members...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SAdd", reflect.TypeOf((*MockRedisClient)(nil).SAdd), varargs...)
+}
+
+// SMembers mocks base method
+func (m *MockRedisClient) SMembers(key string) *redis.StringSliceCmd {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SMembers", key)
+	ret0, _ := ret[0].(*redis.StringSliceCmd)
+	return ret0
+}
+
+// SMembers indicates an expected call of SMembers
+func (mr *MockRedisClientMockRecorder) SMembers(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SMembers", reflect.TypeOf((*MockRedisClient)(nil).SMembers), key)
+}
+
+// SRem mocks base method
+func (m *MockRedisClient) SRem(key string, members ...interface{}) *redis.IntCmd {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{key}
+	for _, a := range members {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SRem", varargs...)
+	ret0, _ := ret[0].(*redis.IntCmd)
+	return ret0
+}
+
+// SRem indicates an expected call of SRem
+func (mr *MockRedisClientMockRecorder) SRem(key interface{}, members ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{key}, members...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SRem", reflect.TypeOf((*MockRedisClient)(nil).SRem), varargs...)
+}
+
+// ScriptExists mocks base method
+func (m *MockRedisClient) ScriptExists(hashes ...string) *redis.BoolSliceCmd {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range hashes {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ScriptExists", varargs...)
+	ret0, _ := ret[0].(*redis.BoolSliceCmd)
+	return ret0
+}
+
+// ScriptExists indicates an expected call of ScriptExists
+func (mr *MockRedisClientMockRecorder) ScriptExists(hashes ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScriptExists", reflect.TypeOf((*MockRedisClient)(nil).ScriptExists), hashes...)
+}
+
+// ScriptLoad mocks base method
+func (m *MockRedisClient) ScriptLoad(script string) *redis.StringCmd {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScriptLoad", script)
+	ret0, _ := ret[0].(*redis.StringCmd)
+	return ret0
+}
+
+// ScriptLoad indicates an expected call of ScriptLoad
+func (mr *MockRedisClientMockRecorder) ScriptLoad(script interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScriptLoad", reflect.TypeOf((*MockRedisClient)(nil).ScriptLoad), script)
+}
+
+// Set mocks base method
+func (m *MockRedisClient) Set(key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", key, value, expiration)
+	ret0, _ := ret[0].(*redis.StatusCmd)
+	return ret0
+}
+
+// Set indicates an expected call of Set
+func (mr *MockRedisClientMockRecorder) Set(key, value, expiration interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockRedisClient)(nil).Set), key, value, expiration)
 }
@@ -0,0 +1,192 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/lzakharov/goss/internal/domain"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+//PasswordConfig contains a password hashing configuration.
+type PasswordConfig struct {
+	Algorithm      string `default:"bcrypt" validate:"required,oneof=bcrypt argon2id"`
+	BcryptCost     int    `default:"10" validate:"min=4,max=31"`
+	Argon2Time     uint32 `default:"1" validate:"min=1,max=16"`
+	Argon2Memory   uint32 `default:"65536" validate:"min=8,max=1048576"`
+	Argon2Threads  uint8  `default:"4" validate:"min=1,max=64"`
+	AllowPlaintext bool   `default:"false"`
+}
+
+//NewPasswordHasher creates a new password hasher.
+func NewPasswordHasher(config *PasswordConfig) domain.PasswordHasher {
+	return &passwordHasher{config: config}
+}
+
+type passwordHasher struct {
+	config *PasswordConfig
+}
+
+type argon2Params struct {
+	version int
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+//Hash hashes the password with the configured algorithm.
+func (h *passwordHasher) Hash(password string) (string, error) {
+	switch h.config.Algorithm {
+	case algorithmArgon2id:
+		return h.hashArgon2id(password)
+	default:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.config.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+}
+
+//Compare compares the hash with the password.
+//Hashes of unknown format are treated as legacy plaintext passwords only while the plaintext migration is allowed,
+//otherwise they are rejected.
+func (h *passwordHasher) Compare(hash, password string) error {
+	switch {
+	case isBcryptHash(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return domain.ErrInvalidCredentials
+		}
+		if err != nil {
+			return domain.ErrInternalSecurity
+		}
+		return nil
+	case isArgon2idHash(hash):
+		params, err := decodeArgon2id(hash)
+		if err != nil {
+			return domain.ErrInternalSecurity
+		}
+		key := argon2.IDKey([]byte(password), params.salt, params.time, params.memory, params.threads, uint32(len(params.key)))
+		if subtle.ConstantTimeCompare(key, params.key) != 1 {
+			return domain.ErrInvalidCredentials
+		}
+		return nil
+	case h.config.AllowPlaintext:
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(password)) != 1 {
+			return domain.ErrInvalidCredentials
+		}
+		return nil
+	default:
+		return domain.ErrInvalidCredentials
+	}
+}
+
+//NeedsRehash returns true if the hash is a legacy plaintext password
+//or was produced with another algorithm or cost parameters.
+func (h *passwordHasher) NeedsRehash(hash string) bool {
+	switch h.config.Algorithm {
+	case algorithmArgon2id:
+		if !isArgon2idHash(hash) {
+			return true
+		}
+		params, err := decodeArgon2id(hash)
+		if err != nil {
+			return true
+		}
+		return params.version != argon2.Version ||
+			params.time != h.config.Argon2Time ||
+			params.memory != h.config.Argon2Memory ||
+			params.threads != h.config.Argon2Threads
+	default:
+		if !isBcryptHash(hash) {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return true
+		}
+		return cost != h.config.BcryptCost
+	}
+}
+
+func (h *passwordHasher) hashArgon2id(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.config.Argon2Time, h.config.Argon2Memory, h.config.Argon2Threads, argon2KeyLength)
+
+	return fmt.Sprintf(argon2idFormat,
+		argon2.Version,
+		h.config.Argon2Memory,
+		h.config.Argon2Time,
+		h.config.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func isArgon2idHash(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
+
+func decodeArgon2id(hash string) (*argon2Params, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("invalid argon2id hash format")
+	}
+
+	params := new(argon2Params)
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &params.version); err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, err
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, err
+	}
+
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+//validate rejects parameters the service never produces, so a tampered hash can't match every password
+//or exhaust the memory.
+func (p *argon2Params) validate() error {
+	switch {
+	case len(p.salt) == 0:
+		return fmt.Errorf("empty argon2id salt")
+	case len(p.key) != argon2KeyLength:
+		return fmt.Errorf("invalid argon2id key length %d", len(p.key))
+	case p.time < 1 || p.time > argon2MaxTime:
+		return fmt.Errorf("invalid argon2id time %d", p.time)
+	case p.memory < 8 || p.memory > argon2MaxMemory:
+		return fmt.Errorf("invalid argon2id memory %d", p.memory)
+	case p.threads < 1 || p.threads > argon2MaxThreads:
+		return fmt.Errorf("invalid argon2id threads %d", p.threads)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/lzakharov/goss/internal/domain"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	bcryptConfig = &PasswordConfig{
+		Algorithm:  "bcrypt",
+		BcryptCost: 4,
+	}
+
+	argon2idConfig = &PasswordConfig{
+		Algorithm:     "argon2id",
+		Argon2Time:    1,
+		Argon2Memory:  64,
+		Argon2Threads: 1,
+	}
+)
+
+func TestPasswordHasher(t *testing.T) {
+	for _, config := range []*PasswordConfig{bcryptConfig, argon2idConfig} {
+		t.Run(config.Algorithm, func(t *testing.T) {
+			hasher := NewPasswordHasher(config)
+
+			hash, err := hasher.Hash("password")
+			require.NoError(t, err)
+			require.NotEqual(t, "password", hash)
+
+			require.NoError(t, hasher.Compare(hash, "password"))
+			require.Equal(t, domain.ErrInvalidCredentials, hasher.Compare(hash, "wrong"))
+			require.False(t, hasher.NeedsRehash(hash))
+		})
+	}
+}
+
+func TestPasswordHasher_Compare(t *testing.T) {
+	hasher := NewPasswordHasher(bcryptConfig)
+
+	t.Run("legacy plaintext password", func(t *testing.T) {
+		hasher := NewPasswordHasher(&PasswordConfig{Algorithm: "bcrypt", BcryptCost: 4, AllowPlaintext: true})
+
+		require.NoError(t, hasher.Compare("password", "password"))
+		require.Equal(t, domain.ErrInvalidCredentials, hasher.Compare("password", "wrong"))
+	})
+
+	t.Run("legacy plaintext password not allowed", func(t *testing.T) {
+		require.Equal(t, domain.ErrInvalidCredentials, hasher.Compare("password", "password"))
+	})
+
+	t.Run("malformed argon2id hash", func(t *testing.T) {
+		require.Equal(t, domain.ErrInternalSecurity, hasher.Compare("$argon2id$broken", "password"))
+	})
+
+	t.Run("argon2id hash with an empty key", func(t *testing.T) {
+		require.Equal(t, domain.ErrInternalSecurity, hasher.Compare("$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$", ""))
+		require.Equal(t, domain.ErrInternalSecurity, hasher.Compare("$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$", "password"))
+	})
+
+	t.Run("argon2id hash with an empty salt", func(t *testing.T) {
+		require.Equal(t, domain.ErrInternalSecurity, hasher.Compare("$argon2id$v=19$m=64,t=1,p=1$$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U", "password"))
+	})
+
+	t.Run("argon2id hash with too expensive parameters", func(t *testing.T) {
+		key := "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+
+		require.Equal(t, domain.ErrInternalSecurity, hasher.Compare("$argon2id$v=19$m=4294967295,t=1,p=1$c2FsdHNhbHQ$"+key, "password"))
+		require.Equal(t, domain.ErrInternalSecurity, hasher.Compare("$argon2id$v=19$m=64,t=4294967295,p=1$c2FsdHNhbHQ$"+key, "password"))
+		require.Equal(t, domain.ErrInternalSecurity, hasher.Compare("$argon2id$v=19$m=64,t=1,p=255$c2FsdHNhbHQ$"+key, "password"))
+	})
+}
+
+func TestPasswordHasher_NeedsRehash(t *testing.T) {
+	bcryptHash, err := NewPasswordHasher(bcryptConfig).Hash("password")
+	require.NoError(t, err)
+	argon2idHash, err := NewPasswordHasher(argon2idConfig).Hash("password")
+	require.NoError(t, err)
+
+	t.Run("legacy plaintext password", func(t *testing.T) {
+		require.True(t, NewPasswordHasher(bcryptConfig).NeedsRehash("password"))
+		require.True(t, NewPasswordHasher(argon2idConfig).NeedsRehash("password"))
+	})
+
+	t.Run("another algorithm", func(t *testing.T) {
+		require.True(t, NewPasswordHasher(bcryptConfig).NeedsRehash(argon2idHash))
+		require.True(t, NewPasswordHasher(argon2idConfig).NeedsRehash(bcryptHash))
+	})
+
+	t.Run("another cost", func(t *testing.T) {
+		require.True(t, NewPasswordHasher(&PasswordConfig{Algorithm: "bcrypt", BcryptCost: 5}).NeedsRehash(bcryptHash))
+	})
+}
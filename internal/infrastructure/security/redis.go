@@ -14,6 +14,17 @@ type RedisClient interface {
 	Set(key string, value interface{}, expiration time.Duration) *redis.StatusCmd
 	Get(key string) *redis.StringCmd
 	Del(keys ...string) *redis.IntCmd
+	Exists(keys ...string) *redis.IntCmd
+	Incr(key string) *redis.IntCmd
+	PTTL(key string) *redis.DurationCmd
+	Expire(key string, expiration time.Duration) *redis.BoolCmd
+	SAdd(key string, members ...interface{}) *redis.IntCmd
+	SRem(key string, members ...interface{}) *redis.IntCmd
+	SMembers(key string) *redis.StringSliceCmd
+	Eval(script string, keys []string, args ...interface{}) *redis.Cmd
+	EvalSha(sha1 string, keys []string, args ...interface{}) *redis.Cmd
+	ScriptExists(hashes ...string) *redis.BoolSliceCmd
+	ScriptLoad(script string) *redis.StringCmd
 }
 
 // RedisClientConfig contains a redis factory configuration.
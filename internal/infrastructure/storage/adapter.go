@@ -2,27 +2,37 @@ package storage
 
 import (
 	"database/sql"
+	"sync"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/lzakharov/goss/internal/domain"
 	"go.uber.org/zap"
-
-	_ "github.com/lib/pq" // postgres driver
 )
 
 //NewAdapter creates a new storage adapter.
-func NewAdapter(logger *zap.Logger, db *sqlx.DB) domain.Storage {
+//Audit events are hashed with the audit key.
+func NewAdapter(logger *zap.Logger, db *sqlx.DB, hasher domain.PasswordHasher, encrypter domain.Encrypter, auditKey []byte) domain.Storage {
 	adapter := &adapter{
-		logger: logger,
-		db:     db,
+		logger:    logger,
+		db:        db,
+		hasher:    hasher,
+		encrypter: encrypter,
+		auditKey:  auditKey,
 	}
 
 	return adapter
 }
 
 type adapter struct {
-	logger *zap.Logger
-	db     *sqlx.DB
+	logger    *zap.Logger
+	db        *sqlx.DB
+	hasher    domain.PasswordHasher
+	encrypter domain.Encrypter
+	auditKey  []byte
+
+	dummyHashOnce sync.Once
+	dummyHash     string
 }
 
 //IsAlive returns true if the adapter can ping it's database.
@@ -52,23 +62,463 @@ func (a *adapter) GetUser(userID int64) (*domain.User, error) {
 }
 
 //GetUserByCredentials gets user by the credentials.
+//Passwords stored with outdated parameters or in plaintext are rehashed on success.
 func (a *adapter) GetUserByCredentials(credentials *domain.Credentials) (*domain.User, error) {
-	user := new(domain.User)
+	user := new(userWithPassword)
 
 	if err := a.db.QueryRowx(
-		getUserByCredentialsQuery,
+		getUserByUsernameQuery,
 		credentials.Username,
-		credentials.Password,
 	).StructScan(user); err != nil {
 		a.logger.Error("Error getting a user by the credentials!",
 			zap.String("username", credentials.Username),
 			zap.Error(err))
 
 		if err == sql.ErrNoRows {
+			a.compareDummyHash(credentials.Password)
 			return nil, domain.ErrInvalidCredentials
 		}
 		return nil, domain.ErrInternalStorage
 	}
 
+	if err := a.hasher.Compare(user.Password, credentials.Password); err != nil {
+		a.logger.Error("Error comparing the password with the stored hash!",
+			zap.String("username", credentials.Username),
+			zap.Error(err))
+		return nil, err
+	}
+
+	if a.hasher.NeedsRehash(user.Password) {
+		a.rehashPassword(user.ID, credentials.Password)
+	}
+
+	return &user.User, nil
+}
+
+//CreateUser creates a new user with the specified role.
+func (a *adapter) CreateUser(credentials *domain.Credentials, role string) (*domain.User, error) {
+	hash, err := a.hasher.Hash(credentials.Password)
+	if err != nil {
+		a.logger.Error("Error hashing user's password!",
+			zap.String("username", credentials.Username),
+			zap.Error(err))
+		return nil, domain.ErrInternalSecurity
+	}
+
+	user := new(domain.User)
+
+	if err := a.db.QueryRowx(
+		createUserQuery,
+		credentials.Username,
+		hash,
+		role,
+	).StructScan(user); err != nil {
+		a.logger.Error("Error creating a new user!",
+			zap.String("username", credentials.Username),
+			zap.Error(err))
+
+		if isUniqueViolation(err, usernameConstraint) {
+			return nil, domain.ErrUserAlreadyExists
+		}
+		return nil, domain.ErrInternalStorage
+	}
+
 	return user, nil
 }
+
+//UpdateUser updates the username and the role of the user.
+func (a *adapter) UpdateUser(user *domain.User) (*domain.User, error) {
+	updated := new(domain.User)
+
+	if err := a.db.QueryRowx(
+		updateUserQuery,
+		user.ID,
+		user.Username,
+		user.Role,
+	).StructScan(updated); err != nil {
+		a.logger.Error("Error updating the user!",
+			zap.Int64("userID", user.ID),
+			zap.Error(err))
+
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrNotFound
+		}
+		if isUniqueViolation(err, usernameConstraint) {
+			return nil, domain.ErrUserAlreadyExists
+		}
+		return nil, domain.ErrInternalStorage
+	}
+
+	return updated, nil
+}
+
+//SetUserDisabled disables or enables the user.
+func (a *adapter) SetUserDisabled(userID int64, disabled bool) error {
+	return a.execOne(domain.ErrNotFound, setUserDisabledQuery, userID, disabled)
+}
+
+//UpdateUserPassword hashes and saves a new password of the user.
+func (a *adapter) UpdateUserPassword(userID int64, password string) error {
+	hash, err := a.hasher.Hash(password)
+	if err != nil {
+		a.logger.Error("Error hashing user's password!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return domain.ErrInternalSecurity
+	}
+
+	return a.execOne(domain.ErrNotFound, updateUserPasswordQuery, userID, hash)
+}
+
+//DeleteUser deletes the user with all the user's data.
+func (a *adapter) DeleteUser(userID int64) error {
+	return a.execOne(domain.ErrNotFound, deleteUserQuery, userID)
+}
+
+//GetClient gets OAuth 2.0 client by the client id.
+func (a *adapter) GetClient(clientID string) (*domain.Client, error) {
+	client := new(oauthClient)
+
+	if err := a.db.QueryRowx(
+		getClientQuery,
+		clientID,
+	).StructScan(client); err != nil {
+		a.logger.Error("Error getting a client by id!",
+			zap.String("clientID", clientID),
+			zap.Error(err))
+
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrNotFound
+		}
+		return nil, domain.ErrInternalStorage
+	}
+
+	return client.toDomain(), nil
+}
+
+//GetClientByCredentials gets OAuth 2.0 client by the credentials.
+//Secrets stored with outdated parameters or in plaintext are rehashed on success.
+func (a *adapter) GetClientByCredentials(credentials *domain.ClientCredentials) (*domain.Client, error) {
+	client := new(clientWithSecret)
+
+	if err := a.db.QueryRowx(
+		getClientWithSecretQuery,
+		credentials.ClientID,
+	).StructScan(client); err != nil {
+		a.logger.Error("Error getting a client by the credentials!",
+			zap.String("clientID", credentials.ClientID),
+			zap.Error(err))
+
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrInvalidClient
+		}
+		return nil, domain.ErrInternalStorage
+	}
+
+	if err := a.hasher.Compare(client.Secret, credentials.ClientSecret); err != nil {
+		a.logger.Error("Error comparing the client secret with the stored hash!",
+			zap.String("clientID", credentials.ClientID),
+			zap.Error(err))
+
+		if err == domain.ErrInvalidCredentials {
+			return nil, domain.ErrInvalidClient
+		}
+		return nil, err
+	}
+
+	if a.hasher.NeedsRehash(client.Secret) {
+		a.rehashClientSecret(client.ID, credentials.ClientSecret)
+	}
+
+	return client.toDomain(), nil
+}
+
+//GetTOTP gets user's TOTP authenticator with the decrypted secret.
+func (a *adapter) GetTOTP(userID int64) (*domain.TOTP, error) {
+	totp := new(totp)
+
+	if err := a.db.QueryRowx(
+		getTOTPQuery,
+		userID,
+	).StructScan(totp); err != nil {
+		a.logger.Error("Error getting user's TOTP secret!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrNotFound
+		}
+		return nil, domain.ErrInternalStorage
+	}
+
+	secret, err := a.encrypter.Decrypt(totp.Secret)
+	if err != nil {
+		a.logger.Error("Error decrypting user's TOTP secret!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return nil, domain.ErrInternalSecurity
+	}
+
+	return &domain.TOTP{
+		Secret:    secret,
+		Confirmed: totp.Confirmed,
+		LastStep:  totp.LastStep,
+	}, nil
+}
+
+//SaveTOTP encrypts and saves a new unconfirmed TOTP secret replacing the previous unconfirmed one.
+func (a *adapter) SaveTOTP(userID int64, secret string) error {
+	encrypted, err := a.encrypter.Encrypt(secret)
+	if err != nil {
+		a.logger.Error("Error encrypting user's TOTP secret!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return domain.ErrInternalSecurity
+	}
+
+	return a.execOne(domain.ErrMFAAlreadyEnabled, saveTOTPQuery, userID, encrypted)
+}
+
+//ConfirmTOTP confirms user's TOTP secret with the time step of the first used code.
+func (a *adapter) ConfirmTOTP(userID int64, step int64) error {
+	return a.execOne(domain.ErrMFAAlreadyEnabled, confirmTOTPQuery, userID, step)
+}
+
+//UseTOTPStep marks the time step as used, an already used step means a replayed code.
+func (a *adapter) UseTOTPStep(userID int64, step int64) error {
+	return a.execOne(domain.ErrInvalidMFACode, useTOTPStepQuery, userID, step)
+}
+
+//DeleteTOTP deletes user's TOTP authenticator.
+func (a *adapter) DeleteTOTP(userID int64) error {
+	return a.execOne(domain.ErrNotFound, deleteTOTPQuery, userID)
+}
+
+//ReencryptTOTPSecrets decrypts all TOTP secrets, also with the previous keys, and encrypts them with the current key.
+//Secrets are locked in one transaction, so TOTP changes made meanwhile wait instead of being overwritten.
+func (a *adapter) ReencryptTOTPSecrets() (int64, error) {
+	tx, err := a.db.Beginx()
+	if err != nil {
+		a.logger.Error("Error beginning a transaction!", zap.Error(err))
+		return 0, domain.ErrInternalStorage
+	}
+
+	var secrets []totpSecret
+	if err := tx.Select(&secrets, listTOTPSecretsQuery); err != nil {
+		a.logger.Error("Error listing TOTP secrets!", zap.Error(err))
+		_ = tx.Rollback()
+		return 0, domain.ErrInternalStorage
+	}
+
+	for _, encrypted := range secrets {
+		userID := encrypted.UserID
+
+		secret, err := a.encrypter.Decrypt(encrypted.Secret)
+		if err != nil {
+			a.logger.Error("Error decrypting user's TOTP secret!",
+				zap.Int64("userID", userID),
+				zap.Error(err))
+			_ = tx.Rollback()
+			return 0, domain.ErrInternalSecurity
+		}
+
+		reencrypted, err := a.encrypter.Encrypt(secret)
+		if err != nil {
+			a.logger.Error("Error encrypting user's TOTP secret!",
+				zap.Int64("userID", userID),
+				zap.Error(err))
+			_ = tx.Rollback()
+			return 0, domain.ErrInternalSecurity
+		}
+
+		if _, err := tx.Exec(updateTOTPSecretQuery, userID, reencrypted); err != nil {
+			a.logger.Error("Error updating user's TOTP secret!",
+				zap.Int64("userID", userID),
+				zap.Error(err))
+			_ = tx.Rollback()
+			return 0, domain.ErrInternalStorage
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		a.logger.Error("Error committing re-encrypted TOTP secrets!", zap.Error(err))
+		return 0, domain.ErrInternalStorage
+	}
+
+	return int64(len(secrets)), nil
+}
+
+//ReplaceRecoveryCodes replaces all user's recovery codes with the new hashes.
+func (a *adapter) ReplaceRecoveryCodes(userID int64, hashes []string) error {
+	tx, err := a.db.Beginx()
+	if err != nil {
+		a.logger.Error("Error beginning a transaction!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return domain.ErrInternalStorage
+	}
+
+	if _, err := tx.Exec(deleteRecoveryCodesQuery, userID); err != nil {
+		a.logger.Error("Error deleting user's recovery codes!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		_ = tx.Rollback()
+		return domain.ErrInternalStorage
+	}
+
+	if len(hashes) > 0 {
+		if _, err := tx.Exec(insertRecoveryCodesQuery, userID, pq.Array(hashes)); err != nil {
+			a.logger.Error("Error inserting user's recovery codes!",
+				zap.Int64("userID", userID),
+				zap.Error(err))
+			_ = tx.Rollback()
+			return domain.ErrInternalStorage
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		a.logger.Error("Error committing user's recovery codes!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return domain.ErrInternalStorage
+	}
+
+	return nil
+}
+
+//UseRecoveryCode marks the unused recovery code as used.
+func (a *adapter) UseRecoveryCode(userID int64, hash string) error {
+	return a.execOne(domain.ErrInvalidMFACode, useRecoveryCodeQuery, userID, hash)
+}
+
+//CountRecoveryCodes returns the number of user's unused recovery codes.
+func (a *adapter) CountRecoveryCodes(userID int64) (int, error) {
+	var count int
+
+	if err := a.db.QueryRowx(
+		countRecoveryCodesQuery,
+		userID,
+	).Scan(&count); err != nil {
+		a.logger.Error("Error counting user's recovery codes!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return 0, domain.ErrInternalStorage
+	}
+
+	return count, nil
+}
+
+//GetPermissions gets permissions of the role and all its parent roles.
+func (a *adapter) GetPermissions(role string) ([]string, error) {
+	permissions := make([]string, 0)
+
+	if err := a.db.Select(&permissions, getPermissionsQuery, role); err != nil {
+		a.logger.Error("Error getting role permissions!",
+			zap.String("role", role),
+			zap.Error(err))
+		return nil, domain.ErrInternalStorage
+	}
+
+	return permissions, nil
+}
+
+//RoleExists returns true if the role is defined.
+func (a *adapter) RoleExists(role string) (bool, error) {
+	var exists bool
+
+	if err := a.db.QueryRowx(
+		roleExistsQuery,
+		role,
+	).Scan(&exists); err != nil {
+		a.logger.Error("Error checking the role!",
+			zap.String("role", role),
+			zap.Error(err))
+		return false, domain.ErrInternalStorage
+	}
+
+	return exists, nil
+}
+
+//execOne executes the query expected to affect exactly one row and returns errNoRows otherwise.
+func (a *adapter) execOne(errNoRows error, query string, args ...interface{}) error {
+	result, err := a.db.Exec(query, args...)
+	if err != nil {
+		a.logger.Error("Error executing a query!",
+			zap.String("query", query),
+			zap.Error(err))
+		return domain.ErrInternalStorage
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		a.logger.Error("Error getting the number of affected rows!",
+			zap.String("query", query),
+			zap.Error(err))
+		return domain.ErrInternalStorage
+	}
+
+	if affected == 0 {
+		return errNoRows
+	}
+
+	return nil
+}
+
+func (a *adapter) rehashPassword(userID int64, password string) {
+	hash, err := a.hasher.Hash(password)
+	if err != nil {
+		a.logger.Error("Error rehashing user's password!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return
+	}
+
+	if _, err := a.db.Exec(updateUserPasswordQuery, userID, hash); err != nil {
+		a.logger.Error("Error updating user's password hash!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return
+	}
+
+	a.logger.Info("User's password rehashed.", zap.Int64("userID", userID))
+}
+
+func (a *adapter) rehashClientSecret(clientID int64, secret string) {
+	hash, err := a.hasher.Hash(secret)
+	if err != nil {
+		a.logger.Error("Error rehashing client secret!",
+			zap.Int64("clientID", clientID),
+			zap.Error(err))
+		return
+	}
+
+	if _, err := a.db.Exec(updateClientSecretQuery, clientID, hash); err != nil {
+		a.logger.Error("Error updating client secret hash!",
+			zap.Int64("clientID", clientID),
+			zap.Error(err))
+		return
+	}
+
+	a.logger.Info("Client secret rehashed.", zap.Int64("clientID", clientID))
+}
+
+//compareDummyHash compares the password with a hash of the dummy password made with the current parameters,
+//so logins of unknown users take as long as logins with a wrong password and don't reveal existing usernames.
+func (a *adapter) compareDummyHash(password string) {
+	a.dummyHashOnce.Do(func() {
+		hash, err := a.hasher.Hash(dummyPassword)
+		if err != nil {
+			a.logger.Error("Error hashing the dummy password!", zap.Error(err))
+			return
+		}
+		a.dummyHash = hash
+	})
+
+	_ = a.hasher.Compare(a.dummyHash, password)
+}
+
+//isUniqueViolation returns true if the error is a violation of the unique constraint.
+func isUniqueViolation(err error, constraint string) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == uniqueViolation && pqErr.Constraint == constraint
+}
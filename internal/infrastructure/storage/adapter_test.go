@@ -2,29 +2,42 @@ package storage
 
 import (
 	"database/sql"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 	"github.com/lzakharov/goss/internal/domain"
 	"go.uber.org/zap"
 )
 
 var (
-	userColumns = []string{"id", "username", "role"}
+	userColumns             = []string{"id", "username", "role"}
+	userWithPasswordColumns = []string{"id", "username", "role", "password"}
+	clientColumns           = []string{"id", "client_id", "scopes", "redirect_uris", "public"}
+	clientWithSecretColumns = []string{"id", "client_id", "scopes", "redirect_uris", "public", "secret"}
 )
 
 func TestNewAdapter(t *testing.T) {
+	ctrl := gomock.NewController(t)
 	logger := zap.NewExample()
 	db := new(sqlx.DB)
+	hasher := domain.NewMockPasswordHasher(ctrl)
+	encrypter := domain.NewMockEncrypter(ctrl)
 
 	expected := &adapter{
-		logger: logger,
-		db:     db,
+		logger:    logger,
+		db:        db,
+		hasher:    hasher,
+		encrypter: encrypter,
+		auditKey:  []byte("auditKey"),
 	}
 
-	actual := NewAdapter(logger, db)
+	actual := NewAdapter(logger, db, hasher, encrypter, []byte("auditKey"))
 
 	require.Equal(t, expected, actual)
 }
@@ -84,50 +97,1231 @@ func TestAdapter_GetUser(t *testing.T) {
 }
 
 func TestAdapter_GetUserByCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
 	logger := zap.NewExample()
 
 	adapter := &adapter{
 		logger: logger,
 	}
 
+	credentials := &domain.Credentials{
+		Username: "alice",
+		Password: "password",
+	}
+
+	expected := &domain.User{
+		ID:       1,
+		Username: "alice",
+		Role:     "client",
+	}
+
 	t.Run("valid credentials", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		require.NoError(t, err)
 		adapter.db = sqlx.NewDb(db, "postgres")
 
-		mock.ExpectQuery(`^SELECT (.+) FROM "user" WHERE username = (.+) AND password = (.+)$`).
-			WillReturnRows(sqlmock.NewRows(userColumns).FromCSVString("1,alice,client"))
+		mock.ExpectQuery(`^SELECT (.+) FROM "user" WHERE username = (.+)$`).
+			WithArgs("alice").
+			WillReturnRows(sqlmock.NewRows(userWithPasswordColumns).FromCSVString("1,alice,client,hash"))
 
-		credentials := &domain.Credentials{
-			Username: "alice",
-			Password: "password",
-		}
+		hasher := domain.NewMockPasswordHasher(ctrl)
+		hasher.EXPECT().Compare("hash", "password").Return(nil)
+		hasher.EXPECT().NeedsRehash("hash").Return(false)
+		adapter.hasher = hasher
+
+		actual, err := adapter.GetUserByCredentials(credentials)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("legacy password", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^SELECT (.+) FROM "user" WHERE username = (.+)$`).
+			WithArgs("alice").
+			WillReturnRows(sqlmock.NewRows(userWithPasswordColumns).FromCSVString("1,alice,client,password"))
+		mock.ExpectExec(`^UPDATE "user" SET password = (.+) WHERE id = (.+)$`).
+			WithArgs(1, "hash").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		hasher := domain.NewMockPasswordHasher(ctrl)
+		hasher.EXPECT().Compare("password", "password").Return(nil)
+		hasher.EXPECT().NeedsRehash("password").Return(true)
+		hasher.EXPECT().Hash("password").Return("hash", nil)
+		adapter.hasher = hasher
+
+		actual, err := adapter.GetUserByCredentials(credentials)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid password", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^SELECT (.+) FROM "user" WHERE username = (.+)$`).
+			WithArgs("alice").
+			WillReturnRows(sqlmock.NewRows(userWithPasswordColumns).FromCSVString("1,alice,client,hash"))
+
+		hasher := domain.NewMockPasswordHasher(ctrl)
+		hasher.EXPECT().Compare("hash", "password").Return(domain.ErrInvalidCredentials)
+		adapter.hasher = hasher
+
+		_, err = adapter.GetUserByCredentials(credentials)
+		require.Equal(t, domain.ErrInvalidCredentials, err)
+	})
+
+	t.Run("nonexistent user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^SELECT (.+) FROM "user" WHERE username = (.+)$`).
+			WillReturnError(sql.ErrNoRows)
+
+		hasher := domain.NewMockPasswordHasher(ctrl)
+		hasher.EXPECT().Hash(dummyPassword).Return("dummyHash", nil)
+		hasher.EXPECT().Compare("dummyHash", "password").Return(domain.ErrInvalidCredentials)
+		adapter.hasher = hasher
+
+		_, err = adapter.GetUserByCredentials(credentials)
+		require.Equal(t, domain.ErrInvalidCredentials, err)
+	})
+}
+
+func TestAdapter_CreateUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+	}
+
+	credentials := &domain.Credentials{
+		Username: "alice",
+		Password: "password",
+	}
+
+	t.Run("new user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^INSERT INTO "user" (.+) VALUES (.+) RETURNING id, username, role, disabled$`).
+			WithArgs("alice", "hash", "user").
+			WillReturnRows(sqlmock.NewRows(userColumns).FromCSVString("1,alice,user"))
+
+		hasher := domain.NewMockPasswordHasher(ctrl)
+		hasher.EXPECT().Hash("password").Return("hash", nil)
+		adapter.hasher = hasher
 
 		expected := &domain.User{
 			ID:       1,
 			Username: "alice",
-			Role:     "client",
+			Role:     "user",
 		}
 
-		actual, err := adapter.GetUserByCredentials(credentials)
+		actual, err := adapter.CreateUser(credentials, "user")
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("existing username", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^INSERT INTO "user" (.+) VALUES (.+) RETURNING id, username, role, disabled$`).
+			WillReturnError(&pq.Error{Code: uniqueViolation, Constraint: usernameConstraint})
+
+		hasher := domain.NewMockPasswordHasher(ctrl)
+		hasher.EXPECT().Hash("password").Return("hash", nil)
+		adapter.hasher = hasher
+
+		_, err = adapter.CreateUser(credentials, "user")
+		require.Equal(t, domain.ErrUserAlreadyExists, err)
+	})
+
+	t.Run("existing id", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^INSERT INTO "user" (.+) VALUES (.+) RETURNING id, username, role, disabled$`).
+			WillReturnError(&pq.Error{Code: uniqueViolation, Constraint: "user_pk"})
+
+		hasher := domain.NewMockPasswordHasher(ctrl)
+		hasher.EXPECT().Hash("password").Return("hash", nil)
+		adapter.hasher = hasher
+
+		_, err = adapter.CreateUser(credentials, "user")
+		require.Equal(t, domain.ErrInternalStorage, err)
+	})
+}
+
+func TestAdapter_GetClient(t *testing.T) {
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+	}
+
+	t.Run("existing client", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^SELECT (.+) FROM oauth_client WHERE client_id = (.+)$`).
+			WithArgs("spa").
+			WillReturnRows(sqlmock.NewRows(clientColumns).
+				AddRow(2, "spa", "{users:read}", "{https://app.example.com/callback}", true))
+
+		expected := &domain.Client{
+			ID:           2,
+			ClientID:     "spa",
+			Scopes:       []string{"users:read"},
+			RedirectURIs: []string{"https://app.example.com/callback"},
+			Public:       true,
+		}
+
+		actual, err := adapter.GetClient("spa")
 		require.NoError(t, err)
 		require.Equal(t, expected, actual)
 	})
 
-	t.Run("invalid credentials", func(t *testing.T) {
+	t.Run("nonexistent client", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		require.NoError(t, err)
 		adapter.db = sqlx.NewDb(db, "postgres")
 
-		mock.ExpectQuery(`^SELECT (.+) FROM "user" WHERE username = (.+) AND password = (.+)$`).
+		mock.ExpectQuery(`^SELECT (.+) FROM oauth_client WHERE client_id = (.+)$`).
 			WillReturnError(sql.ErrNoRows)
 
-		credentials := &domain.Credentials{
-			Username: "alice",
-			Password: "password",
+		_, err = adapter.GetClient("spa")
+		require.Equal(t, domain.ErrNotFound, err)
+	})
+}
+
+func TestAdapter_GetClientByCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+	}
+
+	credentials := &domain.ClientCredentials{
+		ClientID:     "backend",
+		ClientSecret: "secret",
+	}
+
+	expected := &domain.Client{
+		ID:           1,
+		ClientID:     "backend",
+		Scopes:       []string{"users:read", "users:write"},
+		RedirectURIs: []string{},
+	}
+
+	t.Run("valid credentials", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^SELECT (.+) FROM oauth_client WHERE client_id = (.+)$`).
+			WithArgs("backend").
+			WillReturnRows(sqlmock.NewRows(clientWithSecretColumns).AddRow(1, "backend", "{users:read,users:write}", "{}", false, "hash"))
+
+		hasher := domain.NewMockPasswordHasher(ctrl)
+		hasher.EXPECT().Compare("hash", "secret").Return(nil)
+		hasher.EXPECT().NeedsRehash("hash").Return(false)
+		adapter.hasher = hasher
+
+		actual, err := adapter.GetClientByCredentials(credentials)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("legacy secret", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^SELECT (.+) FROM oauth_client WHERE client_id = (.+)$`).
+			WithArgs("backend").
+			WillReturnRows(sqlmock.NewRows(clientWithSecretColumns).AddRow(1, "backend", "{users:read,users:write}", "{}", false, "secret"))
+		mock.ExpectExec(`^UPDATE oauth_client SET secret = (.+) WHERE id = (.+)$`).
+			WithArgs(1, "hash").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		hasher := domain.NewMockPasswordHasher(ctrl)
+		hasher.EXPECT().Compare("secret", "secret").Return(nil)
+		hasher.EXPECT().NeedsRehash("secret").Return(true)
+		hasher.EXPECT().Hash("secret").Return("hash", nil)
+		adapter.hasher = hasher
+
+		actual, err := adapter.GetClientByCredentials(credentials)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid secret", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^SELECT (.+) FROM oauth_client WHERE client_id = (.+)$`).
+			WithArgs("backend").
+			WillReturnRows(sqlmock.NewRows(clientWithSecretColumns).AddRow(1, "backend", "{}", "{}", false, "hash"))
+
+		hasher := domain.NewMockPasswordHasher(ctrl)
+		hasher.EXPECT().Compare("hash", "secret").Return(domain.ErrInvalidCredentials)
+		adapter.hasher = hasher
+
+		_, err = adapter.GetClientByCredentials(credentials)
+		require.Equal(t, domain.ErrInvalidClient, err)
+	})
+
+	t.Run("nonexistent client", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^SELECT (.+) FROM oauth_client WHERE client_id = (.+)$`).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err = adapter.GetClientByCredentials(credentials)
+		require.Equal(t, domain.ErrInvalidClient, err)
+	})
+}
+
+func TestAdapter_GetTOTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+	}
+
+	t.Run("existing secret", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^SELECT (.+) FROM user_totp WHERE user_id = (.+)$`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"secret", "confirmed", "last_step"}).AddRow("encrypted", true, 42))
+
+		encrypter := domain.NewMockEncrypter(ctrl)
+		encrypter.EXPECT().Decrypt("encrypted").Return("secret", nil)
+		adapter.encrypter = encrypter
+
+		expected := &domain.TOTP{
+			Secret:    "secret",
+			Confirmed: true,
+			LastStep:  42,
 		}
 
-		_, err = adapter.GetUserByCredentials(credentials)
-		require.Error(t, err)
+		actual, err := adapter.GetTOTP(1)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("nonexistent secret", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^SELECT (.+) FROM user_totp WHERE user_id = (.+)$`).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err = adapter.GetTOTP(1)
+		require.Equal(t, domain.ErrNotFound, err)
+	})
+}
+
+func TestAdapter_SaveTOTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+	}
+
+	t.Run("new secret", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectExec(`^INSERT INTO user_totp (.+) VALUES (.+) ON CONFLICT (.+)$`).
+			WithArgs(1, "encrypted").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		encrypter := domain.NewMockEncrypter(ctrl)
+		encrypter.EXPECT().Encrypt("secret").Return("encrypted", nil)
+		adapter.encrypter = encrypter
+
+		require.NoError(t, adapter.SaveTOTP(1, "secret"))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("confirmed secret", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectExec(`^INSERT INTO user_totp (.+) VALUES (.+) ON CONFLICT (.+)$`).
+			WithArgs(1, "encrypted").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		encrypter := domain.NewMockEncrypter(ctrl)
+		encrypter.EXPECT().Encrypt("secret").Return("encrypted", nil)
+		adapter.encrypter = encrypter
+
+		require.Equal(t, domain.ErrMFAAlreadyEnabled, adapter.SaveTOTP(1, "secret"))
+	})
+}
+
+func TestAdapter_ReencryptTOTPSecrets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger := zap.NewExample()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	adapter := &adapter{
+		logger: logger,
+		db:     sqlx.NewDb(db, "postgres"),
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^SELECT user_id, secret FROM user_totp ORDER BY user_id FOR UPDATE$`).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "secret"}).
+				AddRow(1, "old1").
+				AddRow(2, "old2"))
+		mock.ExpectExec(`^UPDATE user_totp SET secret = \$2 WHERE user_id = \$1$`).
+			WithArgs(1, "new1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`^UPDATE user_totp SET secret = \$2 WHERE user_id = \$1$`).
+			WithArgs(2, "new2").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		encrypter := domain.NewMockEncrypter(ctrl)
+		encrypter.EXPECT().Decrypt("old1").Return("secret1", nil)
+		encrypter.EXPECT().Encrypt("secret1").Return("new1", nil)
+		encrypter.EXPECT().Decrypt("old2").Return("secret2", nil)
+		encrypter.EXPECT().Encrypt("secret2").Return("new2", nil)
+		adapter.encrypter = encrypter
+
+		count, err := adapter.ReencryptTOTPSecrets()
+		require.NoError(t, err)
+		require.Equal(t, int64(2), count)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("with unknown key", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^SELECT user_id, secret FROM user_totp ORDER BY user_id FOR UPDATE$`).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "secret"}).AddRow(1, "old1"))
+		mock.ExpectRollback()
+
+		encrypter := domain.NewMockEncrypter(ctrl)
+		encrypter.EXPECT().Decrypt("old1").Return("", errors.New("cipher: message authentication failed"))
+		adapter.encrypter = encrypter
+
+		_, err := adapter.ReencryptTOTPSecrets()
+		require.Equal(t, domain.ErrInternalSecurity, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAdapter_UseTOTPStep(t *testing.T) {
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+	}
+
+	t.Run("new step", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectExec(`^UPDATE user_totp SET last_step = (.+) WHERE user_id = (.+) AND last_step < (.+)$`).
+			WithArgs(1, 42).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, adapter.UseTOTPStep(1, 42))
+	})
+
+	t.Run("used step", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectExec(`^UPDATE user_totp SET last_step = (.+) WHERE user_id = (.+) AND last_step < (.+)$`).
+			WithArgs(1, 42).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		require.Equal(t, domain.ErrInvalidMFACode, adapter.UseTOTPStep(1, 42))
+	})
+}
+
+func TestAdapter_ReplaceRecoveryCodes(t *testing.T) {
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`^DELETE FROM user_recovery_code WHERE user_id = (.+)$`).
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 10))
+		mock.ExpectExec(`^INSERT INTO user_recovery_code (.+) SELECT (.+)$`).
+			WithArgs(1, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		require.NoError(t, adapter.ReplaceRecoveryCodes(1, []string{"hash1", "hash2"}))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("without hashes", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`^DELETE FROM user_recovery_code WHERE user_id = (.+)$`).
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 10))
+		mock.ExpectCommit()
+
+		require.NoError(t, adapter.ReplaceRecoveryCodes(1, nil))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("with failed insert", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`^DELETE FROM user_recovery_code WHERE user_id = (.+)$`).
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 10))
+		mock.ExpectExec(`^INSERT INTO user_recovery_code (.+) SELECT (.+)$`).
+			WithArgs(1, sqlmock.AnyArg()).
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		require.Equal(t, domain.ErrInternalStorage, adapter.ReplaceRecoveryCodes(1, []string{"hash1"}))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAdapter_UseRecoveryCode(t *testing.T) {
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+	}
+
+	t.Run("unused code", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectExec(`^UPDATE user_recovery_code SET used_at = now\(\) WHERE user_id = (.+) AND code_hash = (.+) AND used_at IS NULL$`).
+			WithArgs(1, "hash").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, adapter.UseRecoveryCode(1, "hash"))
+	})
+
+	t.Run("used or unknown code", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectExec(`^UPDATE user_recovery_code SET used_at = now\(\) WHERE user_id = (.+) AND code_hash = (.+) AND used_at IS NULL$`).
+			WithArgs(1, "hash").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		require.Equal(t, domain.ErrInvalidMFACode, adapter.UseRecoveryCode(1, "hash"))
+	})
+}
+
+func TestAdapter_CountRecoveryCodes(t *testing.T) {
+	logger := zap.NewExample()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	adapter := &adapter{
+		logger: logger,
+		db:     sqlx.NewDb(db, "postgres"),
+	}
+
+	mock.ExpectQuery(`^SELECT count\(\*\) FROM user_recovery_code WHERE user_id = (.+) AND used_at IS NULL$`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	count, err := adapter.CountRecoveryCodes(1)
+	require.NoError(t, err)
+	require.Equal(t, 7, count)
+}
+
+func TestAdapter_GetPermissions(t *testing.T) {
+	logger := zap.NewExample()
+
+	t.Run("with inherited permissions", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		adapter := &adapter{
+			logger: logger,
+			db:     sqlx.NewDb(db, "postgres"),
+		}
+
+		mock.ExpectQuery(`^WITH RECURSIVE roles AS (.+) SELECT DISTINCT permission FROM role_permission (.+)$`).
+			WithArgs("admin").
+			WillReturnRows(sqlmock.NewRows([]string{"permission"}).
+				AddRow("profile:read").
+				AddRow("users:read"))
+
+		actual, err := adapter.GetPermissions("admin")
+		require.NoError(t, err)
+		require.Equal(t, []string{"profile:read", "users:read"}, actual)
+	})
+
+	t.Run("with unknown role", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		adapter := &adapter{
+			logger: logger,
+			db:     sqlx.NewDb(db, "postgres"),
+		}
+
+		mock.ExpectQuery(`^WITH RECURSIVE roles AS (.+)$`).
+			WithArgs("unknown").
+			WillReturnRows(sqlmock.NewRows([]string{"permission"}))
+
+		actual, err := adapter.GetPermissions("unknown")
+		require.NoError(t, err)
+		require.Empty(t, actual)
+	})
+}
+
+func TestAdapter_UpdateUser(t *testing.T) {
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+	}
+
+	user := &domain.User{
+		ID:       2,
+		Username: "alice",
+		Role:     "admin",
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^UPDATE "user" SET username = (.+), role = (.+) WHERE id = (.+) RETURNING (.+)$`).
+			WithArgs(2, "alice", "admin").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "role", "disabled"}).AddRow(2, "alice", "admin", false))
+
+		actual, err := adapter.UpdateUser(user)
+		require.NoError(t, err)
+		require.Equal(t, user, actual)
+	})
+
+	t.Run("with unknown user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^UPDATE "user" (.+)$`).
+			WithArgs(2, "alice", "admin").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err = adapter.UpdateUser(user)
+		require.Equal(t, domain.ErrNotFound, err)
+	})
+
+	t.Run("with existing username", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^UPDATE "user" (.+)$`).
+			WithArgs(2, "alice", "admin").
+			WillReturnError(&pq.Error{Code: uniqueViolation, Constraint: usernameConstraint})
+
+		_, err = adapter.UpdateUser(user)
+		require.Equal(t, domain.ErrUserAlreadyExists, err)
+	})
+}
+
+func TestAdapter_SetUserDisabled(t *testing.T) {
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+	}
+
+	t.Run("existing user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectExec(`^UPDATE "user" SET disabled = (.+) WHERE id = (.+)$`).
+			WithArgs(2, true).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, adapter.SetUserDisabled(2, true))
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectExec(`^UPDATE "user" SET disabled = (.+) WHERE id = (.+)$`).
+			WithArgs(2, true).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		require.Equal(t, domain.ErrNotFound, adapter.SetUserDisabled(2, true))
+	})
+}
+
+func TestAdapter_UpdateUserPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger := zap.NewExample()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	hasher := domain.NewMockPasswordHasher(ctrl)
+	hasher.EXPECT().Hash("password").Return("hash", nil)
+
+	adapter := &adapter{
+		logger: logger,
+		db:     sqlx.NewDb(db, "postgres"),
+		hasher: hasher,
+	}
+
+	mock.ExpectExec(`^UPDATE "user" SET password = (.+) WHERE id = (.+)$`).
+		WithArgs(2, "hash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, adapter.UpdateUserPassword(2, "password"))
+}
+
+func TestAdapter_DeleteUser(t *testing.T) {
+	logger := zap.NewExample()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	adapter := &adapter{
+		logger: logger,
+		db:     sqlx.NewDb(db, "postgres"),
+	}
+
+	mock.ExpectExec(`^DELETE FROM "user" WHERE id = (.+)$`).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.Equal(t, domain.ErrNotFound, adapter.DeleteUser(2))
+}
+
+func TestAdapter_RoleExists(t *testing.T) {
+	logger := zap.NewExample()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	adapter := &adapter{
+		logger: logger,
+		db:     sqlx.NewDb(db, "postgres"),
+	}
+
+	mock.ExpectQuery(`^SELECT exists\(SELECT 1 FROM role WHERE name = (.+)\)$`).
+		WithArgs("admin").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := adapter.RoleExists("admin")
+	require.NoError(t, err)
+	require.True(t, exists)
+}
+
+func TestAdapter_ListUsers(t *testing.T) {
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+	}
+
+	columns := []string{"id", "username", "role", "disabled"}
+
+	t.Run("filtered", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		disabled := false
+		filter := &domain.UserFilter{
+			Role:           "user",
+			Disabled:       &disabled,
+			UsernamePrefix: "a_",
+			Sort:           domain.SortByID,
+			Limit:          2,
+		}
+
+		mock.ExpectQuery(`^SELECT id, username, role, disabled FROM "user"\s+WHERE role = \$1\s+AND disabled = \$2\s+AND username LIKE \$3\s+ORDER BY id ASC\s+LIMIT \$4$`).
+			WithArgs("user", false, `a\_%`, 3).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(1, "a_1", "user", false).
+				AddRow(2, "a_2", "user", false).
+				AddRow(3, "a_3", "user", false))
+
+		users, more, err := adapter.ListUsers(filter, nil)
+		require.NoError(t, err)
+		require.True(t, more)
+		require.Equal(t, []*domain.User{
+			{ID: 1, Username: "a_1", Role: "user"},
+			{ID: 2, Username: "a_2", Role: "user"},
+		}, users)
+	})
+
+	t.Run("after cursor", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		filter := &domain.UserFilter{
+			Sort:  domain.SortByUsernameDesc,
+			Limit: 2,
+		}
+
+		mock.ExpectQuery(`^SELECT id, username, role, disabled FROM "user"\s+WHERE \(username, id\) < \(\$1, \$2\)\s+ORDER BY username DESC, id DESC\s+LIMIT \$3$`).
+			WithArgs("bob", 2, 3).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "alice", "user", true))
+
+		users, more, err := adapter.ListUsers(filter, &domain.UserCursor{ID: 2, Username: "bob"})
+		require.NoError(t, err)
+		require.False(t, more)
+		require.Equal(t, []*domain.User{{ID: 1, Username: "alice", Role: "user", Disabled: true}}, users)
+	})
+
+	t.Run("invalid sort", func(t *testing.T) {
+		_, _, err := adapter.ListUsers(&domain.UserFilter{Sort: "password"}, nil)
+		require.Equal(t, domain.ErrInvalidRequest, err)
+	})
+}
+
+func TestAdapter_AddAuditEvent(t *testing.T) {
+	logger := zap.NewExample()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	adapter := &adapter{
+		logger:   logger,
+		db:       sqlx.NewDb(db, "postgres"),
+		auditKey: []byte("0123456789abcdef0123456789abcdef"),
+	}
+
+	newEvent := func() *domain.AuditEvent {
+		return &domain.AuditEvent{
+			Time:      time.Date(2019, 11, 22, 12, 0, 0, 123456789, time.UTC),
+			Username:  "alice",
+			Action:    domain.AuditActionLogin,
+			Outcome:   domain.AuditOutcomeFailure,
+			Reason:    "invalid credentials",
+			IP:        "192.0.2.1",
+			UserAgent: "test",
+			RequestID: "request",
+		}
+	}
+	storedTime := time.Date(2019, 11, 22, 12, 0, 0, 123456000, time.UTC)
+
+	t.Run("chained to the last event", func(t *testing.T) {
+		event := newEvent()
+
+		expected := newEvent()
+		expected.Time = storedTime
+		expected.PrevHash = "last"
+		hash := domain.HashAuditEvent(adapter.auditKey, expected)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`^SELECT pg_advisory_xact_lock\(\$1\)$`).
+			WithArgs(auditChainLock).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`^SELECT coalesce\(\(SELECT hash FROM audit_event ORDER BY id DESC LIMIT 1\), hash\) FROM audit_chain_anchor$`).
+			WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow("last"))
+		mock.ExpectQuery(`^INSERT INTO audit_event (.+) VALUES (.+) RETURNING id$`).
+			WithArgs(storedTime, nil, "alice", "login", "failure", "invalid credentials", "192.0.2.1", "test", "request", "last", hash).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectCommit()
+
+		require.NoError(t, adapter.AddAuditEvent(event))
+		require.Equal(t, int64(7), event.ID)
+		require.Equal(t, "last", event.PrevHash)
+		require.Equal(t, hash, event.Hash)
+	})
+
+	t.Run("first event", func(t *testing.T) {
+		event := newEvent()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`^SELECT pg_advisory_xact_lock\(\$1\)$`).
+			WithArgs(auditChainLock).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`^SELECT coalesce\(\(SELECT hash FROM audit_event ORDER BY id DESC LIMIT 1\), hash\) FROM audit_chain_anchor$`).
+			WillReturnRows(sqlmock.NewRows([]string{"hash"}))
+		mock.ExpectQuery(`^INSERT INTO audit_event (.+) VALUES (.+) RETURNING id$`).
+			WithArgs(storedTime, nil, "alice", "login", "failure", "invalid credentials", "192.0.2.1", "test", "request", "", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		require.NoError(t, adapter.AddAuditEvent(event))
+		require.Equal(t, "", event.PrevHash)
+	})
+
+	t.Run("with broken storage", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`^SELECT pg_advisory_xact_lock\(\$1\)$`).
+			WithArgs(auditChainLock).
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		require.Equal(t, domain.ErrInternalStorage, adapter.AddAuditEvent(newEvent()))
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdapter_ListAuditEvents(t *testing.T) {
+	logger := zap.NewExample()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	adapter := &adapter{
+		logger: logger,
+		db:     sqlx.NewDb(db, "postgres"),
+	}
+
+	from := time.Date(2019, 11, 20, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	columns := []string{"id", "time", "user_id", "username", "action", "outcome", "reason", "ip", "user_agent", "request_id", "prev_hash", "hash"}
+
+	mock.ExpectQuery(`^SELECT (.+) FROM audit_event\s+WHERE time >= \$1\s+AND time < \$2\s+AND action = \$3\s+AND id < \$4\s+ORDER BY id DESC\s+LIMIT \$5$`).
+		WithArgs(from, to, "login", 10, 2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(9, from, 1, "alice", "login", "success", "", "192.0.2.1", "test", "request", "prev", "hash").
+			AddRow(8, from, nil, "bob", "login", "failure", "invalid credentials", "192.0.2.1", "test", "request", "", "prev"))
+
+	events, more, err := adapter.ListAuditEvents(&domain.AuditFilter{
+		From:   from,
+		To:     to,
+		Action: domain.AuditActionLogin,
+		Limit:  1,
+	}, &domain.AuditCursor{ID: 10})
+	require.NoError(t, err)
+	require.True(t, more)
+	require.Equal(t, []*domain.AuditEvent{{
+		ID:        9,
+		Time:      from,
+		UserID:    1,
+		Username:  "alice",
+		Action:    "login",
+		Outcome:   "success",
+		IP:        "192.0.2.1",
+		UserAgent: "test",
+		RequestID: "request",
+		PrevHash:  "prev",
+		Hash:      "hash",
+	}}, events)
+}
+
+func TestAdapter_ListAuditChain(t *testing.T) {
+	logger := zap.NewExample()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	adapter := &adapter{
+		logger: logger,
+		db:     sqlx.NewDb(db, "postgres"),
+	}
+
+	at := time.Date(2019, 11, 22, 0, 0, 0, 0, time.UTC)
+	columns := []string{"id", "time", "user_id", "username", "action", "outcome", "reason", "ip", "user_agent", "request_id", "prev_hash", "hash"}
+
+	mock.ExpectQuery(`^SELECT (.+) FROM audit_event WHERE id > \$1 ORDER BY id LIMIT \$2$`).
+		WithArgs(5, 2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(6, at, nil, "", "token_validation", "failure", "invalid access token", "", "", "", "a", "b").
+			AddRow(7, at, 1, "", "logout", "success", "", "", "", "", "b", "c"))
+
+	events, err := adapter.ListAuditChain(5, 2)
+	require.NoError(t, err)
+	require.Equal(t, []*domain.AuditEvent{
+		{ID: 6, Time: at, Action: "token_validation", Outcome: "failure", Reason: "invalid access token", PrevHash: "a", Hash: "b"},
+		{ID: 7, Time: at, UserID: 1, Action: "logout", Outcome: "success", PrevHash: "b", Hash: "c"},
+	}, events)
+}
+
+func TestAdapter_DeleteAuditEvents(t *testing.T) {
+	logger := zap.NewExample()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	adapter := &adapter{
+		logger: logger,
+		db:     sqlx.NewDb(db, "postgres"),
+	}
+
+	before := time.Now()
+
+	t.Run("moves the chain anchor", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`^SELECT pg_advisory_xact_lock\(\$1\)$`).
+			WithArgs(auditChainLock).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`^SELECT id, hash FROM audit_event WHERE time < \$1 ORDER BY id DESC LIMIT 1$`).
+			WithArgs(before).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "hash"}).AddRow(5, "hash"))
+		mock.ExpectExec(`^DELETE FROM audit_event WHERE id <= \$1$`).
+			WithArgs(5).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(`^UPDATE audit_chain_anchor SET event_id = \$1, hash = \$2$`).
+			WithArgs(5, "hash").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		deleted, err := adapter.DeleteAuditEvents(before)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), deleted)
+	})
+
+	t.Run("nothing to delete", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`^SELECT pg_advisory_xact_lock\(\$1\)$`).
+			WithArgs(auditChainLock).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`^SELECT id, hash FROM audit_event WHERE time < \$1 ORDER BY id DESC LIMIT 1$`).
+			WithArgs(before).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "hash"}))
+		mock.ExpectRollback()
+
+		deleted, err := adapter.DeleteAuditEvents(before)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), deleted)
+	})
+
+	t.Run("with broken storage", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`^SELECT pg_advisory_xact_lock\(\$1\)$`).
+			WithArgs(auditChainLock).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`^SELECT id, hash FROM audit_event WHERE time < \$1 ORDER BY id DESC LIMIT 1$`).
+			WithArgs(before).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "hash"}).AddRow(5, "hash"))
+		mock.ExpectExec(`^DELETE FROM audit_event WHERE id <= \$1$`).
+			WithArgs(5).
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		_, err := adapter.DeleteAuditEvents(before)
+		require.Equal(t, domain.ErrInternalStorage, err)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdapter_GetAuditChainAnchor(t *testing.T) {
+	logger := zap.NewExample()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	adapter := &adapter{
+		logger: logger,
+		db:     sqlx.NewDb(db, "postgres"),
+	}
+
+	mock.ExpectQuery(`^SELECT event_id, hash FROM audit_chain_anchor$`).
+		WillReturnRows(sqlmock.NewRows([]string{"event_id", "hash"}).AddRow(5, "hash"))
+
+	anchor, err := adapter.GetAuditChainAnchor()
+	require.NoError(t, err)
+	require.Equal(t, &domain.AuditChainAnchor{EventID: 5, Hash: "hash"}, anchor)
+}
+
+func TestAdapter_CreateAPIKey(t *testing.T) {
+	logger := zap.NewExample()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	adapter := &adapter{
+		logger: logger,
+		db:     sqlx.NewDb(db, "postgres"),
+	}
+
+	createdAt := time.Date(2019, 11, 25, 0, 0, 0, 0, time.UTC)
+	expiresAt := createdAt.Add(24 * time.Hour)
+	columns := []string{"id", "user_id", "name", "scopes", "created_at", "expires_at", "last_used_at"}
+
+	mock.ExpectQuery(`^INSERT INTO api_key \(user_id, name, key_hash, scopes, expires_at\) VALUES (.+) RETURNING (.+)$`).
+		WithArgs(1, "ci", "hash", pq.Array([]string{"users:read"}), expiresAt).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(3, 1, "ci", "{users:read}", createdAt, expiresAt, nil))
+
+	key, err := adapter.CreateAPIKey(&domain.APIKey{
+		UserID:    1,
+		Name:      "ci",
+		Scopes:    []string{"users:read"},
+		ExpiresAt: &expiresAt,
+	}, "hash")
+	require.NoError(t, err)
+	require.Equal(t, &domain.APIKey{
+		ID:        3,
+		UserID:    1,
+		Name:      "ci",
+		Scopes:    []string{"users:read"},
+		CreatedAt: createdAt,
+		ExpiresAt: &expiresAt,
+	}, key)
+}
+
+func TestAdapter_ListAPIKeys(t *testing.T) {
+	logger := zap.NewExample()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	adapter := &adapter{
+		logger: logger,
+		db:     sqlx.NewDb(db, "postgres"),
+	}
+
+	createdAt := time.Date(2019, 11, 25, 0, 0, 0, 0, time.UTC)
+	usedAt := createdAt.Add(time.Hour)
+	columns := []string{"id", "user_id", "name", "scopes", "created_at", "expires_at", "last_used_at"}
+
+	mock.ExpectQuery(`^SELECT (.+) FROM api_key WHERE user_id = \$1 ORDER BY id$`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(3, 1, "ci", "{users:read}", createdAt, nil, usedAt).
+			AddRow(4, 1, "backup", "{}", createdAt, nil, nil))
+
+	keys, err := adapter.ListAPIKeys(1)
+	require.NoError(t, err)
+	require.Equal(t, []*domain.APIKey{
+		{ID: 3, UserID: 1, Name: "ci", Scopes: []string{"users:read"}, CreatedAt: createdAt, LastUsedAt: &usedAt},
+		{ID: 4, UserID: 1, Name: "backup", Scopes: []string{}, CreatedAt: createdAt},
+	}, keys)
+}
+
+func TestAdapter_UseAPIKey(t *testing.T) {
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+	}
+
+	columns := []string{"id", "user_id", "name", "scopes", "created_at", "expires_at", "last_used_at"}
+
+	t.Run("valid key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		createdAt := time.Date(2019, 11, 25, 0, 0, 0, 0, time.UTC)
+		usedAt := createdAt.Add(time.Hour)
+
+		mock.ExpectQuery(`^WITH used AS \( UPDATE api_key SET last_used_at = now\(\) WHERE key_hash = \$1 AND \(expires_at IS NULL OR expires_at > now\(\)\) AND \(last_used_at IS NULL OR last_used_at < now\(\) - interval '1 minute'\) RETURNING (.+) \) SELECT (.+) FROM used UNION ALL SELECT (.+) FROM api_key WHERE key_hash = \$1 AND \(expires_at IS NULL OR expires_at > now\(\)\) AND NOT exists\(SELECT 1 FROM used\)$`).
+			WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(3, 1, "ci", "{users:read}", createdAt, nil, usedAt))
+
+		key, err := adapter.UseAPIKey("hash")
+		require.NoError(t, err)
+		require.Equal(t, &domain.APIKey{
+			ID:         3,
+			UserID:     1,
+			Name:       "ci",
+			Scopes:     []string{"users:read"},
+			CreatedAt:  createdAt,
+			LastUsedAt: &usedAt,
+		}, key)
+	})
+
+	t.Run("unknown or expired key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectQuery(`^WITH used AS \( UPDATE api_key SET (.+) FROM used UNION ALL (.+)$`).
+			WithArgs("hash").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err = adapter.UseAPIKey("hash")
+		require.Equal(t, domain.ErrNotFound, err)
+	})
+}
+
+func TestAdapter_DeleteAPIKey(t *testing.T) {
+	logger := zap.NewExample()
+
+	adapter := &adapter{
+		logger: logger,
+	}
+
+	t.Run("own key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectExec(`^DELETE FROM api_key WHERE user_id = \$1 AND id = \$2$`).
+			WithArgs(1, 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, adapter.DeleteAPIKey(1, 3))
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		adapter.db = sqlx.NewDb(db, "postgres")
+
+		mock.ExpectExec(`^DELETE FROM api_key WHERE user_id = \$1 AND id = \$2$`).
+			WithArgs(1, 3).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		require.Equal(t, domain.ErrNotFound, adapter.DeleteAPIKey(1, 3))
 	})
 }
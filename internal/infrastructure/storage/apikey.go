@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/lzakharov/goss/internal/domain"
+	"go.uber.org/zap"
+)
+
+//CreateAPIKey saves the user's API key by its hash.
+func (a *adapter) CreateAPIKey(key *domain.APIKey, hash string) (*domain.APIKey, error) {
+	row := new(apiKey)
+
+	if err := a.db.QueryRowx(
+		createAPIKeyQuery,
+		key.UserID,
+		key.Name,
+		hash,
+		pq.Array(key.Scopes),
+		key.ExpiresAt,
+	).StructScan(row); err != nil {
+		a.logger.Error("Error creating an API key!",
+			zap.Int64("userID", key.UserID),
+			zap.Error(err))
+		return nil, domain.ErrInternalStorage
+	}
+
+	return row.toDomain(), nil
+}
+
+//ListAPIKeys lists user's API keys from oldest to newest.
+func (a *adapter) ListAPIKeys(userID int64) ([]*domain.APIKey, error) {
+	rows := make([]*apiKey, 0)
+
+	if err := a.db.Select(&rows, listAPIKeysQuery, userID); err != nil {
+		a.logger.Error("Error listing user's API keys!",
+			zap.Int64("userID", userID),
+			zap.Error(err))
+		return nil, domain.ErrInternalStorage
+	}
+
+	keys := make([]*domain.APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, row.toDomain())
+	}
+
+	return keys, nil
+}
+
+//UseAPIKey gets the unexpired API key by the hash and updates its last used time.
+//The last used time is updated at most once a minute, so frequent requests with the key don't write to the database.
+func (a *adapter) UseAPIKey(hash string) (*domain.APIKey, error) {
+	row := new(apiKey)
+
+	if err := a.db.QueryRowx(
+		useAPIKeyQuery,
+		hash,
+	).StructScan(row); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrNotFound
+		}
+
+		a.logger.Error("Error using an API key!", zap.Error(err))
+		return nil, domain.ErrInternalStorage
+	}
+
+	return row.toDomain(), nil
+}
+
+//DeleteAPIKey deletes the user's API key.
+func (a *adapter) DeleteAPIKey(userID, keyID int64) error {
+	return a.execOne(domain.ErrNotFound, deleteAPIKeyQuery, userID, keyID)
+}
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lzakharov/goss/internal/domain"
+	"go.uber.org/zap"
+)
+
+//AddAuditEvent chains the event to the last one and saves it, setting its id and hashes.
+//The time is truncated to microseconds as stored by the database to keep the hash verifiable.
+func (a *adapter) AddAuditEvent(event *domain.AuditEvent) error {
+	event.Time = event.Time.Truncate(time.Microsecond)
+
+	userID := sql.NullInt64{
+		Int64: event.UserID,
+		Valid: event.UserID != 0,
+	}
+
+	tx, err := a.db.Beginx()
+	if err != nil {
+		a.logger.Error("Error beginning a transaction!",
+			zap.Any("event", event),
+			zap.Error(err))
+		return domain.ErrInternalStorage
+	}
+
+	if _, err := tx.Exec(lockAuditChainQuery, auditChainLock); err != nil {
+		a.logger.Error("Error locking the audit chain!",
+			zap.Any("event", event),
+			zap.Error(err))
+		_ = tx.Rollback()
+		return domain.ErrInternalStorage
+	}
+
+	var prevHash string
+
+	if err := tx.QueryRowx(getLastAuditHashQuery).Scan(&prevHash); err != nil && err != sql.ErrNoRows {
+		a.logger.Error("Error getting the last audit event hash!",
+			zap.Any("event", event),
+			zap.Error(err))
+		_ = tx.Rollback()
+		return domain.ErrInternalStorage
+	}
+
+	event.PrevHash = prevHash
+	event.Hash = domain.HashAuditEvent(a.auditKey, event)
+
+	if err := tx.QueryRowx(
+		addAuditEventQuery,
+		event.Time,
+		userID,
+		event.Username,
+		event.Action,
+		event.Outcome,
+		event.Reason,
+		event.IP,
+		event.UserAgent,
+		event.RequestID,
+		event.PrevHash,
+		event.Hash,
+	).Scan(&event.ID); err != nil {
+		a.logger.Error("Error adding an audit event!",
+			zap.Any("event", event),
+			zap.Error(err))
+		_ = tx.Rollback()
+		return domain.ErrInternalStorage
+	}
+
+	if err := tx.Commit(); err != nil {
+		a.logger.Error("Error committing an audit event!",
+			zap.Any("event", event),
+			zap.Error(err))
+		return domain.ErrInternalStorage
+	}
+
+	return nil
+}
+
+//ListAuditEvents lists audit events matching the filter after the cursor from newest to oldest.
+//One extra row is selected to tell whether there are more events.
+func (a *adapter) ListAuditEvents(filter *domain.AuditFilter, after *domain.AuditCursor) ([]*domain.AuditEvent, bool, error) {
+	limit := filter.Limit
+	if limit <= 0 || limit > domain.MaxAuditPageSize {
+		limit = domain.MaxAuditPageSize
+	}
+
+	var (
+		conditions []string
+		args       []interface{}
+	)
+
+	param := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "time >= "+param(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "time < "+param(filter.To))
+	}
+	if filter.UserID != 0 {
+		conditions = append(conditions, "user_id = "+param(filter.UserID))
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = "+param(filter.Action))
+	}
+	if filter.Outcome != "" {
+		conditions = append(conditions, "outcome = "+param(filter.Outcome))
+	}
+	if after != nil {
+		conditions = append(conditions, "id < "+param(after.ID))
+	}
+
+	query := listAuditEventsQuery
+	if len(conditions) > 0 {
+		query += "\nWHERE " + strings.Join(conditions, "\n  AND ")
+	}
+	query += "\nORDER BY id DESC\nLIMIT " + param(limit+1)
+
+	rows := make([]*auditEvent, 0, limit+1)
+
+	if err := a.db.Select(&rows, query, args...); err != nil {
+		a.logger.Error("Error listing audit events!",
+			zap.Any("filter", filter),
+			zap.Error(err))
+		return nil, false, domain.ErrInternalStorage
+	}
+
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+
+	events := make([]*domain.AuditEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, row.toDomain())
+	}
+
+	return events, more, nil
+}
+
+//ListAuditChain lists up to the limit of audit events after the event id from oldest to newest.
+func (a *adapter) ListAuditChain(afterID int64, limit int) ([]*domain.AuditEvent, error) {
+	rows := make([]*auditEvent, 0, limit)
+
+	if err := a.db.Select(&rows, listAuditChainQuery, afterID, limit); err != nil {
+		a.logger.Error("Error listing the audit chain!",
+			zap.Int64("afterID", afterID),
+			zap.Error(err))
+		return nil, domain.ErrInternalStorage
+	}
+
+	events := make([]*domain.AuditEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, row.toDomain())
+	}
+
+	return events, nil
+}
+
+//GetAuditChainAnchor returns the event id and hash the audit chain starts after.
+func (a *adapter) GetAuditChainAnchor() (*domain.AuditChainAnchor, error) {
+	anchor := new(domain.AuditChainAnchor)
+
+	if err := a.db.QueryRowx(getAuditChainAnchorQuery).Scan(&anchor.EventID, &anchor.Hash); err != nil {
+		a.logger.Error("Error getting the audit chain anchor!", zap.Error(err))
+		return nil, domain.ErrInternalStorage
+	}
+
+	return anchor, nil
+}
+
+//DeleteAuditEvents deletes audit events up to the last one older than the time and returns the number of deleted events.
+//The chain anchor is moved to the last deleted event in the same transaction, so the remaining chain stays verifiable.
+func (a *adapter) DeleteAuditEvents(before time.Time) (int64, error) {
+	tx, err := a.db.Beginx()
+	if err != nil {
+		a.logger.Error("Error beginning a transaction!",
+			zap.Time("before", before),
+			zap.Error(err))
+		return 0, domain.ErrInternalStorage
+	}
+
+	if _, err := tx.Exec(lockAuditChainQuery, auditChainLock); err != nil {
+		a.logger.Error("Error locking the audit chain!",
+			zap.Time("before", before),
+			zap.Error(err))
+		_ = tx.Rollback()
+		return 0, domain.ErrInternalStorage
+	}
+
+	anchor := new(domain.AuditChainAnchor)
+
+	if err := tx.QueryRowx(getLastPurgedAuditEventQuery, before).Scan(&anchor.EventID, &anchor.Hash); err != nil {
+		_ = tx.Rollback()
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		a.logger.Error("Error getting the last audit event to delete!",
+			zap.Time("before", before),
+			zap.Error(err))
+		return 0, domain.ErrInternalStorage
+	}
+
+	result, err := tx.Exec(deleteAuditEventsQuery, anchor.EventID)
+	if err != nil {
+		a.logger.Error("Error deleting audit events!",
+			zap.Time("before", before),
+			zap.Error(err))
+		_ = tx.Rollback()
+		return 0, domain.ErrInternalStorage
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		a.logger.Error("Error getting the number of deleted audit events!", zap.Error(err))
+		_ = tx.Rollback()
+		return 0, domain.ErrInternalStorage
+	}
+
+	if _, err := tx.Exec(updateAuditChainAnchorQuery, anchor.EventID, anchor.Hash); err != nil {
+		a.logger.Error("Error moving the audit chain anchor!",
+			zap.Any("anchor", anchor),
+			zap.Error(err))
+		_ = tx.Rollback()
+		return 0, domain.ErrInternalStorage
+	}
+
+	if err := tx.Commit(); err != nil {
+		a.logger.Error("Error committing deleted audit events!",
+			zap.Time("before", before),
+			zap.Error(err))
+		return 0, domain.ErrInternalStorage
+	}
+
+	return deleted, nil
+}
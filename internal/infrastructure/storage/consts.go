@@ -0,0 +1,12 @@
+package storage
+
+const uniqueViolation = "23505"
+
+//dummyPassword is hashed to compare passwords of unknown users with.
+const dummyPassword = "dummy password of an unknown user"
+
+//usernameConstraint is the unique constraint of usernames, other unique violations are internal errors.
+const usernameConstraint = "user_username_key"
+
+//auditChainLock is the advisory lock key serializing audit event inserts, so each event is chained to the last one.
+const auditChainLock = 0x617564697400
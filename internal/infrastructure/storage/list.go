@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lzakharov/goss/internal/domain"
+	"go.uber.org/zap"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+//userOrders maps sort orders to the order clause and the keyset comparison.
+var userOrders = map[string]struct {
+	orderBy string
+	after   string
+}{
+	domain.SortByID:           {"id ASC", "id > %s"},
+	domain.SortByIDDesc:       {"id DESC", "id < %s"},
+	domain.SortByUsername:     {"username ASC, id ASC", "(username, id) > (%s, %s)"},
+	domain.SortByUsernameDesc: {"username DESC, id DESC", "(username, id) < (%s, %s)"},
+}
+
+//ListUsers lists users matching the filter after the cursor in the keyset order.
+//One extra row is selected to tell whether there are more users.
+func (a *adapter) ListUsers(filter *domain.UserFilter, after *domain.UserCursor) ([]*domain.User, bool, error) {
+	order, ok := userOrders[filter.Sort]
+	if !ok {
+		return nil, false, domain.ErrInvalidRequest
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > domain.MaxUserPageSize {
+		limit = domain.MaxUserPageSize
+	}
+
+	var (
+		conditions []string
+		args       []interface{}
+	)
+
+	param := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Role != "" {
+		conditions = append(conditions, "role = "+param(filter.Role))
+	}
+	if filter.Disabled != nil {
+		conditions = append(conditions, "disabled = "+param(*filter.Disabled))
+	}
+	if filter.UsernamePrefix != "" {
+		conditions = append(conditions, "username LIKE "+param(likeEscaper.Replace(filter.UsernamePrefix)+"%"))
+	}
+	if after != nil {
+		if filter.Sort == domain.SortByUsername || filter.Sort == domain.SortByUsernameDesc {
+			conditions = append(conditions, fmt.Sprintf(order.after, param(after.Username), param(after.ID)))
+		} else {
+			conditions = append(conditions, fmt.Sprintf(order.after, param(after.ID)))
+		}
+	}
+
+	query := listUsersQuery
+	if len(conditions) > 0 {
+		query += "\nWHERE " + strings.Join(conditions, "\n  AND ")
+	}
+	query += "\nORDER BY " + order.orderBy + "\nLIMIT " + param(limit+1)
+
+	users := make([]*domain.User, 0, limit+1)
+
+	if err := a.db.Select(&users, query, args...); err != nil {
+		a.logger.Error("Error listing users!",
+			zap.Any("filter", filter),
+			zap.Error(err))
+		return nil, false, domain.ErrInternalStorage
+	}
+
+	if len(users) > limit {
+		return users[:limit], true, nil
+	}
+
+	return users, false, nil
+}
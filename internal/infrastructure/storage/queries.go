@@ -2,12 +2,185 @@ package storage
 
 const (
 	getUserQuery = `
-SELECT id, username, role
+SELECT id, username, role, disabled
 FROM "user"
 WHERE id = $1`
-	getUserByCredentialsQuery = `
-SELECT id, username, role
+	getUserByUsernameQuery = `
+SELECT id, username, role, disabled, password
 FROM "user"
-WHERE username = $1
-  AND password = $2`
+WHERE username = $1`
+	listUsersQuery = `
+SELECT id, username, role, disabled
+FROM "user"`
+	updateUserPasswordQuery = `
+UPDATE "user"
+SET password = $2
+WHERE id = $1`
+	createUserQuery = `
+INSERT INTO "user" (username, password, role)
+VALUES ($1, $2, $3)
+RETURNING id, username, role, disabled`
+	updateUserQuery = `
+UPDATE "user"
+SET username = $2,
+    role     = $3
+WHERE id = $1
+RETURNING id, username, role, disabled`
+	setUserDisabledQuery = `
+UPDATE "user"
+SET disabled = $2
+WHERE id = $1`
+	deleteUserQuery = `
+DELETE
+FROM "user"
+WHERE id = $1`
+	getClientQuery = `
+SELECT id, client_id, scopes, redirect_uris, public
+FROM oauth_client
+WHERE client_id = $1`
+	getClientWithSecretQuery = `
+SELECT id, client_id, scopes, redirect_uris, public, secret
+FROM oauth_client
+WHERE client_id = $1`
+	updateClientSecretQuery = `
+UPDATE oauth_client
+SET secret = $2
+WHERE id = $1`
+	getTOTPQuery = `
+SELECT secret, confirmed, last_step
+FROM user_totp
+WHERE user_id = $1`
+	saveTOTPQuery = `
+INSERT INTO user_totp (user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+    SET secret     = excluded.secret,
+        last_step  = 0,
+        created_at = now()
+WHERE NOT user_totp.confirmed`
+	confirmTOTPQuery = `
+UPDATE user_totp
+SET confirmed    = true,
+    confirmed_at = now(),
+    last_step    = $2
+WHERE user_id = $1
+  AND NOT confirmed`
+	useTOTPStepQuery = `
+UPDATE user_totp
+SET last_step = $2
+WHERE user_id = $1
+  AND last_step < $2`
+	deleteTOTPQuery = `
+DELETE
+FROM user_totp
+WHERE user_id = $1`
+	listTOTPSecretsQuery = `
+SELECT user_id, secret
+FROM user_totp
+ORDER BY user_id
+FOR UPDATE`
+	updateTOTPSecretQuery = `
+UPDATE user_totp
+SET secret = $2
+WHERE user_id = $1`
+	deleteRecoveryCodesQuery = `
+DELETE
+FROM user_recovery_code
+WHERE user_id = $1`
+	insertRecoveryCodesQuery = `
+INSERT INTO user_recovery_code (user_id, code_hash)
+SELECT $1, unnest($2::text[])`
+	useRecoveryCodeQuery = `
+UPDATE user_recovery_code
+SET used_at = now()
+WHERE user_id = $1
+  AND code_hash = $2
+  AND used_at IS NULL`
+	countRecoveryCodesQuery = `
+SELECT count(*)
+FROM user_recovery_code
+WHERE user_id = $1
+  AND used_at IS NULL`
+	getPermissionsQuery = `
+WITH RECURSIVE roles AS (
+    SELECT name, parent
+    FROM role
+    WHERE name = $1
+    UNION
+    SELECT role.name, role.parent
+    FROM role
+             JOIN roles ON role.name = roles.parent
+)
+SELECT DISTINCT permission
+FROM role_permission
+WHERE role IN (SELECT name FROM roles)
+ORDER BY permission`
+	roleExistsQuery = `
+SELECT exists(SELECT 1 FROM role WHERE name = $1)`
+	createAPIKeyQuery = `
+INSERT INTO api_key (user_id, name, key_hash, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, name, scopes, created_at, expires_at, last_used_at`
+	listAPIKeysQuery = `
+SELECT id, user_id, name, scopes, created_at, expires_at, last_used_at
+FROM api_key
+WHERE user_id = $1
+ORDER BY id`
+	useAPIKeyQuery = `
+WITH used AS (
+    UPDATE api_key
+    SET last_used_at = now()
+    WHERE key_hash = $1
+      AND (expires_at IS NULL OR expires_at > now())
+      AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')
+    RETURNING id, user_id, name, scopes, created_at, expires_at, last_used_at
+)
+SELECT id, user_id, name, scopes, created_at, expires_at, last_used_at
+FROM used
+UNION ALL
+SELECT id, user_id, name, scopes, created_at, expires_at, last_used_at
+FROM api_key
+WHERE key_hash = $1
+  AND (expires_at IS NULL OR expires_at > now())
+  AND NOT exists(SELECT 1 FROM used)`
+	deleteAPIKeyQuery = `
+DELETE
+FROM api_key
+WHERE user_id = $1
+  AND id = $2`
+	lockAuditChainQuery = `
+SELECT pg_advisory_xact_lock($1)`
+	getLastAuditHashQuery = `
+SELECT coalesce((SELECT hash FROM audit_event ORDER BY id DESC LIMIT 1), hash)
+FROM audit_chain_anchor`
+	addAuditEventQuery = `
+INSERT INTO audit_event (time, user_id, username, action, outcome, reason, ip, user_agent, request_id, prev_hash, hash)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id`
+	listAuditEventsQuery = `
+SELECT id, time, user_id, username, action, outcome, reason, ip, user_agent, request_id, prev_hash, hash
+FROM audit_event`
+	listAuditChainQuery = `
+SELECT id, time, user_id, username, action, outcome, reason, ip, user_agent, request_id, prev_hash, hash
+FROM audit_event
+WHERE id > $1
+ORDER BY id
+LIMIT $2`
+	getLastPurgedAuditEventQuery = `
+SELECT id, hash
+FROM audit_event
+WHERE time < $1
+ORDER BY id DESC
+LIMIT 1`
+	deleteAuditEventsQuery = `
+DELETE
+FROM audit_event
+WHERE id <= $1`
+	getAuditChainAnchorQuery = `
+SELECT event_id, hash
+FROM audit_chain_anchor`
+	updateAuditChainAnchorQuery = `
+UPDATE audit_chain_anchor
+SET event_id = $1,
+    hash     = $2`
 )
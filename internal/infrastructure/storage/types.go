@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/lzakharov/goss/internal/domain"
+)
+
+type userWithPassword struct {
+	domain.User
+	Password string
+}
+
+type oauthClient struct {
+	ID           int64
+	ClientID     string `db:"client_id"`
+	Scopes       pq.StringArray
+	RedirectURIs pq.StringArray `db:"redirect_uris"`
+	Public       bool
+}
+
+type clientWithSecret struct {
+	oauthClient
+	Secret string
+}
+
+func (c *oauthClient) toDomain() *domain.Client {
+	return &domain.Client{
+		ID:           c.ID,
+		ClientID:     c.ClientID,
+		Scopes:       []string(c.Scopes),
+		RedirectURIs: []string(c.RedirectURIs),
+		Public:       c.Public,
+	}
+}
+
+type totp struct {
+	Secret    string
+	Confirmed bool
+	LastStep  int64 `db:"last_step"`
+}
+
+type totpSecret struct {
+	UserID int64 `db:"user_id"`
+	Secret string
+}
+
+type auditEvent struct {
+	ID        int64
+	Time      time.Time
+	UserID    sql.NullInt64 `db:"user_id"`
+	Username  string
+	Action    string
+	Outcome   string
+	Reason    string
+	IP        string
+	UserAgent string `db:"user_agent"`
+	RequestID string `db:"request_id"`
+	PrevHash  string `db:"prev_hash"`
+	Hash      string
+}
+
+func (e *auditEvent) toDomain() *domain.AuditEvent {
+	return &domain.AuditEvent{
+		ID:        e.ID,
+		Time:      e.Time,
+		UserID:    e.UserID.Int64,
+		Username:  e.Username,
+		Action:    e.Action,
+		Outcome:   e.Outcome,
+		Reason:    e.Reason,
+		IP:        e.IP,
+		UserAgent: e.UserAgent,
+		RequestID: e.RequestID,
+		PrevHash:  e.PrevHash,
+		Hash:      e.Hash,
+	}
+}
+
+type apiKey struct {
+	ID         int64
+	UserID     int64 `db:"user_id"`
+	Name       string
+	Scopes     pq.StringArray
+	CreatedAt  time.Time   `db:"created_at"`
+	ExpiresAt  pq.NullTime `db:"expires_at"`
+	LastUsedAt pq.NullTime `db:"last_used_at"`
+}
+
+func (k *apiKey) toDomain() *domain.APIKey {
+	key := &domain.APIKey{
+		ID:        k.ID,
+		UserID:    k.UserID,
+		Name:      k.Name,
+		Scopes:    []string(k.Scopes),
+		CreatedAt: k.CreatedAt,
+	}
+	if k.ExpiresAt.Valid {
+		key.ExpiresAt = &k.ExpiresAt.Time
+	}
+	if k.LastUsedAt.Valid {
+		key.LastUsedAt = &k.LastUsedAt.Time
+	}
+
+	return key
+}
@@ -0,0 +1,11 @@
+-- +migrate Up
+
+INSERT INTO oauth_client (id, client_id, secret, scopes)
+VALUES (1, 'backend', 'secret', '{users:read}')
+ON CONFLICT DO NOTHING;
+
+-- +migrate Down
+
+DELETE
+FROM oauth_client
+WHERE id = 1;
@@ -0,0 +1,11 @@
+-- +migrate Up
+
+INSERT INTO oauth_client (id, client_id, secret, scopes, redirect_uris, public)
+VALUES (2, 'spa', '', '{users:read}', '{http://localhost:3000/callback}', true)
+ON CONFLICT DO NOTHING;
+
+-- +migrate Down
+
+DELETE
+FROM oauth_client
+WHERE id = 2;
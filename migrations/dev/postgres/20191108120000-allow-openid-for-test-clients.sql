@@ -0,0 +1,12 @@
+-- +migrate Up
+
+UPDATE oauth_client
+SET scopes = array_append(scopes, 'openid')
+WHERE id = 2
+  AND NOT 'openid' = ANY (scopes);
+
+-- +migrate Down
+
+UPDATE oauth_client
+SET scopes = array_remove(scopes, 'openid')
+WHERE id = 2;
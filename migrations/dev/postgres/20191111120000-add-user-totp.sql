@@ -0,0 +1,18 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS user_totp
+(
+    user_id      bigint      not null,
+    secret       text        not null,
+    confirmed    boolean     not null default false,
+    last_step    bigint      not null default 0,
+    created_at   timestamptz not null default now(),
+    confirmed_at timestamptz,
+
+    CONSTRAINT user_totp_pk PRIMARY KEY (user_id),
+    CONSTRAINT user_totp_user_fk FOREIGN KEY (user_id) REFERENCES "user" (id) ON DELETE CASCADE
+);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS user_totp;
@@ -0,0 +1,18 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS user_recovery_code
+(
+    id         bigserial   not null,
+    user_id    bigint      not null,
+    code_hash  text        not null,
+    created_at timestamptz not null default now(),
+    used_at    timestamptz,
+
+    CONSTRAINT user_recovery_code_pk PRIMARY KEY (id),
+    CONSTRAINT user_recovery_code_user_fk FOREIGN KEY (user_id) REFERENCES "user" (id) ON DELETE CASCADE,
+    CONSTRAINT user_recovery_code_unique UNIQUE (user_id, code_hash)
+);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS user_recovery_code;
@@ -0,0 +1,9 @@
+-- +migrate Up
+
+ALTER TABLE "user"
+    ADD COLUMN IF NOT EXISTS disabled boolean not null default false;
+
+-- +migrate Down
+
+ALTER TABLE "user"
+    DROP COLUMN IF EXISTS disabled;
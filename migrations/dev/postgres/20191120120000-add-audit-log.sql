@@ -0,0 +1,32 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS audit_event
+(
+    id         bigserial   not null,
+    time       timestamptz not null,
+    user_id    bigint,
+    username   text        not null default '',
+    action     text        not null,
+    outcome    text        not null,
+    reason     text        not null default '',
+    ip         text        not null default '',
+    user_agent text        not null default '',
+    request_id text        not null default '',
+
+    CONSTRAINT audit_event_pk PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS audit_event_time_idx ON audit_event (time);
+CREATE INDEX IF NOT EXISTS audit_event_user_id_idx ON audit_event (user_id, id);
+
+INSERT INTO role_permission (role, permission)
+VALUES ('admin', 'audit:read')
+ON CONFLICT DO NOTHING;
+
+-- +migrate Down
+
+DELETE
+FROM role_permission
+WHERE permission = 'audit:read';
+
+DROP TABLE IF EXISTS audit_event;
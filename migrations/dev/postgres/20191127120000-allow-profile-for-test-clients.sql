@@ -0,0 +1,12 @@
+-- +migrate Up
+
+UPDATE oauth_client
+SET scopes = array_cat(scopes, '{profile:read,profile:write}')
+WHERE id = 2
+  AND NOT 'profile:read' = ANY (scopes);
+
+-- +migrate Down
+
+UPDATE oauth_client
+SET scopes = array_remove(array_remove(scopes, 'profile:read'), 'profile:write')
+WHERE id = 2;
@@ -0,0 +1,21 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS audit_chain_anchor
+(
+    id       boolean not null default true,
+    event_id bigint  not null,
+    hash     text    not null,
+
+    CONSTRAINT audit_chain_anchor_pk PRIMARY KEY (id),
+    CONSTRAINT audit_chain_anchor_single CHECK (id)
+);
+
+INSERT INTO audit_chain_anchor (event_id, hash)
+SELECT coalesce(max(id), 0), ''
+FROM audit_event
+WHERE hash = ''
+ON CONFLICT DO NOTHING;
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS audit_chain_anchor;
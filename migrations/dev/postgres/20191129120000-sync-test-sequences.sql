@@ -0,0 +1,10 @@
+-- +migrate Up
+
+-- Test users and clients are inserted with explicit ids, so the sequences are moved past them.
+SELECT setval(pg_get_serial_sequence('"user"', 'id'), coalesce(max(id), 1), max(id) IS NOT NULL)
+FROM "user";
+
+SELECT setval(pg_get_serial_sequence('oauth_client', 'id'), coalesce(max(id), 1), max(id) IS NOT NULL)
+FROM oauth_client;
+
+-- +migrate Down
@@ -0,0 +1,16 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS oauth_client
+(
+    id         bigserial   not null,
+    client_id  text        not null unique,
+    secret     text        not null,
+    scopes     text[]      not null default '{}',
+    created_at timestamptz not null default now(),
+
+    CONSTRAINT oauth_client_pk PRIMARY KEY (id)
+);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS oauth_client;
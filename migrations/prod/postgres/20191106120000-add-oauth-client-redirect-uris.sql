@@ -0,0 +1,11 @@
+-- +migrate Up
+
+ALTER TABLE oauth_client
+    ADD COLUMN IF NOT EXISTS redirect_uris text[]  not null default '{}',
+    ADD COLUMN IF NOT EXISTS public        boolean not null default false;
+
+-- +migrate Down
+
+ALTER TABLE oauth_client
+    DROP COLUMN IF EXISTS redirect_uris,
+    DROP COLUMN IF EXISTS public;
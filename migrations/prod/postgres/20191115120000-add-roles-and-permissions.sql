@@ -0,0 +1,36 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS role
+(
+    name   text not null,
+    parent text,
+
+    CONSTRAINT role_pk PRIMARY KEY (name),
+    CONSTRAINT role_parent_fk FOREIGN KEY (parent) REFERENCES role (name) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS role_permission
+(
+    role       text not null,
+    permission text not null,
+
+    CONSTRAINT role_permission_pk PRIMARY KEY (role, permission),
+    CONSTRAINT role_permission_role_fk FOREIGN KEY (role) REFERENCES role (name) ON DELETE CASCADE
+);
+
+INSERT INTO role (name, parent)
+VALUES ('user', NULL),
+       ('admin', 'user')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permission (role, permission)
+VALUES ('user', 'profile:read'),
+       ('user', 'profile:write'),
+       ('admin', 'users:read'),
+       ('admin', 'users:write')
+ON CONFLICT DO NOTHING;
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS role_permission;
+DROP TABLE IF EXISTS role;
@@ -0,0 +1,11 @@
+-- +migrate Up
+
+ALTER TABLE audit_event
+    ADD COLUMN IF NOT EXISTS prev_hash text not null default '',
+    ADD COLUMN IF NOT EXISTS hash      text not null default '';
+
+-- +migrate Down
+
+ALTER TABLE audit_event
+    DROP COLUMN IF EXISTS hash,
+    DROP COLUMN IF EXISTS prev_hash;
@@ -0,0 +1,23 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS api_key
+(
+    id           bigserial   not null,
+    user_id      bigint      not null,
+    name         text        not null,
+    key_hash     text        not null,
+    scopes       text[]      not null default '{}',
+    created_at   timestamptz not null default now(),
+    expires_at   timestamptz,
+    last_used_at timestamptz,
+
+    CONSTRAINT api_key_pk PRIMARY KEY (id),
+    CONSTRAINT api_key_user_fk FOREIGN KEY (user_id) REFERENCES "user" (id) ON DELETE CASCADE,
+    CONSTRAINT api_key_hash_unique UNIQUE (key_hash)
+);
+
+CREATE INDEX IF NOT EXISTS api_key_user_id_idx ON api_key (user_id);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS api_key;
@@ -0,0 +1,9 @@
+-- +migrate Up
+
+-- Events hashed before the audit key was introduced can't be verified with it, so the chain is anchored after them.
+UPDATE audit_chain_anchor
+SET event_id = last.id,
+    hash     = last.hash
+FROM (SELECT id, hash FROM audit_event ORDER BY id DESC LIMIT 1) AS last;
+
+-- +migrate Down